@@ -1,8 +1,10 @@
 package clouds
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"html/template"
 	"satellity/internal/configs"
 	"time"
 
@@ -15,10 +17,59 @@ func SendVerificationEmail(ctx context.Context, purpose, recipient, code string)
 	title := v.Title
 	if purpose == "PASSWORD" {
 		title = v.Reset
+	} else if purpose == "EMAIL" {
+		title = v.Change
 	}
 	return sendEmail(ctx, title, fmt.Sprintf(v.Body, code), recipient)
 }
 
+// SendEmailChangedNotice notify the old address that the email has been changed
+func SendEmailChangedNotice(ctx context.Context, recipient, email string) error {
+	c := configs.AppConfig.Email.Changed
+	return sendEmail(ctx, c.Title, fmt.Sprintf(c.Body, email), recipient)
+}
+
+// EmailItem is an entry of a notification or digest email
+type EmailItem struct {
+	Title string
+	Text  string
+	URL   string
+}
+
+// NotificationEmail is the content of a notification or digest email, the
+// unsubscribe link works without sign in
+type NotificationEmail struct {
+	Name           string
+	Items          []EmailItem
+	UnsubscribeURL string
+}
+
+var notificationEmailTemplate = template.Must(template.New("notification").Parse(`<p>Hi {{.Name}},</p>
+<ul>
+{{range .Items}}<li><a href="{{.URL}}">{{.Title}}</a><br>{{.Text}}</li>
+{{end}}</ul>
+<p><small><a href="{{.UnsubscribeURL}}">Unsubscribe</a> from these emails.</small></p>`))
+
+// SendNotificationEmail send the notifications batched since the last email
+func SendNotificationEmail(ctx context.Context, recipient string, email NotificationEmail) error {
+	title := fmt.Sprintf(configs.AppConfig.Email.Notification.Title, len(email.Items))
+	return sendTemplateEmail(ctx, title, email, recipient)
+}
+
+// SendDigestEmail send the popular topics of the period, day or week
+func SendDigestEmail(ctx context.Context, recipient, period string, email NotificationEmail) error {
+	title := fmt.Sprintf(configs.AppConfig.Email.Digest.Title, period)
+	return sendTemplateEmail(ctx, title, email, recipient)
+}
+
+func sendTemplateEmail(ctx context.Context, subject string, email NotificationEmail, recipient string) error {
+	var body bytes.Buffer
+	if err := notificationEmailTemplate.Execute(&body, email); err != nil {
+		return err
+	}
+	return sendEmail(ctx, subject, body.String(), recipient)
+}
+
 func sendEmail(ctx context.Context, subject, body, recipient string) error {
 	config := configs.AppConfig
 	if config.Environment == "test" {
@@ -11,12 +11,35 @@ const (
 	BuildVersion = "BUILD_VERSION"
 )
 
+// OAuthProvider is a sign in provider, Type is one of github, gitlab and oidc.
+// URL is the GitHub Enterprise or GitLab host, or the OpenID Connect issuer.
+type OAuthProvider struct {
+	Name         string   `yaml:"name"`
+	Type         string   `yaml:"type"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	URL          string   `yaml:"url"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+// RateLimitGroup is a token bucket for the requests matching the methods and
+// path regexps, an empty list matches all. Rate is tokens refilled per second.
+type RateLimitGroup struct {
+	Name    string   `yaml:"name"`
+	Methods []string `yaml:"methods"`
+	Paths   []string `yaml:"paths"`
+	Rate    float64  `yaml:"rate"`
+	Burst   int      `yaml:"burst"`
+}
+
 // Option for configurations
 type Option struct {
 	Name string `yaml:"name"`
 	HTTP struct {
-		Host string `yaml:"host"`
-		Port string `yaml:"port"`
+		Host           string   `yaml:"host"`
+		Port           string   `yaml:"port"`
+		TrustedProxies []string `yaml:"trusted_proxies"`
 	} `yaml:"http"`
 	Database struct {
 		User     string `yaml:"user"`
@@ -29,12 +52,22 @@ type Option struct {
 		ClientID     string `yaml:"client_id"`
 		ClientSecret string `yaml:"client_secret"`
 	} `yaml:"github"`
+	OAuth []OAuthProvider `yaml:"oauth"`
+	Web3  struct {
+		Domain   string  `yaml:"domain"`
+		URI      string  `yaml:"uri"`
+		ChainIDs []int64 `yaml:"chain_ids"`
+	} `yaml:"web3"`
 	System struct {
 		Attachments struct {
 			Storage string `yaml:"storage"`
 			Path    string `yaml:"path"`
 		} `yaml:"attachments"`
 	} `yaml:"system"`
+	RateLimit struct {
+		Backend string           `yaml:"backend"`
+		Groups  []RateLimitGroup `yaml:"groups"`
+	} `yaml:"rate_limit"`
 	Recaptcha struct {
 		URL     string `yaml:"url"`
 		SiteKey string `yaml:"site_key"`
@@ -43,10 +76,23 @@ type Option struct {
 	Operators []string `yaml:"operators"`
 	Email     struct {
 		Verification struct {
+			Title  string `yaml:"title"`
+			Reset  string `yaml:"reset"`
+			Change string `yaml:"change"`
+			Body   string `yaml:"body"`
+		} `yaml:"verification"`
+		Changed struct {
 			Title string `yaml:"title"`
-			Reset string `yaml:"reset"`
 			Body  string `yaml:"body"`
-		} `yaml:"verification"`
+		} `yaml:"changed"`
+		Notification struct {
+			Title string `yaml:"title"`
+		} `yaml:"notification"`
+		Digest struct {
+			Title string `yaml:"title"`
+		} `yaml:"digest"`
+		URL    string `yaml:"url"`
+		Secret string `yaml:"secret"`
 	} `yaml:"email"`
 	Mailgun struct {
 		Domain string `yaml:"domain"`
@@ -3,6 +3,7 @@ package admin
 import (
 	"encoding/json"
 	"net/http"
+	"satellity/internal/middlewares"
 	"satellity/internal/models"
 	"satellity/internal/session"
 	"satellity/internal/views"
@@ -13,19 +14,26 @@ import (
 type categoryImpl struct{}
 
 type categoryRequest struct {
-	Name        string `json:"name"`
-	Alias       string `json:"alias"`
-	Description string `json:"description"`
-	Position    int64  `json:"position"`
+	Name         string `json:"name"`
+	Alias        string `json:"alias"`
+	Description  string `json:"description"`
+	Position     int64  `json:"position"`
+	CommentsView string `json:"comments_view"`
+}
+
+type categoryModeratorRequest struct {
+	UserID string `json:"user_id"`
 }
 
 func registerAdminCategory(router *httptreemux.Group) {
 	impl := &categoryImpl{}
 
-	router.POST("/categories", impl.create)
-	router.POST("/categories/:id", impl.update)
-	router.GET("/categories", impl.index)
-	router.GET("/categories/:id", impl.show)
+	router.POST("/categories", middlewares.Permitted(models.PermissionCategoryManage, impl.create))
+	router.POST("/categories/:id", middlewares.Permitted(models.PermissionCategoryManage, impl.update))
+	router.GET("/categories", middlewares.Permitted(models.PermissionCategoryManage, impl.index))
+	router.GET("/categories/:id", middlewares.Permitted(models.PermissionCategoryManage, impl.show))
+	router.POST("/categories/:id/moderators", middlewares.Permitted(models.PermissionCategoryManage, impl.addModerator))
+	router.DELETE("/categories/:id/moderators/:user_id", middlewares.Permitted(models.PermissionCategoryManage, impl.removeModerator))
 }
 
 func (impl *categoryImpl) create(w http.ResponseWriter, r *http.Request, _ map[string]string) {
@@ -63,6 +71,8 @@ func (impl *categoryImpl) update(w http.ResponseWriter, r *http.Request, params
 		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
 	} else if err := category.Update(r.Context(), body.Name, body.Alias, body.Description, body.Position); err != nil {
 		views.RenderErrorResponse(w, r, err)
+	} else if err := category.SetCommentsView(r.Context(), body.CommentsView); err != nil {
+		views.RenderErrorResponse(w, r, err)
 	} else {
 		views.RenderCategory(w, r, category)
 	}
@@ -78,3 +88,40 @@ func (impl *categoryImpl) show(w http.ResponseWriter, r *http.Request, params ma
 		views.RenderCategory(w, r, category)
 	}
 }
+
+func (impl *categoryImpl) addModerator(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body categoryModeratorRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if category, err := models.ReadCategory(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if category == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if user, err := models.ReadUser(r.Context(), body.UserID); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if user == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if err := category.AddModerator(r.Context(), user); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderCategory(w, r, category)
+	}
+}
+
+func (impl *categoryImpl) removeModerator(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if category, err := models.ReadCategory(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if category == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if user, err := models.ReadUser(r.Context(), params["user_id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if user == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if err := category.RemoveModerator(r.Context(), user); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderCategory(w, r, category)
+	}
+}
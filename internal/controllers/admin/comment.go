@@ -16,8 +16,9 @@ type commentImpl struct{}
 func registerAdminComment(router *httptreemux.Group) {
 	impl := &commentImpl{}
 
-	router.GET("/comments", impl.index)
-	router.DELETE("/comments/:id", impl.destroy)
+	router.GET("/comments", middlewares.Permitted(models.PermissionCommentEditAny, impl.index))
+	router.DELETE("/comments/:id", middlewares.Permitted(models.PermissionCommentEditAny, impl.destroy))
+	router.POST("/comments/:id/restore", middlewares.Permitted(models.PermissionCommentEditAny, impl.restore))
 }
 
 func (impl *commentImpl) destroy(w http.ResponseWriter, r *http.Request, params map[string]string) {
@@ -32,6 +33,18 @@ func (impl *commentImpl) destroy(w http.ResponseWriter, r *http.Request, params
 	}
 }
 
+func (impl *commentImpl) restore(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if comment, err := models.ReadComment(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if comment == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if err = comment.Restore(r.Context(), middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderComment(w, r, comment)
+	}
+}
+
 func (impl *commentImpl) index(w http.ResponseWriter, r *http.Request, params map[string]string) {
 	offset, _ := time.Parse(time.RFC3339Nano, r.URL.Query().Get("offset"))
 	if comments, err := models.ReadComments(r.Context(), offset, nil, nil); err != nil {
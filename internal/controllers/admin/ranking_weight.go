@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"satellity/internal/middlewares"
+	"satellity/internal/models"
+	"satellity/internal/session"
+	"satellity/internal/views"
+
+	"github.com/dimfeld/httptreemux"
+)
+
+type rankingWeightImpl struct{}
+
+func registerAdminRankingWeight(router *httptreemux.Group) {
+	impl := &rankingWeightImpl{}
+
+	router.GET("/ranking_weights", middlewares.Permitted(models.PermissionCategoryManage, impl.index))
+	router.POST("/ranking_weights", middlewares.Permitted(models.PermissionCategoryManage, impl.update))
+}
+
+func (impl *rankingWeightImpl) index(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if weights, err := models.ReadRankingWeights(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderRankingWeights(w, r, weights)
+	}
+}
+
+// update take the weights by name, e.g. {"likes":1,"gravity":1.8}
+func (impl *rankingWeightImpl) update(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body map[string]float64
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if weights, err := models.UpdateRankingWeights(r.Context(), body, middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderRankingWeights(w, r, weights)
+	}
+}
@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"satellity/internal/middlewares"
+	"satellity/internal/models"
+	"satellity/internal/session"
+	"satellity/internal/views"
+
+	"github.com/dimfeld/httptreemux"
+)
+
+type roleImpl struct{}
+
+type rolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+func registerAdminRole(router *httptreemux.Group) {
+	impl := &roleImpl{}
+
+	router.GET("/roles", middlewares.Permitted(models.PermissionUserManage, impl.index))
+	router.POST("/roles/:name", middlewares.Permitted(models.PermissionUserManage, impl.update))
+}
+
+func (impl *roleImpl) index(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if roles, err := models.ReadRoles(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderRoles(w, r, roles)
+	}
+}
+
+func (impl *roleImpl) update(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body rolePermissionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if role, err := models.ReadRole(r.Context(), params["name"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if role == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if err := role.UpdatePermissions(r.Context(), body.Permissions, middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderRole(w, r, role)
+	}
+}
@@ -11,4 +11,6 @@ func RegisterAdminRoutes(router *httptreemux.Group) {
 	registerAdminCategory(api)
 	registerAdminTopic(api)
 	registerAdminComment(api)
+	registerAdminRole(api)
+	registerAdminRankingWeight(api)
 }
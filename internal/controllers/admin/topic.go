@@ -16,8 +16,8 @@ type topicImpl struct{}
 func registerAdminTopic(router *httptreemux.Group) {
 	impl := &topicImpl{}
 
-	router.DELETE("/topics/:id", impl.destroy)
-	router.GET("/topics", impl.index)
+	router.DELETE("/topics/:id", middlewares.Permitted(models.PermissionTopicDelete, impl.destroy))
+	router.GET("/topics", middlewares.Permitted(models.PermissionTopicDelete, impl.index))
 }
 
 func (impl *topicImpl) destroy(w http.ResponseWriter, r *http.Request, params map[string]string) {
@@ -1,8 +1,11 @@
 package admin
 
 import (
+	"encoding/json"
 	"net/http"
+	"satellity/internal/middlewares"
 	"satellity/internal/models"
+	"satellity/internal/session"
 	"satellity/internal/views"
 	"time"
 
@@ -14,7 +17,26 @@ type userImpl struct{}
 func registerAdminUser(router *httptreemux.Group) {
 	impl := &userImpl{}
 
-	router.GET("/users", impl.index)
+	router.GET("/users", middlewares.Permitted(models.PermissionUserManage, impl.index))
+	router.DELETE("/users/:id/two_factor", middlewares.Permitted(models.PermissionUserManage, impl.resetTwoFactor))
+	router.POST("/users/:id/merge", middlewares.Permitted(models.PermissionUserManage, impl.merge))
+	router.POST("/users/:id/role", middlewares.Permitted(models.PermissionUserManage, impl.assignRole))
+	router.POST("/users/:id/suspend", middlewares.Permitted(models.PermissionUserBan, impl.suspend))
+	router.POST("/users/:id/unsuspend", middlewares.Permitted(models.PermissionUserBan, impl.unsuspend))
+	router.POST("/users/:id/password_reset", middlewares.Permitted(models.PermissionUserManage, impl.requirePasswordReset))
+}
+
+type mergeRequest struct {
+	SourceID string `json:"source_id"`
+}
+
+type roleRequest struct {
+	Role string `json:"role"`
+}
+
+type suspendRequest struct {
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason"`
 }
 
 func (impl *userImpl) index(w http.ResponseWriter, r *http.Request, _ map[string]string) {
@@ -23,6 +45,97 @@ func (impl *userImpl) index(w http.ResponseWriter, r *http.Request, _ map[string
 	if err != nil {
 		views.RenderErrorResponse(w, r, err)
 	} else {
-		views.RenderUsers(w, r, users)
+		views.RenderAdminUsers(w, r, users)
+	}
+}
+
+func (impl *userImpl) resetTwoFactor(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if user, err := models.ReadUser(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if user == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if err := user.ResetTwoFactor(r.Context(), middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderBlankResponse(w, r)
+	}
+}
+
+func (impl *userImpl) merge(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body mergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if user, err := models.ReadUser(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if user == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if source, err := models.ReadUser(r.Context(), body.SourceID); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if source == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if err := user.MergeUser(r.Context(), source, middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAdminUser(w, r, user)
+	}
+}
+
+func (impl *userImpl) assignRole(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body roleRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if user, err := models.ReadUser(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if user == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if err := user.AssignRole(r.Context(), body.Role, middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAdminUser(w, r, user)
+	}
+}
+
+func (impl *userImpl) suspend(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body suspendRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if user, err := models.ReadUser(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if user == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if err := user.Suspend(r.Context(), body.Until, body.Reason, middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAdminUser(w, r, user)
+	}
+}
+
+func (impl *userImpl) unsuspend(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if user, err := models.ReadUser(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if user == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if err := user.Unsuspend(r.Context(), middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAdminUser(w, r, user)
+	}
+}
+
+func (impl *userImpl) requirePasswordReset(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if user, err := models.ReadUser(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if user == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if err := user.RequirePasswordReset(r.Context(), middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAdminUser(w, r, user)
 	}
 }
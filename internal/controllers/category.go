@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"encoding/json"
 	"net/http"
+	"satellity/internal/middlewares"
 	"satellity/internal/models"
 	"satellity/internal/session"
 	"satellity/internal/views"
@@ -17,6 +19,8 @@ func registerCategory(router *httptreemux.Group) {
 
 	router.GET("/categories", impl.index)
 	router.GET("/categories/:id/topics", impl.topics)
+	router.GET("/categories/:id/subscription", middlewares.Authenticated(impl.subscription))
+	router.POST("/categories/:id/subscription", middlewares.Authenticated(impl.subscribe))
 }
 
 func (impl *categoryImpl) index(w http.ResponseWriter, r *http.Request, _ map[string]string) {
@@ -41,3 +45,34 @@ func (impl *categoryImpl) topics(w http.ResponseWriter, r *http.Request, params
 		views.RenderTopics(w, r, topics)
 	}
 }
+
+func (impl *categoryImpl) subscription(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if category, err := models.ReadCategory(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if category == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if s, err := category.ReadSubscription(r.Context(), middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderSubscription(w, r, s)
+	}
+}
+
+// subscribe set the level watching, tracking or muted of the topics in the
+// category, a blank level removes it
+func (impl *categoryImpl) subscribe(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body subscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if category, err := models.ReadCategory(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if category == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if s, err := category.Subscribe(r.Context(), middlewares.CurrentUser(r), body.Level); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderSubscription(w, r, s)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"satellity/internal/models"
 	"satellity/internal/session"
 	"satellity/internal/views"
+	"strconv"
 	"time"
 
 	"github.com/dimfeld/httptreemux"
@@ -15,16 +16,24 @@ import (
 type commentImpl struct{}
 
 type commentRequest struct {
-	TopicID string `json:"topic_id"`
-	Body    string `json:"body"`
+	TopicID         string `json:"topic_id"`
+	Body            string `json:"body"`
+	ParentCommentID string `json:"parent_comment_id"`
+}
+
+type commentVoteRequest struct {
+	Vote int `json:"vote"`
 }
 
 func registerComment(router *httptreemux.Group) {
 	impl := &commentImpl{}
 
-	router.POST("/comments", impl.create)
-	router.POST("/comments/:id", impl.update)
-	router.DELETE("/comments/:id", impl.destory)
+	router.POST("/comments", middlewares.Permitted(models.PermissionCommentCreate, impl.create))
+	router.POST("/comments/:id", middlewares.Authenticated(impl.update))
+	router.DELETE("/comments/:id", middlewares.Authenticated(impl.destory))
+	router.POST("/comments/:id/vote", middlewares.Authenticated(impl.vote))
+	router.GET("/comments/:id/revisions", impl.revisions)
+	router.GET("/comments/:id/replies", impl.replies)
 	router.GET("/topics/:id/comments", impl.comments)
 }
 
@@ -38,7 +47,7 @@ func (impl *commentImpl) create(w http.ResponseWriter, r *http.Request, _ map[st
 		views.RenderErrorResponse(w, r, err)
 	} else if topic == nil {
 		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
-	} else if comment, err := middlewares.CurrentUser(r).CreateComment(r.Context(), body.Body, topic); err != nil {
+	} else if comment, err := middlewares.CurrentUser(r).CreateReply(r.Context(), body.Body, topic, body.ParentCommentID); err != nil {
 		views.RenderErrorResponse(w, r, err)
 	} else {
 		views.RenderComment(w, r, comment)
@@ -74,15 +83,74 @@ func (impl *commentImpl) destory(w http.ResponseWriter, r *http.Request, params
 	}
 }
 
+func (impl *commentImpl) vote(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body commentVoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if comment, err := models.ReadComment(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if comment == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if err := comment.VoteBy(r.Context(), middlewares.CurrentUser(r), body.Vote); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderComment(w, r, comment)
+	}
+}
+
+// comments of a topic by created_at, offset is a time, or by score with
+// sort=best, offset is the count of comments to skip
 func (impl *commentImpl) comments(w http.ResponseWriter, r *http.Request, params map[string]string) {
-	offset, _ := time.Parse(time.RFC3339Nano, r.URL.Query().Get("offset"))
-	if topic, err := models.ReadTopic(r.Context(), params["id"]); err != nil {
+	query := r.URL.Query()
+	topic, err := models.ReadTopic(r.Context(), params["id"])
+	if err != nil {
 		views.RenderErrorResponse(w, r, err)
+		return
 	} else if topic == nil {
 		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
-	} else if comments, err := models.ReadComments(r.Context(), offset, topic, nil); err != nil {
+		return
+	}
+	var comments []*models.Comment
+	if query.Get("sort") == "best" {
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		comments, err = models.ReadBestComments(r.Context(), topic, offset)
+	} else {
+		offset, _ := time.Parse(time.RFC3339Nano, query.Get("offset"))
+		comments, err = models.ReadComments(r.Context(), offset, topic, nil)
+	}
+	if err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if err := models.FillCommentVotes(r.Context(), comments, middlewares.CurrentUser(r)); err != nil {
 		views.RenderErrorResponse(w, r, err)
 	} else {
 		views.RenderComments(w, r, comments)
 	}
 }
+
+func (impl *commentImpl) revisions(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if comment, err := models.ReadComment(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if comment == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if revisions, err := comment.ReadRevisions(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderCommentRevisions(w, r, revisions)
+	}
+}
+
+func (impl *commentImpl) replies(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if comment, err := models.ReadComment(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if comment == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if replies, err := comment.ReadReplies(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if err := models.FillCommentVotes(r.Context(), replies, middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderComments(w, r, replies)
+	}
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"net/http"
+	"satellity/internal/middlewares"
+	"satellity/internal/views"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+)
+
+type notificationImpl struct{}
+
+func registerNotification(router *httptreemux.Group) {
+	impl := &notificationImpl{}
+
+	router.GET("/notifications", middlewares.Authenticated(impl.index))
+	router.POST("/notifications/read", middlewares.Authenticated(impl.readAll))
+	router.POST("/notifications/:id/read", middlewares.Authenticated(impl.read))
+}
+
+func (impl *notificationImpl) index(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	offset, _ := time.Parse(time.RFC3339Nano, r.URL.Query().Get("offset"))
+	if notifications, err := middlewares.CurrentUser(r).ReadNotifications(r.Context(), offset); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderNotifications(w, r, notifications)
+	}
+}
+
+func (impl *notificationImpl) readAll(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if err := middlewares.CurrentUser(r).ReadAllNotifications(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderBlankResponse(w, r)
+	}
+}
+
+func (impl *notificationImpl) read(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if err := middlewares.CurrentUser(r).ReadNotification(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderBlankResponse(w, r)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"runtime"
 	"satellity/internal/configs"
 	"satellity/internal/controllers/admin"
+	"satellity/internal/oauth"
 	"satellity/internal/session"
 	"satellity/internal/views"
 
@@ -21,6 +22,8 @@ func RegisterRoutes(router *httptreemux.TreeMux) {
 	registerCategory(api)
 	registerTopic(api)
 	registerComment(api)
+	registerSearch(api)
+	registerNotification(api)
 	registerVerification(api)
 	admin.RegisterAdminRoutes(api)
 }
@@ -33,10 +36,29 @@ func health(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 
 func client(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 	config := configs.AppConfig
-	views.RenderResponse(w, r, map[string]string{
+	providers, err := oauth.Providers()
+	if err != nil {
+		views.RenderErrorResponse(w, r, session.ServerError(r.Context(), err))
+		return
+	}
+	oauthProviders := make([]map[string]string, 0, len(providers))
+	for _, p := range providers {
+		authorizeURL, err := p.AuthorizeURL(r.Context())
+		if err != nil {
+			session.Logger(r.Context()).Error(err)
+			continue
+		}
+		oauthProviders = append(oauthProviders, map[string]string{
+			"name":          p.Name(),
+			"client_id":     p.ClientID(),
+			"authorize_url": authorizeURL,
+		})
+	}
+	views.RenderResponse(w, r, map[string]interface{}{
 		"name":               config.Name,
 		"github_client_id":   config.Github.ClientID,
 		"recaptcha_site_key": config.Recaptcha.SiteKey,
+		"oauth_providers":    oauthProviders,
 	})
 }
 
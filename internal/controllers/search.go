@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"net/http"
+	"satellity/internal/models"
+	"satellity/internal/views"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+)
+
+type searchImpl struct{}
+
+func registerSearch(router *httptreemux.Group) {
+	impl := &searchImpl{}
+
+	router.GET("/search", impl.index)
+}
+
+// index search by q, filtered by type, category_id, user_id and the created_at
+// range from and to, cursor is the cursor of the last result of the previous page
+func (impl *searchImpl) index(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	query := r.URL.Query()
+	from, _ := time.Parse(time.RFC3339Nano, query.Get("from"))
+	to, _ := time.Parse(time.RFC3339Nano, query.Get("to"))
+	results, err := models.Search(r.Context(), models.SearchQuery{
+		Query:      query.Get("q"),
+		Type:       query.Get("type"),
+		CategoryID: query.Get("category_id"),
+		UserID:     query.Get("user_id"),
+		From:       from,
+		To:         to,
+		Cursor:     query.Get("cursor"),
+	})
+	if err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderSearchResults(w, r, results)
+	}
+}
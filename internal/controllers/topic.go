@@ -7,6 +7,7 @@ import (
 	"satellity/internal/models"
 	"satellity/internal/session"
 	"satellity/internal/views"
+	"strconv"
 	"time"
 
 	"github.com/dimfeld/httptreemux"
@@ -22,18 +23,29 @@ type topicRequest struct {
 	Draft      bool   `json:"draft"`
 }
 
+type subscriptionRequest struct {
+	Level string `json:"level"`
+}
+
 func registerTopic(router *httptreemux.Group) {
 	impl := &topicImpl{}
 
-	router.POST("/topics", impl.create)
-	router.POST("/topics/:id", impl.update)
-	router.POST("/topics/:id/like", impl.like)
-	router.POST("/topics/:id/unlike", impl.unlike)
-	router.POST("/topics/:id/bookmark", impl.bookmark)
-	router.POST("/topics/:id/unsave", impl.unsave)
+	router.POST("/topics", middlewares.Permitted(models.PermissionTopicCreate, impl.create))
+	router.POST("/topics/:id", middlewares.Authenticated(impl.update))
+	router.POST("/topics/:id/like", middlewares.Authenticated(impl.like))
+	router.POST("/topics/:id/unlike", middlewares.Authenticated(impl.unlike))
+	router.POST("/topics/:id/bookmark", middlewares.Authenticated(impl.bookmark))
+	router.POST("/topics/:id/unsave", middlewares.Authenticated(impl.unsave))
+	router.POST("/topics/:id/lock", middlewares.Authenticated(impl.lock))
+	router.POST("/topics/:id/unlock", middlewares.Authenticated(impl.unlock))
+	router.GET("/topics/:id/subscription", middlewares.Authenticated(impl.subscription))
+	router.POST("/topics/:id/subscription", middlewares.Authenticated(impl.subscribe))
+	router.POST("/topics/:id/revisions/:revision_id/revert", middlewares.Authenticated(impl.revert))
+	router.DELETE("/topics/:id", middlewares.Authenticated(impl.destroy))
 	router.GET("/topics", impl.index)
 	router.GET("/topics/draft", impl.draft)
 	router.GET("/topics/:id", impl.show)
+	router.GET("/topics/:id/revisions", impl.revisions)
 }
 
 func (impl *topicImpl) create(w http.ResponseWriter, r *http.Request, _ map[string]string) {
@@ -87,8 +99,20 @@ func (impl *topicImpl) show(w http.ResponseWriter, r *http.Request, params map[s
 	}
 }
 
+// index of topics by created_at, offset is a time, or with order=hot|top and
+// period=day|week|month|year|all, offset is the count of topics to skip
 func (impl *topicImpl) index(w http.ResponseWriter, r *http.Request, params map[string]string) {
-	offset, _ := time.Parse(time.RFC3339Nano, r.URL.Query().Get("offset"))
+	query := r.URL.Query()
+	if order := query.Get("order"); order != "" && order != models.TopicOrderNew {
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		if topics, err := models.ReadRankedTopics(r.Context(), order, query.Get("period"), offset); err != nil {
+			views.RenderErrorResponse(w, r, err)
+		} else {
+			views.RenderTopics(w, r, topics)
+		}
+		return
+	}
+	offset, _ := time.Parse(time.RFC3339Nano, query.Get("offset"))
 	if topics, err := models.ReadTopics(r.Context(), offset, nil, nil); err != nil {
 		views.RenderErrorResponse(w, r, err)
 	} else {
@@ -123,3 +147,89 @@ func (impl *topicImpl) action(w http.ResponseWriter, r *http.Request, id, action
 		views.RenderTopic(w, r, topic)
 	}
 }
+
+func (impl *topicImpl) lock(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	impl.lockAction(w, r, params["id"], true)
+}
+
+func (impl *topicImpl) unlock(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	impl.lockAction(w, r, params["id"], false)
+}
+
+func (impl *topicImpl) lockAction(w http.ResponseWriter, r *http.Request, id string, locked bool) {
+	if topic, err := models.ReadTopic(r.Context(), id); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if topic == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if err = topic.Lock(r.Context(), middlewares.CurrentUser(r), locked); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderTopic(w, r, topic)
+	}
+}
+
+func (impl *topicImpl) subscription(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if topic, err := models.ReadTopic(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if topic == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if s, err := topic.ReadSubscription(r.Context(), middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderSubscription(w, r, s)
+	}
+}
+
+// subscribe set the level watching, tracking or muted, a blank level follows the category
+func (impl *topicImpl) subscribe(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body subscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if topic, err := models.ReadTopic(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if topic == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if s, err := topic.Subscribe(r.Context(), middlewares.CurrentUser(r), body.Level); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderSubscription(w, r, s)
+	}
+}
+
+func (impl *topicImpl) destroy(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if topic, err := models.ReadTopic(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if topic == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if err := topic.Delete(r.Context(), middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderBlankResponse(w, r)
+	}
+}
+
+func (impl *topicImpl) revisions(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if topic, err := models.ReadTopic(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if topic == nil || topic.Draft {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if revisions, err := topic.ReadRevisions(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderTopicRevisions(w, r, revisions)
+	}
+}
+
+func (impl *topicImpl) revert(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if topic, err := models.ReadTopic(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else if topic == nil {
+		views.RenderErrorResponse(w, r, session.NotFoundError(r.Context()))
+	} else if topic, err = topic.Revert(r.Context(), params["revision_id"], middlewares.CurrentUser(r)); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderTopic(w, r, topic)
+	}
+}
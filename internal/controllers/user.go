@@ -15,22 +15,61 @@ import (
 type userImpl struct{}
 
 type userRequest struct {
-	Code          string `json:"code"`
-	SessionSecret string `json:"session_secret"`
-	Email         string `json:"email"`
-	Password      string `json:"password"`
-	Nickname      string `json:"nickname"`
-	Avatar        string `json:"avatar"`
-	Biography     string `json:"biography"`
+	Code           string   `json:"code"`
+	SessionSecret  string   `json:"session_secret"`
+	Email          string   `json:"email"`
+	Password       string   `json:"password"`
+	NewPassword    string   `json:"new_password"`
+	Nickname       string   `json:"nickname"`
+	Avatar         string   `json:"avatar"`
+	Biography      string   `json:"biography"`
+	Name           string   `json:"name"`
+	Scopes         []string `json:"scopes"`
+	Message        string   `json:"message"`
+	Signature      string   `json:"signature"`
+	VerificationID string   `json:"verification_id"`
+	Mode           string   `json:"mode"`
+	Replies        bool     `json:"replies"`
+	Mentions       bool     `json:"mentions"`
+	Digest         string   `json:"digest"`
+	Token          string   `json:"token"`
 }
 
 func registerUser(router *httptreemux.Group) {
 	impl := &userImpl{}
 
 	router.POST("/oauth/:provider", impl.oauth)
+	router.POST("/web3/nonces", impl.web3Nonce)
+	router.POST("/web3/sessions", impl.web3)
 	router.POST("/sessions", impl.create)
-	router.POST("/me", impl.update)
-	router.GET("/me", impl.me)
+	router.POST("/me", middlewares.Authenticated(impl.update))
+	router.GET("/me", middlewares.Authenticated(impl.me))
+	router.GET("/me/sessions", middlewares.Authenticated(impl.sessions))
+	router.DELETE("/me/sessions", middlewares.Authenticated(impl.destroySessions))
+	router.DELETE("/me/sessions/:id", middlewares.Authenticated(impl.destroySession))
+	router.GET("/me/tokens", middlewares.Authenticated(impl.tokens))
+	router.POST("/me/tokens", middlewares.Authenticated(impl.createToken))
+	router.DELETE("/me/tokens/:id", middlewares.Authenticated(impl.destroyToken))
+	router.GET("/me/two_factor", middlewares.Authenticated(impl.twoFactor))
+	router.POST("/me/two_factor", middlewares.Authenticated(impl.enrollTwoFactor))
+	router.POST("/me/two_factor/enable", middlewares.Authenticated(impl.enableTwoFactor))
+	router.POST("/me/two_factor/disable", middlewares.Authenticated(impl.disableTwoFactor))
+	router.GET("/me/identities", middlewares.Authenticated(impl.identities))
+	router.POST("/me/identities/:provider", middlewares.Authenticated(impl.linkIdentity))
+	router.DELETE("/me/identities/:id", middlewares.Authenticated(impl.unlinkIdentity))
+	router.POST("/me/wallet", middlewares.Authenticated(impl.linkWallet))
+	router.DELETE("/me/wallet", middlewares.Authenticated(impl.unlinkWallet))
+	router.POST("/me/email_links", middlewares.Authenticated(impl.createEmailLink))
+	router.POST("/me/email", middlewares.Authenticated(impl.linkEmail))
+	router.DELETE("/me/email", middlewares.Authenticated(impl.unlinkEmail))
+	router.POST("/me/email_changes", middlewares.Authenticated(impl.createEmailChange))
+	router.POST("/me/email_changes/:id", middlewares.Authenticated(impl.verifyEmailChange))
+	router.POST("/me/password", middlewares.Authenticated(impl.changePassword))
+	router.POST("/me/export", middlewares.Authenticated(impl.export))
+	router.DELETE("/me/account", middlewares.Authenticated(impl.destroyAccount))
+	router.GET("/me/email_preference", middlewares.Authenticated(impl.emailPreference))
+	router.POST("/me/email_preference", middlewares.Authenticated(impl.updateEmailPreference))
+	router.POST("/email_preference/unsubscribe", impl.unsubscribe)
 	router.GET("/users/:id", impl.show)
 	router.GET("/users/:id/topics", impl.topics)
 }
@@ -41,7 +80,28 @@ func (impl *userImpl) oauth(w http.ResponseWriter, r *http.Request, params map[s
 		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
 		return
 	}
-	if user, err := models.CreateGithubUser(r.Context(), body.Code, body.SessionSecret); err != nil {
+	if user, err := models.CreateOAuthUser(r.Context(), params["provider"], body.Code, body.SessionSecret); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAccount(w, r, user)
+	}
+}
+
+func (impl *userImpl) web3Nonce(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if nonce, err := models.CreateWeb3Nonce(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderWeb3Nonce(w, r, nonce, models.Web3Options())
+	}
+}
+
+func (impl *userImpl) web3(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if user, err := models.CreateWeb3User(r.Context(), body.Nickname, body.Message, body.Signature, body.SessionSecret); err != nil {
 		views.RenderErrorResponse(w, r, err)
 	} else {
 		views.RenderAccount(w, r, user)
@@ -54,7 +114,7 @@ func (impl *userImpl) create(w http.ResponseWriter, r *http.Request, _ map[strin
 		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
 		return
 	}
-	if user, err := models.CreateSession(r.Context(), body.Email, body.Password, body.SessionSecret); err != nil {
+	if user, err := models.CreateSession(r.Context(), body.Email, body.Password, body.Code, body.SessionSecret); err != nil {
 		views.RenderErrorResponse(w, r, err)
 	} else {
 		views.RenderAccount(w, r, user)
@@ -76,7 +136,298 @@ func (impl *userImpl) update(w http.ResponseWriter, r *http.Request, _ map[strin
 }
 
 func (impl *userImpl) me(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	views.RenderAccount(w, r, middlewares.CurrentUser(r))
+	current := middlewares.CurrentUser(r)
+	if err := current.CountUnreadNotifications(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAccount(w, r, current)
+	}
+}
+
+func (impl *userImpl) export(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if archive, err := middlewares.CurrentUser(r).Export(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderUserArchive(w, r, archive)
+	}
+}
+
+func (impl *userImpl) destroyAccount(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if err := middlewares.CurrentUser(r).DeleteAccount(r.Context(), body.Mode, body.Password); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderBlankResponse(w, r)
+	}
+}
+
+func (impl *userImpl) emailPreference(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if p, err := middlewares.CurrentUser(r).ReadEmailPreference(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderEmailPreference(w, r, p)
+	}
+}
+
+func (impl *userImpl) updateEmailPreference(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if p, err := middlewares.CurrentUser(r).UpdateEmailPreference(r.Context(), body.Replies, body.Mentions, body.Digest); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderEmailPreference(w, r, p)
+	}
+}
+
+// unsubscribe by the token of an unsubscribe link, without sign in
+func (impl *userImpl) unsubscribe(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if p, err := models.UnsubscribeEmail(r.Context(), body.Token); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderEmailPreference(w, r, p)
+	}
+}
+
+func (impl *userImpl) sessions(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	current := middlewares.CurrentUser(r)
+	if sessions, err := current.ReadSessions(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderSessions(w, r, current, sessions)
+	}
+}
+
+func (impl *userImpl) destroySession(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if err := middlewares.CurrentUser(r).DeleteSession(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderBlankResponse(w, r)
+	}
+}
+
+func (impl *userImpl) destroySessions(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if err := middlewares.CurrentUser(r).DeleteSessions(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderBlankResponse(w, r)
+	}
+}
+
+func (impl *userImpl) tokens(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if tokens, err := middlewares.CurrentUser(r).ReadAPITokens(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAPITokens(w, r, tokens)
+	}
+}
+
+func (impl *userImpl) createToken(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if token, err := middlewares.CurrentUser(r).CreateAPIToken(r.Context(), body.Name, body.Scopes); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAPIToken(w, r, token)
+	}
+}
+
+func (impl *userImpl) destroyToken(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if err := middlewares.CurrentUser(r).DeleteAPIToken(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderBlankResponse(w, r)
+	}
+}
+
+func (impl *userImpl) twoFactor(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	current := middlewares.CurrentUser(r)
+	if tf, err := current.ReadTwoFactor(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderTwoFactor(w, r, current, tf)
+	}
+}
+
+func (impl *userImpl) enrollTwoFactor(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	current := middlewares.CurrentUser(r)
+	if tf, err := current.EnrollTwoFactor(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderTwoFactor(w, r, current, tf)
+	}
+}
+
+func (impl *userImpl) enableTwoFactor(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	current := middlewares.CurrentUser(r)
+	if tf, err := current.EnableTwoFactor(r.Context(), body.Code); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderTwoFactor(w, r, current, tf)
+	}
+}
+
+func (impl *userImpl) disableTwoFactor(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if err := middlewares.CurrentUser(r).DisableTwoFactor(r.Context(), body.Code); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderBlankResponse(w, r)
+	}
+}
+
+func (impl *userImpl) identities(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if identities, err := middlewares.CurrentUser(r).ReadIdentities(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderIdentities(w, r, identities)
+	}
+}
+
+func (impl *userImpl) linkIdentity(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	if identity, err := middlewares.CurrentUser(r).LinkIdentity(r.Context(), params["provider"], body.Code); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderIdentity(w, r, identity)
+	}
+}
+
+func (impl *userImpl) unlinkIdentity(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if err := middlewares.CurrentUser(r).UnlinkIdentity(r.Context(), params["id"]); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderBlankResponse(w, r)
+	}
+}
+
+func (impl *userImpl) linkWallet(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	current := middlewares.CurrentUser(r)
+	if err := current.LinkWallet(r.Context(), body.Message, body.Signature); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAccount(w, r, current)
+	}
+}
+
+func (impl *userImpl) unlinkWallet(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	current := middlewares.CurrentUser(r)
+	if err := current.UnlinkWallet(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAccount(w, r, current)
+	}
+}
+
+func (impl *userImpl) createEmailLink(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	current := middlewares.CurrentUser(r)
+	if verification, err := current.CreateEmailLink(r.Context(), body.Email); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderVerification(w, r, verification)
+	}
+}
+
+func (impl *userImpl) linkEmail(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	current := middlewares.CurrentUser(r)
+	if err := current.LinkEmail(r.Context(), body.VerificationID, body.Code, body.Password); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAccount(w, r, current)
+	}
+}
+
+func (impl *userImpl) unlinkEmail(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	current := middlewares.CurrentUser(r)
+	if err := current.UnlinkEmail(r.Context()); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAccount(w, r, current)
+	}
+}
+
+func (impl *userImpl) createEmailChange(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	current := middlewares.CurrentUser(r)
+	if verification, err := current.CreateEmailChange(r.Context(), body.Email, body.Password); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderVerification(w, r, verification)
+	}
+}
+
+func (impl *userImpl) verifyEmailChange(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	current := middlewares.CurrentUser(r)
+	if err := current.ChangeEmail(r.Context(), params["id"], body.Code); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAccount(w, r, current)
+	}
+}
+
+func (impl *userImpl) changePassword(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body userRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		views.RenderErrorResponse(w, r, session.BadRequestError(r.Context()))
+		return
+	}
+	current := middlewares.CurrentUser(r)
+	if err := current.ChangePassword(r.Context(), body.Password, body.NewPassword); err != nil {
+		views.RenderErrorResponse(w, r, err)
+	} else {
+		views.RenderAccount(w, r, current)
+	}
 }
 
 func (impl *userImpl) show(w http.ResponseWriter, r *http.Request, params map[string]string) {
@@ -10,11 +10,6 @@ import (
 	"github.com/dimfeld/httptreemux"
 )
 
-const (
-	purposeUser     = "USER"
-	purposePassword = "PASSWORD"
-)
-
 type verificationImpl struct{}
 
 type verificationRequest struct {
@@ -24,6 +19,7 @@ type verificationRequest struct {
 	Username      string `json:"username"`
 	Password      string `json:"password"`
 	Purpose       string `json:"purpose"`
+	TwoFactorCode string `json:"two_factor_code"`
 	SessionSecret string `json:"session_secret"`
 }
 
@@ -55,14 +51,14 @@ func (impl *verificationImpl) verify(w http.ResponseWriter, r *http.Request, par
 	}
 
 	switch body.Purpose {
-	case purposeUser:
-		user, err := models.VerifyEmailVerification(r.Context(), params["id"], body.Code, body.Username, body.Password, body.SessionSecret)
+	case models.EmailVerificationPurposeUser:
+		user, err := models.VerifyEmailVerification(r.Context(), params["id"], body.Code, body.Username, body.Password, body.TwoFactorCode, body.SessionSecret)
 		if err != nil {
 			views.RenderErrorResponse(w, r, err)
 		} else {
 			views.RenderAccount(w, r, user)
 		}
-	case purposePassword:
+	case models.EmailVerificationPurposePassword:
 		err := models.Reset(r.Context(), params["id"], body.Code, body.Password)
 		if err != nil {
 			views.RenderErrorResponse(w, r, err)
@@ -84,6 +84,41 @@ func (d *Database) RunInTransaction(ctx context.Context, fn func(pgx.Tx) error)
 	return tx.Commit(ctx)
 }
 
+// RunInReadCommittedTransaction run a query in a read committed transaction, for
+// hot rows like counters, which are serialized by row locks instead of failing
+// on concurrent updates
+func (d *Database) RunInReadCommittedTransaction(ctx context.Context, fn func(pgx.Tx) error) error {
+	tx, err := d.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RunWithAdvisoryLock run fn only if the session level advisory lock of key is
+// acquired, for the background jobs which should run on one instance at a time.
+// It returns false without running fn if another session holds the lock.
+func (d *Database) RunWithAdvisoryLock(ctx context.Context, key int64, fn func() error) (bool, error) {
+	conn, err := d.db.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	var locked bool
+	err = conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked)
+	if err != nil || !locked {
+		return false, err
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+	return true, fn()
+}
+
 // PrepareColumnsAndExpressions prepare columns and placeholders
 func PrepareColumnsAndExpressions(columns []string, offset int) (string, string) {
 	if len(columns) < 1 {
@@ -0,0 +1,133 @@
+package durable
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Limiter is a token bucket store, the bucket of a key holds burst tokens and
+// refills rate tokens per second. It's implemented by the generic cell rate
+// algorithm, which keeps only the theoretical arrival time (tat) of each key.
+type Limiter interface {
+	Take(ctx context.Context, key string, rate float64, burst int) (Allowance, error)
+}
+
+// Allowance is the result of taking a token
+type Allowance struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+const limiterSweepInterval = time.Minute
+
+func newAllowance(tat, now time.Time, interval time.Duration, burst int, allowed bool) Allowance {
+	a := Allowance{Allowed: allowed, Limit: burst}
+	if d := tat.Sub(now); d > 0 {
+		a.Reset = d
+	}
+	tau := interval * time.Duration(burst)
+	if allowed {
+		a.Remaining = int((tau - a.Reset) / interval)
+	} else {
+		a.RetryAfter = a.Reset - tau + interval
+	}
+	return a
+}
+
+func emissionInterval(rate float64) time.Duration {
+	return time.Duration(float64(time.Second) / rate)
+}
+
+// MemoryLimiter keeps the buckets in the process
+type MemoryLimiter struct {
+	mutex sync.Mutex
+	tats  map[string]time.Time
+	swept time.Time
+}
+
+// NewMemoryLimiter create a *MemoryLimiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{tats: make(map[string]time.Time), swept: time.Now()}
+}
+
+// Take a token from the bucket of key
+func (l *MemoryLimiter) Take(ctx context.Context, key string, rate float64, burst int) (Allowance, error) {
+	now := time.Now()
+	interval := emissionInterval(rate)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if now.Sub(l.swept) > limiterSweepInterval {
+		for k, tat := range l.tats {
+			if tat.Before(now) {
+				delete(l.tats, k)
+			}
+		}
+		l.swept = now
+	}
+
+	tat := l.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+	tat = tat.Add(interval)
+	if tat.Sub(now) > interval*time.Duration(burst) {
+		return newAllowance(l.tats[key], now, interval, burst, false), nil
+	}
+	l.tats[key] = tat
+	return newAllowance(tat, now, interval, burst, true), nil
+}
+
+// PostgresLimiter keeps the buckets in the rate_limits table, which are shared
+// by all instances
+type PostgresLimiter struct {
+	db    *Database
+	mutex sync.Mutex
+	swept time.Time
+}
+
+// NewPostgresLimiter create a *PostgresLimiter
+func NewPostgresLimiter(db *Database) *PostgresLimiter {
+	return &PostgresLimiter{db: db, swept: time.Now()}
+}
+
+// Take a token from the bucket of key, in a single statement without a transaction
+func (l *PostgresLimiter) Take(ctx context.Context, key string, rate float64, burst int) (Allowance, error) {
+	now := time.Now()
+	interval := emissionInterval(rate)
+	if err := l.sweep(ctx, now); err != nil {
+		return Allowance{}, err
+	}
+
+	var tat time.Time
+	query := "INSERT INTO rate_limits AS r (limit_key,tat) VALUES ($1,$2::timestamptz+$3*INTERVAL '1 microsecond') ON CONFLICT (limit_key) DO UPDATE SET tat=GREATEST(r.tat,$2)+$3*INTERVAL '1 microsecond' WHERE GREATEST(r.tat,$2)+$3*INTERVAL '1 microsecond'<=$4 RETURNING tat"
+	err := l.db.QueryRow(ctx, query, key, now, interval.Microseconds(), now.Add(interval*time.Duration(burst))).Scan(&tat)
+	if err == nil {
+		return newAllowance(tat, now, interval, burst, true), nil
+	} else if err != pgx.ErrNoRows {
+		return Allowance{}, err
+	}
+	err = l.db.QueryRow(ctx, "SELECT tat FROM rate_limits WHERE limit_key=$1", key).Scan(&tat)
+	if err != nil && err != pgx.ErrNoRows {
+		return Allowance{}, err
+	}
+	return newAllowance(tat, now, interval, burst, false), nil
+}
+
+func (l *PostgresLimiter) sweep(ctx context.Context, now time.Time) error {
+	l.mutex.Lock()
+	if now.Sub(l.swept) < limiterSweepInterval {
+		l.mutex.Unlock()
+		return nil
+	}
+	l.swept = now
+	l.mutex.Unlock()
+	_, err := l.db.Exec(ctx, "DELETE FROM rate_limits WHERE tat<$1", now)
+	return err
+}
@@ -0,0 +1,39 @@
+package durable
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	limiter := NewMemoryLimiter()
+	for i := 0; i < 3; i++ {
+		a, err := limiter.Take(ctx, "key", 1, 3)
+		assert.Nil(err)
+		assert.True(a.Allowed)
+		assert.Equal(3, a.Limit)
+		assert.Equal(2-i, a.Remaining)
+	}
+	a, err := limiter.Take(ctx, "key", 1, 3)
+	assert.Nil(err)
+	assert.False(a.Allowed)
+	assert.Equal(0, a.Remaining)
+	assert.True(a.RetryAfter > 0 && a.RetryAfter <= time.Second)
+	assert.True(a.Reset > 2*time.Second && a.Reset <= 3*time.Second)
+
+	a, err = limiter.Take(ctx, "other", 1, 3)
+	assert.Nil(err)
+	assert.True(a.Allowed)
+
+	limiter.tats["key"] = time.Now().Add(2 * time.Second)
+	a, err = limiter.Take(ctx, "key", 1, 3)
+	assert.Nil(err)
+	assert.True(a.Allowed)
+	assert.Equal(0, a.Remaining)
+}
@@ -8,27 +8,21 @@ import (
 	"satellity/internal/session"
 	"satellity/internal/views"
 	"strings"
-)
 
-var whitelist = [][2]string{
-	{"GET", "^/api/_hc$"},
-	{"GET", "^/api/products"},
-	{"GET", "^/api/categories"},
-	{"GET", "^/api/client"},
-	{"GET", "^/api/topics"},
-	{"GET", "^/api/users"},
-	{"POST", "^/api/oauth"},
-	{"POST", "^/api/sessions"},
-	{"POST", "^/api/email_verifications"},
-}
+	"github.com/dimfeld/httptreemux"
+)
 
-var userWhitelist = [][2]string{
-	{"GET", "^/api/me"},
-	{"POST", "^/api/comments"},
-	{"DELETE", "^/api/comments"},
-	{"POST", "^/api/topics"},
-	{"POST", "^/api/me"},
-	{"GET", "^/api/user"},
+// credentials and the account itself can't be managed by api tokens
+var apiTokenBlacklist = []string{
+	"^/api/me/sessions",
+	"^/api/me/tokens",
+	"^/api/me/two_factor",
+	"^/api/me/identities",
+	"^/api/me/wallet",
+	"^/api/me/email",
+	"^/api/me/password",
+	"^/api/me/export",
+	"^/api/me/account",
 }
 
 type contextValueKey int
@@ -41,56 +35,75 @@ func CurrentUser(r *http.Request) *models.User {
 	return user
 }
 
-// Authenticate handle routes by user's role
+// Authenticate read the current user from the Authorization header, routes
+// declare what they require with Authenticated and Permitted
 func Authenticate(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		header := r.Header.Get("Authorization")
 		if !strings.HasPrefix(header, "Bearer ") {
-			handleUnauthorized(handler, w, r)
+			handler.ServeHTTP(w, r)
 			return
 		}
-		user, err := models.AuthenticateUser(r.Context(), header[7:])
+		authenticate := models.AuthenticateUser
+		if strings.HasPrefix(header[7:], models.APITokenPrefix) {
+			authenticate = models.AuthenticateAPIToken
+		}
+		user, err := authenticate(r.Context(), header[7:])
 		if err != nil {
 			views.RenderErrorResponse(w, r, err)
 			return
 		}
 		if user == nil {
-			handleUnauthorized(handler, w, r)
+			handler.ServeHTTP(w, r)
 			return
 		}
-		ctx := context.WithValue(r.Context(), keyCurrentUser, user)
-		if user.GetRole() != models.UserRoleAdmin {
-			handleUserRouters(handler, w, r.WithContext(ctx))
+		if user.APIToken != nil && !permitAPIToken(user.APIToken, r) {
+			views.RenderErrorResponse(w, r, session.ForbiddenError(r.Context()))
 			return
 		}
+		ctx := context.WithValue(r.Context(), keyCurrentUser, user)
 		handler.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func handleUnauthorized(handler http.Handler, w http.ResponseWriter, r *http.Request) {
-	for _, pp := range whitelist {
-		if pp[0] != r.Method {
-			continue
-		}
-		if matched, _ := regexp.MatchString(pp[1], strings.ToLower(r.URL.Path)); matched {
-			handler.ServeHTTP(w, r)
+// Authenticated requires a signed in user
+func Authenticated(handler httptreemux.HandlerFunc) httptreemux.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if CurrentUser(r) == nil {
+			views.RenderErrorResponse(w, r, session.AuthorizationError(r.Context()))
 			return
 		}
+		handler(w, r, params)
 	}
-
-	views.RenderErrorResponse(w, r, session.AuthorizationError(r.Context()))
 }
 
-func handleUserRouters(handler http.Handler, w http.ResponseWriter, r *http.Request) {
-	for _, pp := range userWhitelist {
-		if pp[0] != r.Method {
-			continue
+// Permitted requires a signed in user whose role grants the permission
+func Permitted(permission string, handler httptreemux.HandlerFunc) httptreemux.HandlerFunc {
+	return Authenticated(func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if can, err := CurrentUser(r).Can(r.Context(), permission); err != nil {
+			views.RenderErrorResponse(w, r, err)
+		} else if !can {
+			views.RenderErrorResponse(w, r, session.ForbiddenError(r.Context()))
+		} else {
+			handler(w, r, params)
 		}
-		if matched, _ := regexp.MatchString(pp[1], strings.ToLower(r.URL.Path)); matched {
-			handler.ServeHTTP(w, r)
-			return
+	})
+}
+
+// permitAPIToken maps token scopes to routes: read for GET, post for
+// other methods, and moderate for admin routes
+func permitAPIToken(token *models.APIToken, r *http.Request) bool {
+	path := strings.ToLower(r.URL.Path)
+	for _, p := range apiTokenBlacklist {
+		if matched, _ := regexp.MatchString(p, path); matched {
+			return false
 		}
 	}
-
-	handleUnauthorized(handler, w, r)
+	if strings.HasPrefix(path, "/api/admin") && !token.HasScope(models.APITokenScopeModerate) {
+		return false
+	}
+	if r.Method == "GET" {
+		return token.HasScope(models.APITokenScopeRead)
+	}
+	return token.HasScope(models.APITokenScopePost) || token.HasScope(models.APITokenScopeModerate)
 }
@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"net"
 	"net/http"
 	"satellity/internal/durable"
 	"satellity/internal/session"
@@ -13,6 +14,16 @@ func Context(handler http.Handler, d *durable.Database, r *render.Render) http.H
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		ctx := session.WithDatabase(req.Context(), d)
 		ctx = session.WithRender(ctx, r)
+		ctx = session.WithRemoteAddr(ctx, remoteAddr(req))
 		handler.ServeHTTP(w, req.WithContext(ctx))
 	})
 }
+
+// remoteAddr is the client ip, ProxyHeaders has rewritten RemoteAddr behind a trusted proxy
+func remoteAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/handlers"
+)
+
+var forwardedHeaders = []string{"Forwarded", "X-Forwarded-For", "X-Real-Ip", "X-Forwarded-Proto", "X-Forwarded-Scheme", "X-Forwarded-Host"}
+
+// ProxyHeaders rewrite the request by the forwarded headers only if the
+// connection comes from a trusted proxy, an ip or a CIDR, otherwise the headers
+// are dropped. The client ip is the last address in X-Forwarded-For which isn't
+// a trusted proxy, the ones before it can be forged by the client.
+func ProxyHeaders(handler http.Handler, trusted []string) http.Handler {
+	networks := make([]*net.IPNet, len(trusted))
+	for i, t := range trusted {
+		if !strings.Contains(t, "/") {
+			if ip := net.ParseIP(t); ip != nil && ip.To4() != nil {
+				t = t + "/32"
+			} else {
+				t = t + "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(t)
+		if err != nil {
+			panic(fmt.Errorf("invalid trusted proxy %s", trusted[i]))
+		}
+		networks[i] = n
+	}
+	isTrusted := func(addr string) bool {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return false
+		}
+		for _, n := range networks {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	proxied := handlers.ProxyHeaders(handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if !isTrusted(host) {
+			for _, h := range forwardedHeaders {
+				r.Header.Del(h)
+			}
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		client := r.Header.Get("X-Real-Ip")
+		var hops []string
+		for _, v := range r.Header.Values("X-Forwarded-For") {
+			hops = append(hops, strings.Split(v, ",")...)
+		}
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			client = hop
+			if !isTrusted(hop) {
+				break
+			}
+		}
+		if net.ParseIP(client) != nil {
+			r.RemoteAddr = client
+		}
+		// the client ip is resolved already, ProxyHeaders only rewrites the scheme and host
+		r.Header.Del("Forwarded")
+		r.Header.Del("X-Forwarded-For")
+		r.Header.Del("X-Real-Ip")
+		proxied.ServeHTTP(w, r)
+	})
+}
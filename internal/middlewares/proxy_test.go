@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	var addr, scheme string
+	handler := ProxyHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addr, scheme = remoteAddr(r), r.URL.Scheme
+	}), []string{"127.0.0.1", "10.0.0.0/8"})
+
+	testCases := []struct {
+		remote    string
+		forwarded []string
+		realIP    string
+		proto     string
+		addr      string
+		scheme    string
+	}{
+		{"203.0.113.7:4321", []string{"198.51.100.1"}, "198.51.100.2", "https", "203.0.113.7", ""},
+		{"127.0.0.1:4321", nil, "", "", "127.0.0.1", ""},
+		{"127.0.0.1:4321", nil, "198.51.100.2", "https", "198.51.100.2", "https"},
+		{"127.0.0.1:4321", []string{"198.51.100.1, 203.0.113.7"}, "203.0.113.7", "", "203.0.113.7", ""},
+		{"127.0.0.1:4321", []string{"198.51.100.1", "203.0.113.7, 10.0.0.2"}, "", "", "203.0.113.7", ""},
+		{"127.0.0.1:4321", []string{"10.0.0.3, 10.0.0.2"}, "", "", "10.0.0.3", ""},
+		{"127.0.0.1:4321", []string{"unknown"}, "", "", "127.0.0.1", ""},
+	}
+	for _, tc := range testCases {
+		r := httptest.NewRequest("GET", "/api/me", nil)
+		r.RemoteAddr = tc.remote
+		for _, f := range tc.forwarded {
+			r.Header.Add("X-Forwarded-For", f)
+		}
+		if tc.realIP != "" {
+			r.Header.Set("X-Real-IP", tc.realIP)
+		}
+		if tc.proto != "" {
+			r.Header.Set("X-Forwarded-Proto", tc.proto)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		assert.Equal(tc.addr, addr, tc.remote, tc.forwarded)
+		assert.Equal(tc.scheme, scheme)
+	}
+
+	assert.Panics(func() { ProxyHeaders(handler, []string{"localhost"}) })
+}
@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"satellity/internal/configs"
+	"satellity/internal/durable"
+	"satellity/internal/session"
+	"satellity/internal/views"
+	"strings"
+	"time"
+)
+
+type rateLimitGroup struct {
+	name    string
+	methods map[string]bool
+	paths   []*regexp.Regexp
+	rate    float64
+	burst   int
+}
+
+func (g *rateLimitGroup) match(r *http.Request) bool {
+	if len(g.methods) > 0 && !g.methods[r.Method] {
+		return false
+	}
+	if len(g.paths) == 0 {
+		return true
+	}
+	for _, p := range g.paths {
+		if p.MatchString(r.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimit throttle requests by the bucket of the first matched group, the
+// bucket is per user, or per ip for anonymous requests. It must be wrapped by
+// Authenticate to know the current user.
+func RateLimit(handler http.Handler, limiter durable.Limiter, options []configs.RateLimitGroup) http.Handler {
+	groups := make([]*rateLimitGroup, len(options))
+	for i, c := range options {
+		if c.Rate <= 0 || c.Burst < 1 {
+			panic(fmt.Errorf("invalid rate limit group %s", c.Name))
+		}
+		g := &rateLimitGroup{name: c.Name, methods: make(map[string]bool), rate: c.Rate, burst: c.Burst}
+		for _, m := range c.Methods {
+			g.methods[strings.ToUpper(m)] = true
+		}
+		for _, p := range c.Paths {
+			g.paths = append(g.paths, regexp.MustCompile(p))
+		}
+		groups[i] = g
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var group *rateLimitGroup
+		for _, g := range groups {
+			if g.match(r) {
+				group = g
+				break
+			}
+		}
+		if group == nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		key := fmt.Sprintf("%s:ip:%s", group.name, session.RemoteAddr(r.Context()))
+		if user := CurrentUser(r); user != nil {
+			key = fmt.Sprintf("%s:user:%s", group.name, user.UserID)
+		}
+		a, err := limiter.Take(r.Context(), key, group.rate, group.burst)
+		if err != nil {
+			// the limiter fails open and the error is only logged. It guards
+			// against abuse rather than grants access, so an outage of its
+			// store shouldn't take down the whole site, and logins and
+			// verification codes keep their own attempt locks in the database.
+			session.ServerError(r.Context(), err)
+			handler.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("RateLimit-Limit", fmt.Sprint(a.Limit))
+		w.Header().Set("RateLimit-Remaining", fmt.Sprint(a.Remaining))
+		w.Header().Set("RateLimit-Reset", fmt.Sprint(seconds(a.Reset)))
+		if !a.Allowed {
+			w.Header().Set("Retry-After", fmt.Sprint(seconds(a.RetryAfter)))
+			views.RenderErrorResponse(w, r, session.TooManyRequestsError(r.Context()))
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
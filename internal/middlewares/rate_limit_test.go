@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"satellity/internal/configs"
+	"satellity/internal/durable"
+	"satellity/internal/session"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/unrolled/render"
+)
+
+type stubLimiter struct {
+	keys      []string
+	allowance durable.Allowance
+	err       error
+}
+
+func (l *stubLimiter) Take(ctx context.Context, key string, rate float64, burst int) (durable.Allowance, error) {
+	l.keys = append(l.keys, key)
+	return l.allowance, l.err
+}
+
+func TestRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	groups := []configs.RateLimitGroup{
+		{Name: "write", Methods: []string{"post"}, Paths: []string{"^/api/topics"}, Rate: 1, Burst: 3},
+	}
+	limiter := &stubLimiter{}
+	served := 0
+	handler := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+	}), limiter, groups)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		ctx := session.WithRender(r.Context(), render.New())
+		ctx = session.WithRemoteAddr(ctx, "203.0.113.7")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r.WithContext(ctx))
+		return w
+	}
+
+	w := serve("GET", "/api/topics")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal(1, served)
+	assert.Len(limiter.keys, 0)
+
+	limiter.allowance = durable.Allowance{Allowed: true, Limit: 3, Remaining: 2, Reset: 1500 * time.Millisecond}
+	w = serve("POST", "/api/topics")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal(2, served)
+	assert.Equal([]string{"write:ip:203.0.113.7"}, limiter.keys)
+	assert.Equal("3", w.Header().Get("RateLimit-Limit"))
+	assert.Equal("2", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal("2", w.Header().Get("RateLimit-Reset"))
+	assert.Equal("", w.Header().Get("Retry-After"))
+
+	limiter.allowance = durable.Allowance{Allowed: false, Limit: 3, RetryAfter: 300 * time.Millisecond, Reset: 3 * time.Second}
+	w = serve("POST", "/api/topics")
+	assert.Equal(http.StatusTooManyRequests, w.Code)
+	assert.Equal(2, served)
+	assert.Equal("0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal("1", w.Header().Get("Retry-After"))
+	assert.Contains(w.Body.String(), "429")
+
+	limiter.err = errors.New("database is down")
+	w = serve("POST", "/api/topics")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal(3, served)
+	assert.Equal("", w.Header().Get("RateLimit-Limit"))
+
+	assert.Panics(func() {
+		RateLimit(handler, limiter, []configs.RateLimitGroup{{Name: "invalid", Rate: 0, Burst: 1}})
+	})
+}
@@ -0,0 +1,220 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"satellity/internal/durable"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// advisoryLockID keeps two instances from migrating the same database at once
+const advisoryLockID = 7219346501
+
+const createSchemaMigrationsDDL = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+  version               BIGINT PRIMARY KEY,
+  name                  VARCHAR(256) NOT NULL,
+  applied_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+`
+
+//go:embed sql/*.sql
+var files embed.FS
+
+var fileRegexp = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a numbered schema change, every up has a matching down
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration with the time it was applied, AppliedAt is invalid when pending
+type Status struct {
+	*Migration
+	AppliedAt sql.NullTime
+}
+
+// Load read all embedded migrations ordered by version
+func Load() ([]*Migration, error) {
+	return load(files, "sql")
+}
+
+func load(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[int64]*Migration)
+	for _, entry := range entries {
+		matches := fileRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m := set[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: matches[2]}
+			set[version] = m
+		}
+		if m.Name != matches[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, m.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(set))
+	for _, m := range set {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s requires both up and down", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up apply all pending migrations, returns the applied ones
+func Up(ctx context.Context, db *durable.Database) ([]*Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	var applied []*Migration
+	for _, m := range migrations {
+		var done bool
+		err := db.RunInTransaction(ctx, func(tx pgx.Tx) error {
+			versions, err := prepare(ctx, tx)
+			if err != nil {
+				return err
+			}
+			if _, ok := versions[m.Version]; ok {
+				return nil
+			}
+			if _, err := tx.Exec(ctx, m.Up); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+			}
+			_, err = tx.Exec(ctx, "INSERT INTO schema_migrations(version,name,applied_at) VALUES ($1,$2,$3)", m.Version, m.Name, time.Now())
+			done = true
+			return err
+		})
+		if err != nil {
+			return applied, err
+		}
+		if done {
+			applied = append(applied, m)
+		}
+	}
+	return applied, nil
+}
+
+// Down roll back the latest applied migrations, steps less than 0 rolls back all
+func Down(ctx context.Context, db *durable.Database, steps int) ([]*Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	var reverted []*Migration
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if steps >= 0 && len(reverted) >= steps {
+			break
+		}
+		m := migrations[i]
+		var done bool
+		err := db.RunInTransaction(ctx, func(tx pgx.Tx) error {
+			versions, err := prepare(ctx, tx)
+			if err != nil {
+				return err
+			}
+			if _, ok := versions[m.Version]; !ok {
+				return nil
+			}
+			if _, err := tx.Exec(ctx, m.Down); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+			}
+			_, err = tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version=$1", m.Version)
+			done = true
+			return err
+		})
+		if err != nil {
+			return reverted, err
+		}
+		if done {
+			reverted = append(reverted, m)
+		}
+	}
+	return reverted, nil
+}
+
+// ReadStatus read all migrations with their applied time
+func ReadStatus(ctx context.Context, db *durable.Database) ([]*Status, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	var versions map[int64]time.Time
+	err = db.RunInTransaction(ctx, func(tx pgx.Tx) error {
+		versions, err = prepare(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]*Status, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = &Status{Migration: m}
+		if t, ok := versions[m.Version]; ok {
+			statuses[i].AppliedAt = sql.NullTime{Time: t, Valid: true}
+		}
+	}
+	return statuses, nil
+}
+
+func prepare(ctx context.Context, tx pgx.Tx) (map[int64]time.Time, error) {
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", advisoryLockID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, createSchemaMigrationsDDL); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, "SELECT version,applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
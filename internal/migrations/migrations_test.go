@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	migrations, err := Load()
+	assert.Nil(err)
+	assert.True(len(migrations) > 0)
+	for i, m := range migrations {
+		assert.NotEmpty(m.Up)
+		assert.NotEmpty(m.Down)
+		if i > 0 {
+			assert.True(migrations[i-1].Version < m.Version)
+		}
+	}
+
+	fsys := fstest.MapFS{
+		"sql/0002_second.up.sql":   {Data: []byte("SELECT 2;")},
+		"sql/0002_second.down.sql": {Data: []byte("SELECT -2;")},
+		"sql/0001_first.up.sql":    {Data: []byte("SELECT 1;")},
+		"sql/0001_first.down.sql":  {Data: []byte("SELECT -1;")},
+	}
+	migrations, err = load(fsys, "sql")
+	assert.Nil(err)
+	assert.Len(migrations, 2)
+	assert.Equal(int64(1), migrations[0].Version)
+	assert.Equal("first", migrations[0].Name)
+	assert.Equal("SELECT 1;", migrations[0].Up)
+	assert.Equal("SELECT -1;", migrations[0].Down)
+	assert.Equal(int64(2), migrations[1].Version)
+
+	fsys = fstest.MapFS{
+		"sql/0001_first.up.sql": {Data: []byte("SELECT 1;")},
+	}
+	migrations, err = load(fsys, "sql")
+	assert.NotNil(err)
+	assert.Nil(migrations)
+
+	fsys = fstest.MapFS{
+		"sql/first.up.sql": {Data: []byte("SELECT 1;")},
+	}
+	migrations, err = load(fsys, "sql")
+	assert.NotNil(err)
+	assert.Nil(migrations)
+
+	fsys = fstest.MapFS{
+		"sql/0001_first.up.sql":   {Data: []byte("SELECT 1;")},
+		"sql/0001_other.down.sql": {Data: []byte("SELECT -1;")},
+	}
+	migrations, err = load(fsys, "sql")
+	assert.NotNil(err)
+	assert.Nil(migrations)
+}
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS statistics;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS topic_users;
+DROP TABLE IF EXISTS topics;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS email_verifications;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
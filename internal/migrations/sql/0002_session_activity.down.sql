@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS sessions_expiresx;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS last_active_at;
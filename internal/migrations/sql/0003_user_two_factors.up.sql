@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS user_two_factors (
+  user_id               VARCHAR(36) PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+  secret                VARCHAR(128) NOT NULL,
+  last_used_step        BIGINT NOT NULL DEFAULT 0,
+  enabled_at            TIMESTAMP WITH TIME ZONE,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+  code_id               VARCHAR(36) PRIMARY KEY,
+  user_id               VARCHAR(36) NOT NULL REFERENCES users ON DELETE CASCADE,
+  code_hash             VARCHAR(128) NOT NULL,
+  used_at               TIMESTAMP WITH TIME ZONE,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_recovery_codes_user_hashx ON user_recovery_codes (user_id, code_hash);
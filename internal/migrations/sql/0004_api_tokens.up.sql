@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS api_tokens (
+  token_id              VARCHAR(36) PRIMARY KEY,
+  user_id               VARCHAR(36) NOT NULL REFERENCES users ON DELETE CASCADE,
+  name                  VARCHAR(128) NOT NULL,
+  token_hash            VARCHAR(128) NOT NULL,
+  scopes                VARCHAR(32)[] NOT NULL,
+  last_used_at          TIMESTAMP WITH TIME ZONE,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_tokens_hashx ON api_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS api_tokens_user_createdx ON api_tokens (user_id, created_at DESC);
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS github_id VARCHAR(1024) UNIQUE;
+
+UPDATE users SET github_id=i.subject FROM user_identities i WHERE i.user_id=users.user_id AND i.provider='github';
+
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+  identity_id           VARCHAR(36) PRIMARY KEY,
+  user_id               VARCHAR(36) NOT NULL REFERENCES users ON DELETE CASCADE,
+  provider              VARCHAR(64) NOT NULL,
+  subject               VARCHAR(512) NOT NULL,
+  login                 VARCHAR(512) NOT NULL DEFAULT '',
+  email                 VARCHAR(512) NOT NULL DEFAULT '',
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_identities_provider_subjectx ON user_identities (provider, subject);
+CREATE INDEX IF NOT EXISTS user_identities_user_createdx ON user_identities (user_id, created_at);
+
+INSERT INTO user_identities (identity_id, user_id, provider, subject, created_at, updated_at)
+  SELECT md5(user_id || ':github')::uuid::text, user_id, 'github', github_id, created_at, updated_at FROM users WHERE github_id IS NOT NULL
+  ON CONFLICT DO NOTHING;
+
+ALTER TABLE users DROP COLUMN IF EXISTS github_id;
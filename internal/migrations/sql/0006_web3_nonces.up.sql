@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS web3_nonces (
+  nonce                 VARCHAR(32) PRIMARY KEY,
+  expires_at            TIMESTAMP WITH TIME ZONE NOT NULL,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS web3_nonces_expiresx ON web3_nonces (expires_at);
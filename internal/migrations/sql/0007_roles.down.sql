@@ -0,0 +1,3 @@
+UPDATE users SET role='member' WHERE role IN ('moderator','read_only');
+
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS roles (
+  name                  VARCHAR(32) PRIMARY KEY,
+  permissions           VARCHAR(64)[] NOT NULL,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO roles (name, permissions) VALUES
+  ('admin', ARRAY['topic.create','comment.create','topic.edit_any','topic.delete','comment.edit_any','category.manage','user.ban','user.manage']),
+  ('moderator', ARRAY['topic.create','comment.create','topic.edit_any','topic.delete','comment.edit_any','user.ban']),
+  ('member', ARRAY['topic.create','comment.create']),
+  ('read_only', ARRAY[]::VARCHAR(64)[])
+  ON CONFLICT DO NOTHING;
+
+UPDATE users SET role='member' WHERE role NOT IN (SELECT name FROM roles);
@@ -0,0 +1,3 @@
+ALTER TABLE topics DROP COLUMN IF EXISTS locked;
+
+DROP TABLE IF EXISTS category_moderators;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS category_moderators (
+  category_id           VARCHAR(36) NOT NULL REFERENCES categories ON DELETE CASCADE,
+  user_id               VARCHAR(36) NOT NULL REFERENCES users ON DELETE CASCADE,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (category_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS category_moderators_userx ON category_moderators (user_id);
+
+ALTER TABLE topics ADD COLUMN IF NOT EXISTS locked BOOL NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+ALTER TABLE email_verifications DROP COLUMN IF EXISTS user_id;
+ALTER TABLE email_verifications DROP COLUMN IF EXISTS purpose;
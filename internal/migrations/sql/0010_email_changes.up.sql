@@ -0,0 +1,2 @@
+ALTER TABLE email_verifications ADD COLUMN IF NOT EXISTS purpose VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE email_verifications ADD COLUMN IF NOT EXISTS user_id VARCHAR(36) REFERENCES users ON DELETE CASCADE;
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required BOOL NOT NULL DEFAULT false;
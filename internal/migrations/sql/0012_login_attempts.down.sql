@@ -0,0 +1,2 @@
+ALTER TABLE email_verifications DROP COLUMN IF EXISTS attempts;
+DROP TABLE IF EXISTS login_attempts;
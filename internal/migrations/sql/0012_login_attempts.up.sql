@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+  attempt_key           VARCHAR(512) PRIMARY KEY,
+  failures              INTEGER NOT NULL DEFAULT 0,
+  locked_until          TIMESTAMP WITH TIME ZONE,
+  updated_at            TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_updatedx ON login_attempts (updated_at);
+
+ALTER TABLE email_verifications ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+  limit_key             VARCHAR(512) PRIMARY KEY,
+  tat                   TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_tatx ON rate_limits (tat);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS topic_revisions (
+  revision_id           VARCHAR(36) PRIMARY KEY,
+  topic_id              VARCHAR(36) NOT NULL REFERENCES topics ON DELETE CASCADE,
+  user_id               VARCHAR(36) REFERENCES users ON DELETE SET NULL,
+  version               INTEGER NOT NULL,
+  title                 VARCHAR(512) NOT NULL,
+  body                  TEXT NOT NULL,
+  category_id           VARCHAR(36) NOT NULL,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS topic_revisions_topic_versionx ON topic_revisions (topic_id, version);
@@ -0,0 +1,4 @@
+DELETE FROM comments WHERE deleted_at IS NOT NULL;
+ALTER TABLE comments DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE comments DROP COLUMN IF EXISTS deleted_at;
+DROP TABLE IF EXISTS comment_revisions;
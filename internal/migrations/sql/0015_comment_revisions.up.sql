@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS comment_revisions (
+  revision_id           VARCHAR(36) PRIMARY KEY,
+  comment_id            VARCHAR(36) NOT NULL REFERENCES comments ON DELETE CASCADE,
+  user_id               VARCHAR(36) REFERENCES users ON DELETE SET NULL,
+  version               INTEGER NOT NULL,
+  body                  TEXT NOT NULL,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS comment_revisions_comment_versionx ON comment_revisions (comment_id, version);
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(36);
@@ -0,0 +1,6 @@
+ALTER TABLE categories DROP COLUMN IF EXISTS comments_view;
+
+DROP INDEX IF EXISTS comments_parent_createdx;
+ALTER TABLE comments DROP COLUMN IF EXISTS replies_count;
+ALTER TABLE comments DROP COLUMN IF EXISTS depth;
+ALTER TABLE comments DROP COLUMN IF EXISTS parent_comment_id;
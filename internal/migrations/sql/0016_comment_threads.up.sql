@@ -0,0 +1,6 @@
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS parent_comment_id VARCHAR(36) REFERENCES comments ON DELETE SET NULL;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS depth INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS replies_count BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS comments_parent_createdx ON comments (parent_comment_id, created_at);
+
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS comments_view VARCHAR(16) NOT NULL DEFAULT 'flat';
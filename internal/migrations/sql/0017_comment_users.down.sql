@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS comments_topic_score_createdx;
+DROP TABLE IF EXISTS comment_users;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS comment_users (
+  comment_id            VARCHAR(36) NOT NULL REFERENCES comments ON DELETE CASCADE,
+  user_id               VARCHAR(36) NOT NULL REFERENCES users ON DELETE CASCADE,
+  vote                  SMALLINT NOT NULL DEFAULT 0,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS comment_users_reversex ON comment_users(user_id, comment_id);
+CREATE INDEX IF NOT EXISTS comments_topic_score_createdx ON comments (topic_id, score DESC, created_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS ranking_weights;
+
+ALTER TABLE topics ALTER COLUMN score TYPE INTEGER USING 0;
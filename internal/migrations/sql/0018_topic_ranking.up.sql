@@ -0,0 +1,17 @@
+ALTER TABLE topics ALTER COLUMN score TYPE DOUBLE PRECISION;
+ALTER TABLE topics ALTER COLUMN score SET DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS ranking_weights (
+  name                  VARCHAR(32) PRIMARY KEY,
+  weight                DOUBLE PRECISION NOT NULL,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO ranking_weights (name, weight) VALUES
+  ('likes', 1),
+  ('comments', 2),
+  ('views', 0.05),
+  ('bookmarks', 1.5),
+  ('gravity', 1.8)
+  ON CONFLICT DO NOTHING;
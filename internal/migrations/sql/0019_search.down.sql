@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS comments_search ON comments;
+DROP TRIGGER IF EXISTS topics_search ON topics;
+
+DROP INDEX IF EXISTS comments_searchx;
+DROP INDEX IF EXISTS topics_searchx;
+ALTER TABLE comments DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE topics DROP COLUMN IF EXISTS search_vector;
+
+DROP FUNCTION IF EXISTS comments_search_trigger();
+DROP FUNCTION IF EXISTS topics_search_trigger();
+DROP FUNCTION IF EXISTS comment_search_vector(TEXT);
+DROP FUNCTION IF EXISTS topic_search_vector(TEXT, TEXT);
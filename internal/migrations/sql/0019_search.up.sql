@@ -0,0 +1,34 @@
+CREATE OR REPLACE FUNCTION topic_search_vector(title TEXT, body TEXT) RETURNS tsvector AS $$
+  SELECT setweight(to_tsvector('english', coalesce(title, '')), 'A') || setweight(to_tsvector('english', coalesce(body, '')), 'B')
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION comment_search_vector(body TEXT) RETURNS tsvector AS $$
+  SELECT to_tsvector('english', coalesce(body, ''))
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION topics_search_trigger() RETURNS trigger AS $$
+BEGIN
+  NEW.search_vector := topic_search_vector(NEW.title, NEW.body);
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION comments_search_trigger() RETURNS trigger AS $$
+BEGIN
+  NEW.search_vector := comment_search_vector(NEW.body);
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE topics ADD COLUMN IF NOT EXISTS search_vector tsvector;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS search_vector tsvector;
+UPDATE topics SET search_vector=topic_search_vector(title, body);
+UPDATE comments SET search_vector=comment_search_vector(body);
+
+CREATE INDEX IF NOT EXISTS topics_searchx ON topics USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS comments_searchx ON comments USING GIN (search_vector);
+
+DROP TRIGGER IF EXISTS topics_search ON topics;
+CREATE TRIGGER topics_search BEFORE INSERT OR UPDATE OF title, body ON topics FOR EACH ROW EXECUTE FUNCTION topics_search_trigger();
+DROP TRIGGER IF EXISTS comments_search ON comments;
+CREATE TRIGGER comments_search BEFORE INSERT OR UPDATE OF body ON comments FOR EACH ROW EXECUTE FUNCTION comments_search_trigger();
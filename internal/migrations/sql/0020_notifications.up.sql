@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS notifications (
+  notification_id       VARCHAR(36) PRIMARY KEY,
+  user_id               VARCHAR(36) NOT NULL REFERENCES users ON DELETE CASCADE,
+  notification_type     VARCHAR(32) NOT NULL,
+  topic_id              VARCHAR(36) REFERENCES topics ON DELETE CASCADE,
+  comment_id            VARCHAR(36) REFERENCES comments ON DELETE CASCADE,
+  group_key             VARCHAR(128) NOT NULL,
+  actor_ids             VARCHAR(36)[] NOT NULL,
+  read_at               TIMESTAMP WITH TIME ZONE,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_unread_groupx ON notifications (user_id, group_key) WHERE read_at IS NULL;
+CREATE INDEX IF NOT EXISTS notifications_user_updatedx ON notifications (user_id, updated_at DESC);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS mentions (
+  mention_id            VARCHAR(36) PRIMARY KEY,
+  topic_id              VARCHAR(36) NOT NULL REFERENCES topics ON DELETE CASCADE,
+  comment_id            VARCHAR(36) REFERENCES comments ON DELETE CASCADE,
+  user_id               VARCHAR(36) NOT NULL REFERENCES users ON DELETE CASCADE,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS mentions_topic_userx ON mentions (topic_id, user_id) WHERE comment_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS mentions_comment_userx ON mentions (comment_id, user_id) WHERE comment_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS mentions_user_createdx ON mentions (user_id, created_at DESC);
@@ -0,0 +1,5 @@
+DROP FUNCTION IF EXISTS topic_subscription(VARCHAR, VARCHAR);
+DROP TABLE IF EXISTS category_users;
+DELETE FROM topic_users WHERE liked_at IS NULL AND bookmarked_at IS NULL;
+DROP INDEX IF EXISTS topic_users_watchingx;
+ALTER TABLE topic_users DROP COLUMN IF EXISTS subscription;
@@ -0,0 +1,27 @@
+ALTER TABLE topic_users ADD COLUMN IF NOT EXISTS subscription VARCHAR(16) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS topic_users_watchingx ON topic_users (topic_id) WHERE subscription='watching';
+
+CREATE TABLE IF NOT EXISTS category_users (
+  category_id           VARCHAR(36) NOT NULL REFERENCES categories ON DELETE CASCADE,
+  user_id               VARCHAR(36) NOT NULL REFERENCES users ON DELETE CASCADE,
+  subscription          VARCHAR(16) NOT NULL,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (category_id, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS category_users_reversex ON category_users(user_id, category_id);
+
+CREATE OR REPLACE FUNCTION topic_subscription(tid VARCHAR, uid VARCHAR) RETURNS VARCHAR AS $$
+  SELECT COALESCE(
+    (SELECT NULLIF(tu.subscription, '') FROM topic_users tu WHERE tu.topic_id=tid AND tu.user_id=uid),
+    (SELECT cu.subscription FROM category_users cu INNER JOIN topics t ON t.category_id=cu.category_id WHERE t.topic_id=tid AND cu.user_id=uid),
+    'tracking')
+$$ LANGUAGE SQL STABLE;
+
+INSERT INTO topic_users (topic_id, user_id, subscription, created_at, updated_at)
+  SELECT topic_id, user_id, 'watching', created_at, created_at FROM topics WHERE draft=false
+  ON CONFLICT (topic_id, user_id) DO UPDATE SET subscription='watching';
+INSERT INTO topic_users (topic_id, user_id, subscription, created_at, updated_at)
+  SELECT topic_id, user_id, 'watching', min(created_at), min(created_at) FROM comments GROUP BY topic_id, user_id
+  ON CONFLICT (topic_id, user_id) DO UPDATE SET subscription='watching';
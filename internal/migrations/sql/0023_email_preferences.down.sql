@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS notifications_unread_updatedx;
+DROP TABLE IF EXISTS email_preferences;
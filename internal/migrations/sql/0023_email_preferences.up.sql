@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS email_preferences (
+  user_id               VARCHAR(36) PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+  replies               BOOLEAN NOT NULL DEFAULT true,
+  mentions              BOOLEAN NOT NULL DEFAULT true,
+  digest                VARCHAR(16) NOT NULL DEFAULT 'never',
+  notified_at           TIMESTAMP WITH TIME ZONE,
+  digested_at           TIMESTAMP WITH TIME ZONE,
+  created_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS email_preferences_digestx ON email_preferences (digest, digested_at);
+CREATE INDEX IF NOT EXISTS notifications_unread_updatedx ON notifications (updated_at) WHERE read_at IS NULL;
@@ -0,0 +1,12 @@
+CREATE OR REPLACE FUNCTION topic_search_vector(title TEXT, body TEXT) RETURNS tsvector AS $$
+  SELECT setweight(to_tsvector('english', coalesce(title, '')), 'A') || setweight(to_tsvector('english', coalesce(body, '')), 'B')
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION comment_search_vector(body TEXT) RETURNS tsvector AS $$
+  SELECT to_tsvector('english', coalesce(body, ''))
+$$ LANGUAGE SQL IMMUTABLE;
+
+UPDATE topics SET search_vector=topic_search_vector(title, body) WHERE title LIKE '%<%' OR body LIKE '%<%';
+UPDATE comments SET search_vector=comment_search_vector(body) WHERE body LIKE '%<%';
+
+DROP FUNCTION IF EXISTS search_text(TEXT);
@@ -0,0 +1,14 @@
+CREATE OR REPLACE FUNCTION search_text(t TEXT) RETURNS TEXT AS $$
+  SELECT translate(coalesce(t, ''), '<>', E'\x04\x05')
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION topic_search_vector(title TEXT, body TEXT) RETURNS tsvector AS $$
+  SELECT setweight(to_tsvector('english', search_text(title)), 'A') || setweight(to_tsvector('english', search_text(body)), 'B')
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION comment_search_vector(body TEXT) RETURNS tsvector AS $$
+  SELECT to_tsvector('english', search_text(body))
+$$ LANGUAGE SQL IMMUTABLE;
+
+UPDATE topics SET search_vector=topic_search_vector(title, body) WHERE title LIKE '%<%' OR body LIKE '%<%';
+UPDATE comments SET search_vector=comment_search_vector(body) WHERE body LIKE '%<%';
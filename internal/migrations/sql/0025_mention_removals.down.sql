@@ -0,0 +1,2 @@
+DELETE FROM mentions WHERE removed_at IS NOT NULL;
+ALTER TABLE mentions DROP COLUMN IF EXISTS removed_at;
@@ -0,0 +1 @@
+ALTER TABLE mentions ADD COLUMN IF NOT EXISTS removed_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,204 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"satellity/internal/durable"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// APIToken related CONST
+const (
+	APITokenPrefix = "sat_"
+
+	APITokenScopeRead     = "read"
+	APITokenScopePost     = "post"
+	APITokenScopeModerate = "moderate"
+
+	apiTokensLimit         = 20
+	apiTokenActiveInterval = time.Minute
+)
+
+// APIToken is a long-lived personal token for bots and scripts
+type APIToken struct {
+	TokenID    string
+	UserID     string
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	LastUsedAt sql.NullTime
+	CreatedAt  time.Time
+
+	Token string // plain token, only available right after created
+}
+
+var apiTokenColumns = []string{"token_id", "user_id", "name", "token_hash", "scopes", "last_used_at", "created_at"}
+
+func (t *APIToken) values() []interface{} {
+	return []interface{}{t.TokenID, t.UserID, t.Name, t.TokenHash, t.Scopes, t.LastUsedAt, t.CreatedAt}
+}
+
+func apiTokenFromRow(row durable.Row) (*APIToken, error) {
+	var t APIToken
+	err := row.Scan(&t.TokenID, &t.UserID, &t.Name, &t.TokenHash, &t.Scopes, &t.LastUsedAt, &t.CreatedAt)
+	return &t, err
+}
+
+// CreateAPIToken create a scoped token, moderate scope is only for users
+// whose role grants more than posting
+func (user *User) CreateAPIToken(ctx context.Context, name string, scopes []string) (*APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 128 {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "name", "invalid", name)
+	}
+	set := make(map[string]bool)
+	for _, scope := range scopes {
+		switch scope {
+		case APITokenScopeRead, APITokenScopePost, APITokenScopeModerate:
+		default:
+			return nil, session.BadDataErrorWithFieldAndData(ctx, "scopes", "invalid", scope)
+		}
+		set[scope] = true
+	}
+	if len(set) == 0 {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "scopes", "blank", "")
+	}
+
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, session.ServerError(ctx, err)
+	}
+	plain := APITokenPrefix + hex.EncodeToString(b[:])
+	token := &APIToken{
+		TokenID:   uuid.Must(uuid.NewV4()).String(),
+		UserID:    user.UserID,
+		Name:      name,
+		TokenHash: hashAPIToken(plain),
+		CreatedAt: time.Now(),
+		Token:     plain,
+	}
+	for _, scope := range []string{APITokenScopeRead, APITokenScopePost, APITokenScopeModerate} {
+		if set[scope] {
+			token.Scopes = append(token.Scopes, scope)
+		}
+	}
+
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		if set[APITokenScopeModerate] {
+			if can, err := user.canModerate(ctx, tx); err != nil {
+				return err
+			} else if !can {
+				return session.ForbiddenError(ctx)
+			}
+		}
+		var count int64
+		if err := tx.QueryRow(ctx, "SELECT count(*) FROM api_tokens WHERE user_id=$1", user.UserID).Scan(&count); err != nil {
+			return err
+		}
+		if count >= apiTokensLimit {
+			return session.BadDataErrorWithFieldAndData(ctx, "tokens", "too many", fmt.Sprint(count))
+		}
+		rows := [][]interface{}{token.values()}
+		_, err := tx.CopyFrom(ctx, pgx.Identifier{"api_tokens"}, apiTokenColumns, pgx.CopyFromRows(rows))
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return token, nil
+}
+
+// ReadAPITokens read all tokens of the user
+func (user *User) ReadAPITokens(ctx context.Context) ([]*APIToken, error) {
+	query := fmt.Sprintf("SELECT %s FROM api_tokens WHERE user_id=$1 ORDER BY user_id,created_at DESC", strings.Join(apiTokenColumns, ","))
+	rows, err := session.Database(ctx).Query(ctx, query, user.UserID)
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	defer rows.Close()
+
+	var tokens []*APIToken
+	for rows.Next() {
+		token, err := apiTokenFromRow(rows)
+		if err != nil {
+			return nil, session.TransactionError(ctx, err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return tokens, nil
+}
+
+// DeleteAPIToken revoke a token of the user
+func (user *User) DeleteAPIToken(ctx context.Context, id string) error {
+	if uuid.FromStringOrNil(id).String() != id {
+		return nil
+	}
+	_, err := session.Database(ctx).Exec(ctx, "DELETE FROM api_tokens WHERE user_id=$1 AND token_id=$2", user.UserID, id)
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// AuthenticateAPIToken read a user by the plain token, the token is kept in user.APIToken
+func AuthenticateAPIToken(ctx context.Context, plain string) (*User, error) {
+	if !strings.HasPrefix(plain, APITokenPrefix) {
+		return nil, nil
+	}
+	var user *User
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		query := fmt.Sprintf("SELECT %s FROM api_tokens WHERE token_hash=$1", strings.Join(apiTokenColumns, ","))
+		token, err := apiTokenFromRow(tx.QueryRow(ctx, query, hashAPIToken(plain)))
+		if err == pgx.ErrNoRows {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		user, err = findUserByID(ctx, tx, token.UserID)
+		if err != nil || user == nil {
+			return err
+		}
+		if user.IsSuspended() {
+			return session.UserSuspendedError(ctx, user.SuspendedUntil.Time, user.SuspensionReason)
+		}
+		user.APIToken = token
+		t := time.Now()
+		if token.LastUsedAt.Valid && token.LastUsedAt.Time.Add(apiTokenActiveInterval).After(t) {
+			return nil
+		}
+		token.LastUsedAt = sql.NullTime{Time: t, Valid: true}
+		_, err = tx.Exec(ctx, "UPDATE api_tokens SET last_used_at=$1 WHERE token_id=$2", token.LastUsedAt, token.TokenID)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return user, nil
+}
+
+// HasScope check if the token grants the scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func hashAPIToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPITokenCRUD(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+
+	tokenCases := []struct {
+		name   string
+		scopes []string
+		valid  bool
+	}{
+		{"", []string{APITokenScopeRead}, false},
+		{"bot", []string{}, false},
+		{"bot", []string{"write"}, false},
+		{"bot", []string{APITokenScopeModerate}, false},
+		{"bot", []string{APITokenScopePost, APITokenScopeRead, APITokenScopeRead}, true},
+	}
+	for _, tc := range tokenCases {
+		token, err := user.CreateAPIToken(ctx, tc.name, tc.scopes)
+		if !tc.valid {
+			assert.NotNil(err)
+			assert.Nil(token)
+			continue
+		}
+		assert.Nil(err)
+		assert.NotNil(token)
+		assert.Equal([]string{APITokenScopeRead, APITokenScopePost}, token.Scopes)
+		assert.True(token.HasScope(APITokenScopeRead))
+		assert.False(token.HasScope(APITokenScopeModerate))
+
+		existing, err := AuthenticateAPIToken(ctx, token.Token)
+		assert.Nil(err)
+		assert.NotNil(existing)
+		assert.Equal(user.UserID, existing.UserID)
+		assert.Equal(token.TokenID, existing.APIToken.TokenID)
+		existing, err = AuthenticateAPIToken(ctx, APITokenPrefix+"invalid")
+		assert.Nil(err)
+		assert.Nil(existing)
+
+		tokens, err := user.ReadAPITokens(ctx)
+		assert.Nil(err)
+		assert.Len(tokens, 1)
+		assert.True(tokens[0].LastUsedAt.Valid)
+		assert.Equal("", tokens[0].Token)
+		err = user.DeleteAPIToken(ctx, uuid.Must(uuid.NewV4()).String())
+		assert.Nil(err)
+		err = user.DeleteAPIToken(ctx, token.TokenID)
+		assert.Nil(err)
+		tokens, err = user.ReadAPITokens(ctx)
+		assert.Nil(err)
+		assert.Len(tokens, 0)
+		existing, err = AuthenticateAPIToken(ctx, token.Token)
+		assert.Nil(err)
+		assert.Nil(existing)
+	}
+}
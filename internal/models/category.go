@@ -13,28 +13,38 @@ import (
 	"github.com/jackc/pgx/v4"
 )
 
+// Comments of a topic are shown as a flat list, which quotes the replied comment,
+// or as nested threads, depends on the category of the topic.
+const (
+	CategoryCommentsViewFlat   = "flat"
+	CategoryCommentsViewNested = "nested"
+)
+
 // Category is used to categorize topics.
 type Category struct {
-	CategoryID  string
-	Name        string
-	Alias       string
-	Description string
-	TopicsCount int64
-	LastTopicID sql.NullString
-	Position    int64
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	CategoryID   string
+	Name         string
+	Alias        string
+	Description  string
+	TopicsCount  int64
+	LastTopicID  sql.NullString
+	Position     int64
+	CommentsView string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	Moderators []*User
 }
 
-var categoryColumns = []string{"category_id", "name", "alias", "description", "topics_count", "last_topic_id", "position", "created_at", "updated_at"}
+var categoryColumns = []string{"category_id", "name", "alias", "description", "topics_count", "last_topic_id", "position", "comments_view", "created_at", "updated_at"}
 
 func (c *Category) values() []interface{} {
-	return []interface{}{c.CategoryID, c.Name, c.Alias, c.Description, c.TopicsCount, c.LastTopicID, c.Position, c.CreatedAt, c.UpdatedAt}
+	return []interface{}{c.CategoryID, c.Name, c.Alias, c.Description, c.TopicsCount, c.LastTopicID, c.Position, c.CommentsView, c.CreatedAt, c.UpdatedAt}
 }
 
 func categoryFromRows(row durable.Row) (*Category, error) {
 	var c Category
-	err := row.Scan(&c.CategoryID, &c.Name, &c.Alias, &c.Description, &c.TopicsCount, &c.LastTopicID, &c.Position, &c.CreatedAt, &c.UpdatedAt)
+	err := row.Scan(&c.CategoryID, &c.Name, &c.Alias, &c.Description, &c.TopicsCount, &c.LastTopicID, &c.Position, &c.CommentsView, &c.CreatedAt, &c.UpdatedAt)
 	return &c, err
 }
 
@@ -53,14 +63,15 @@ func CreateCategory(ctx context.Context, name, alias, description string, positi
 
 	t := time.Now()
 	category := &Category{
-		CategoryID:  uuid.Must(uuid.NewV4()).String(),
-		Name:        name,
-		Alias:       alias,
-		Description: description,
-		TopicsCount: 0,
-		Position:    position,
-		CreatedAt:   t,
-		UpdatedAt:   t,
+		CategoryID:   uuid.Must(uuid.NewV4()).String(),
+		Name:         name,
+		Alias:        alias,
+		Description:  description,
+		TopicsCount:  0,
+		Position:     position,
+		CommentsView: CategoryCommentsViewFlat,
+		CreatedAt:    t,
+		UpdatedAt:    t,
 	}
 
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
@@ -110,13 +121,36 @@ func (category *Category) Update(ctx context.Context, name, alias, description s
 	return nil
 }
 
+// SetCommentsView switch the comments of the category between flat and nested threads,
+// a blank view keeps the current one
+func (category *Category) SetCommentsView(ctx context.Context, view string) error {
+	view = strings.TrimSpace(view)
+	if view == "" || view == category.CommentsView {
+		return nil
+	}
+	if view != CategoryCommentsViewFlat && view != CategoryCommentsViewNested {
+		return session.BadDataError(ctx)
+	}
+	t := time.Now()
+	_, err := session.Database(ctx).Exec(ctx, "UPDATE categories SET (comments_view,updated_at)=($1,$2) WHERE category_id=$3", view, t, category.CategoryID)
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	category.CommentsView = view
+	category.UpdatedAt = t
+	return nil
+}
+
 // ReadCategory read a category by ID
 func ReadCategory(ctx context.Context, id string) (*Category, error) {
 	var category *Category
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
 		var err error
 		category, err = findCategory(ctx, tx, id)
-		return err
+		if err != nil || category == nil {
+			return err
+		}
+		return category.fillModerators(ctx, tx)
 	})
 	if err != nil {
 		return nil, session.TransactionError(ctx, err)
@@ -143,7 +177,24 @@ func ReadAllCategories(ctx context.Context) ([]*Category, error) {
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
 		var err error
 		categories, err = readCategories(ctx, tx)
-		return err
+		if err != nil || len(categories) == 0 {
+			return err
+		}
+		ids := make([]string, len(categories))
+		for i, c := range categories {
+			ids[i] = c.CategoryID
+		}
+		set, err := readCategoryModeratorSet(ctx, tx, ids)
+		if err != nil {
+			return err
+		}
+		for _, c := range categories {
+			c.Moderators = set[c.CategoryID]
+			if c.Moderators == nil {
+				c.Moderators = []*User{}
+			}
+		}
+		return nil
 	})
 	if err != nil {
 		return nil, session.TransactionError(ctx, err)
@@ -204,33 +255,7 @@ func EmitToCategory(ctx context.Context, id string) (*Category, error) {
 	var category *Category
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
 		var err error
-		category, err = findCategory(ctx, tx, id)
-		if err != nil || category == nil {
-			return err
-		}
-		topic, err := category.latestTopic(ctx, tx)
-		if err != nil {
-			return err
-		}
-		lastTopicID := sql.NullString{String: "", Valid: false}
-		if topic != nil {
-			lastTopicID = sql.NullString{String: topic.TopicID, Valid: true}
-		}
-		if category.LastTopicID.String != lastTopicID.String {
-			category.LastTopicID = lastTopicID
-		}
-		category.TopicsCount = 0
-		if category.LastTopicID.Valid {
-			count, err := fetchTopicsCount(ctx, tx, category.CategoryID)
-			if err != nil {
-				return err
-			}
-			category.TopicsCount = count
-		}
-		category.UpdatedAt = time.Now()
-		cols, posits := durable.PrepareColumnsAndExpressions([]string{"last_topic_id", "topics_count", "updated_at"}, 1)
-		values := []interface{}{category.CategoryID, category.LastTopicID, category.TopicsCount, category.UpdatedAt}
-		_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE categories SET (%s)=(%s) WHERE category_id=$1", cols, posits), values...)
+		category, err = emitToCategory(ctx, tx, id)
 		return err
 	})
 	if err != nil {
@@ -239,6 +264,37 @@ func EmitToCategory(ctx context.Context, id string) (*Category, error) {
 	return category, nil
 }
 
+func emitToCategory(ctx context.Context, tx pgx.Tx, id string) (*Category, error) {
+	category, err := findCategory(ctx, tx, id)
+	if err != nil || category == nil {
+		return nil, err
+	}
+	topic, err := category.latestTopic(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	lastTopicID := sql.NullString{String: "", Valid: false}
+	if topic != nil {
+		lastTopicID = sql.NullString{String: topic.TopicID, Valid: true}
+	}
+	if category.LastTopicID.String != lastTopicID.String {
+		category.LastTopicID = lastTopicID
+	}
+	category.TopicsCount = 0
+	if category.LastTopicID.Valid {
+		count, err := fetchTopicsCount(ctx, tx, category.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		category.TopicsCount = count
+	}
+	category.UpdatedAt = time.Now()
+	cols, posits := durable.PrepareColumnsAndExpressions([]string{"last_topic_id", "topics_count", "updated_at"}, 1)
+	values := []interface{}{category.CategoryID, category.LastTopicID, category.TopicsCount, category.UpdatedAt}
+	_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE categories SET (%s)=(%s) WHERE category_id=$1", cols, posits), values...)
+	return category, err
+}
+
 func findCategory(ctx context.Context, tx pgx.Tx, id string) (*Category, error) {
 	if uuid.FromStringOrNil(id).String() != id {
 		return nil, nil
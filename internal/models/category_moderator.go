@@ -0,0 +1,106 @@
+package models
+
+import (
+	"context"
+	"satellity/internal/session"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// AddModerator appoint the user to moderate topics and comments of the category
+func (category *Category) AddModerator(ctx context.Context, user *User) error {
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "INSERT INTO category_moderators (category_id,user_id,created_at) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING", category.CategoryID, user.UserID, time.Now())
+		if err != nil {
+			return err
+		}
+		return category.fillModerators(ctx, tx)
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// RemoveModerator revoke the user from moderators of the category
+func (category *Category) RemoveModerator(ctx context.Context, user *User) error {
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "DELETE FROM category_moderators WHERE category_id=$1 AND user_id=$2", category.CategoryID, user.UserID)
+		if err != nil {
+			return err
+		}
+		return category.fillModerators(ctx, tx)
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+func (category *Category) fillModerators(ctx context.Context, tx pgx.Tx) error {
+	set, err := readCategoryModeratorSet(ctx, tx, []string{category.CategoryID})
+	if err != nil {
+		return err
+	}
+	category.Moderators = set[category.CategoryID]
+	if category.Moderators == nil {
+		category.Moderators = []*User{}
+	}
+	return nil
+}
+
+// readCategoryModeratorSet read moderators grouped by category id
+func readCategoryModeratorSet(ctx context.Context, tx pgx.Tx, ids []string) (map[string][]*User, error) {
+	rows, err := tx.Query(ctx, "SELECT category_id,user_id FROM category_moderators WHERE category_id=ANY($1) ORDER BY category_id,created_at", ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairs [][2]string
+	var userIDs []string
+	for rows.Next() {
+		var pair [2]string
+		if err := rows.Scan(&pair[0], &pair[1]); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+		userIDs = append(userIDs, pair[1])
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	set := make(map[string][]*User)
+	if len(pairs) == 0 {
+		return set, nil
+	}
+	userSet, err := readUserSet(ctx, tx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		if u := userSet[pair[1]]; u != nil {
+			set[pair[0]] = append(set[pair[0]], u)
+		}
+	}
+	return set, nil
+}
+
+func (user *User) moderatesCategory(ctx context.Context, tx pgx.Tx, categoryID string) (bool, error) {
+	var count int64
+	err := tx.QueryRow(ctx, "SELECT count(*) FROM category_moderators WHERE category_id=$1 AND user_id=$2", categoryID, user.UserID).Scan(&count)
+	return count > 0, err
+}
+
+// canModerateCategory is true if the role grants the permission globally, or
+// the user is a moderator of the category
+func (user *User) canModerateCategory(ctx context.Context, tx pgx.Tx, categoryID, permission string) (bool, error) {
+	if user == nil {
+		return false, nil
+	}
+	if can, err := user.can(ctx, tx, permission); err != nil || can {
+		return can, err
+	}
+	return user.moderatesCategory(ctx, tx, categoryID)
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryModerator(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	moderator := createTestUser(ctx, "moderator@gmail.com", "moderator", "password")
+	assert.NotNil(moderator)
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+	other, _ := CreateCategory(ctx, "other", "other", "Description", 0)
+	assert.NotNil(other)
+	topic, err := user.CreateTopic(ctx, "title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	comment, err := user.CreateComment(ctx, "comment body", topic)
+	assert.Nil(err)
+
+	err = topic.Lock(ctx, moderator, true)
+	assert.NotNil(err)
+	err = comment.Update(ctx, "moderated body", moderator)
+	assert.NotNil(err)
+
+	err = category.AddModerator(ctx, moderator)
+	assert.Nil(err)
+	assert.Len(category.Moderators, 1)
+	categories, err := ReadAllCategories(ctx)
+	assert.Nil(err)
+	assert.Len(categories, 2)
+	assert.Len(categories[0].Moderators, 1)
+	assert.Len(categories[1].Moderators, 0)
+	existing, err := ReadCategory(ctx, category.CategoryID)
+	assert.Nil(err)
+	assert.Equal(moderator.UserID, existing.Moderators[0].UserID)
+
+	err = topic.Lock(ctx, moderator, true)
+	assert.Nil(err)
+	topic, _ = ReadTopic(ctx, topic.TopicID)
+	assert.True(topic.Locked)
+	_, err = user.CreateComment(ctx, "locked comment", topic)
+	assert.NotNil(err)
+	_, err = moderator.CreateComment(ctx, "moderator comment", topic)
+	assert.Nil(err)
+	err = comment.Update(ctx, "moderated body", moderator)
+	assert.Nil(err)
+	_, err = moderator.UpdateTopic(ctx, topic.TopicID, "title", "body", TopicTypePost, other.CategoryID, false)
+	assert.NotNil(err)
+	err = other.AddModerator(ctx, moderator)
+	assert.Nil(err)
+	topic, err = moderator.UpdateTopic(ctx, topic.TopicID, "title", "body", TopicTypePost, other.CategoryID, false)
+	assert.Nil(err)
+	assert.Equal(other.CategoryID, topic.CategoryID)
+
+	err = other.RemoveModerator(ctx, moderator)
+	assert.Nil(err)
+	assert.Len(other.Moderators, 0)
+	err = comment.Delete(ctx, moderator)
+	assert.NotNil(err)
+	err = topic.Delete(ctx, moderator)
+	assert.NotNil(err)
+	err = other.AddModerator(ctx, moderator)
+	assert.Nil(err)
+	err = comment.Delete(ctx, moderator)
+	assert.Nil(err)
+	err = topic.Delete(ctx, moderator)
+	assert.Nil(err)
+	topic, err = ReadTopic(ctx, topic.TopicID)
+	assert.Nil(err)
+	assert.Nil(topic)
+	other, err = ReadCategory(ctx, other.CategoryID)
+	assert.Nil(err)
+	assert.Equal(int64(0), other.TopicsCount)
+	assert.False(other.LastTopicID.Valid)
+}
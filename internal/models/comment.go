@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"satellity/internal/durable"
 	"satellity/internal/session"
@@ -18,31 +19,59 @@ const (
 
 // Comment is struct for comment of topic
 type Comment struct {
-	CommentID string
-	Body      string
-	TopicID   string
-	UserID    string
-	Score     int
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	CommentID       string
+	Body            string
+	TopicID         string
+	UserID          string
+	Score           int
+	ParentCommentID sql.NullString
+	Depth           int
+	RepliesCount    int64
+	DeletedAt       sql.NullTime
+	DeletedBy       sql.NullString
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 
-	User *User
+	Vote     int
+	User     *User
+	Parent   *Comment
+	Mentions []*Mention
 }
 
-var commentColumns = []string{"comment_id", "body", "topic_id", "user_id", "score", "created_at", "updated_at"}
+var commentColumns = []string{"comment_id", "body", "topic_id", "user_id", "score", "parent_comment_id", "depth", "replies_count", "deleted_at", "deleted_by", "created_at", "updated_at"}
 
 func (c *Comment) values() []interface{} {
-	return []interface{}{c.CommentID, c.Body, c.TopicID, c.UserID, c.Score, c.CreatedAt, c.UpdatedAt}
+	return []interface{}{c.CommentID, c.Body, c.TopicID, c.UserID, c.Score, c.ParentCommentID, c.Depth, c.RepliesCount, c.DeletedAt, c.DeletedBy, c.CreatedAt, c.UpdatedAt}
 }
 
 func commentFromRows(row durable.Row) (*Comment, error) {
 	var c Comment
-	err := row.Scan(&c.CommentID, &c.Body, &c.TopicID, &c.UserID, &c.Score, &c.CreatedAt, &c.UpdatedAt)
+	err := row.Scan(&c.CommentID, &c.Body, &c.TopicID, &c.UserID, &c.Score, &c.ParentCommentID, &c.Depth, &c.RepliesCount, &c.DeletedAt, &c.DeletedBy, &c.CreatedAt, &c.UpdatedAt)
 	return &c, err
 }
 
+// IsDeleted is true for the tombstone of a deleted comment
+func (c *Comment) IsDeleted() bool {
+	return c.DeletedAt.Valid
+}
+
+// IsDeletedByModerator is true if the comment is deleted by someone else than the author
+func (c *Comment) IsDeletedByModerator() bool {
+	return c.DeletedAt.Valid && c.DeletedBy.String != c.UserID
+}
+
 // CreateComment create a new comment
 func (user *User) CreateComment(ctx context.Context, body string, topic *Topic) (*Comment, error) {
+	return user.createComment(ctx, body, topic, "")
+}
+
+// CreateReply create a comment replying to the parent comment of the topic, or a
+// top level comment if parentID is blank
+func (user *User) CreateReply(ctx context.Context, body string, topic *Topic, parentID string) (*Comment, error) {
+	return user.createComment(ctx, body, topic, parentID)
+}
+
+func (user *User) createComment(ctx context.Context, body string, topic *Topic, parentID string) (*Comment, error) {
 	body = strings.TrimSpace(body)
 	if len(body) < commentBodySizeLimit {
 		return nil, session.BadDataError(ctx)
@@ -55,7 +84,27 @@ func (user *User) CreateComment(ctx context.Context, body string, topic *Topic)
 		CreatedAt: t,
 		UpdatedAt: t,
 	}
+	var parent *Comment
+	var mentioned []*User
+	var watchers []string
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		if topic.Locked {
+			if can, err := user.canModerateCategory(ctx, tx, topic.CategoryID, PermissionCommentEditAny); err != nil {
+				return err
+			} else if !can {
+				return session.ForbiddenError(ctx)
+			}
+		}
+		if parentID != "" {
+			var err error
+			parent, err = findComment(ctx, tx, parentID)
+			if err != nil {
+				return err
+			}
+			if err := c.replyTo(ctx, parent, topic); err != nil {
+				return err
+			}
+		}
 		count, err := fetchCommentsCount(ctx, tx, topic.TopicID)
 		if err != nil {
 			return err
@@ -73,39 +122,84 @@ func (user *User) CreateComment(ctx context.Context, body string, topic *Topic)
 		c.TopicID = topic.TopicID
 		rows := [][]interface{}{c.values()}
 		_, err = tx.CopyFrom(ctx, pgx.Identifier{"comments"}, commentColumns, pgx.CopyFromRows(rows))
-		return err
+		if err != nil {
+			return err
+		}
+		c.Mentions, mentioned, err = syncMentions(ctx, tx, topic.TopicID, c.CommentID, c.Body)
+		if err != nil {
+			return err
+		}
+		if err := watchTopic(ctx, tx, topic.TopicID, user.UserID); err != nil {
+			return err
+		}
+		watchers, err = readTopicWatcherIDs(ctx, tx, topic)
+		if err != nil {
+			return err
+		}
+		return updateRepliesCount(ctx, tx, c.ParentCommentID)
 	})
 	if err != nil {
 		return nil, session.TransactionError(ctx, err)
 	}
 	c.User = user
 	UpsertStatistic(ctx, StatisticTypeComments)
+	notified := make(map[string]bool)
+	if parent != nil {
+		notify(ctx, NotificationTypeReplied, parent.UserID, user, topic.TopicID, c.CommentID, parent.CommentID)
+		notified[parent.UserID] = true
+	}
+	notifyMentions(ctx, mentioned, user, topic.TopicID, c.CommentID)
+	for _, u := range mentioned {
+		notified[u.UserID] = true
+	}
+	for _, id := range watchers {
+		if !notified[id] {
+			notify(ctx, NotificationTypeCommented, id, user, topic.TopicID, c.CommentID, topic.TopicID)
+		}
+	}
 	return c, nil
 }
 
-// UpdateComment update the comment by id
+// UpdateComment update the comment by id, the previous body is kept as a revision
 func (comment *Comment) Update(ctx context.Context, body string, user *User) error {
-	if !comment.isPermit(user) {
+	if comment.IsDeleted() {
+		return session.ForbiddenError(ctx)
+	}
+	if can, err := comment.isPermit(ctx, user); err != nil {
+		return err
+	} else if !can {
 		return session.ForbiddenError(ctx)
 	}
 	body = strings.TrimSpace(body)
 	if len(body) < commentBodySizeLimit {
 		return session.BadDataError(ctx)
 	}
+	prev := *comment
 	comment.Body = body
 	comment.UpdatedAt = time.Now()
+	var mentioned []*User
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
 		cols, posits := durable.PrepareColumnsAndExpressions([]string{"body", "updated_at"}, 1)
 		_, err := tx.Exec(ctx, fmt.Sprintf("UPDATE comments SET (%s)=(%s) WHERE comment_id=$1", cols, posits), comment.CommentID, comment.Body, comment.UpdatedAt)
-		return err
+		if err != nil {
+			return err
+		}
+		comment.Mentions, mentioned, err = syncMentions(ctx, tx, comment.TopicID, comment.CommentID, comment.Body)
+		if err != nil {
+			return err
+		}
+		return recordCommentRevision(ctx, tx, &prev, comment, user)
 	})
 	if err != nil {
 		return session.TransactionError(ctx, err)
 	}
+	notifyMentions(ctx, mentioned, user, comment.TopicID, comment.CommentID)
 	return nil
 }
 
-// ReadComments read all comments, parameters: offset default time.Now()
+// ReadComments read all comments, parameters: offset default time.Now().
+// The comments of a topic in a nested category are the top level ones, and
+// replies quote their parents otherwise.
 func ReadComments(ctx context.Context, offset time.Time, topic *Topic, user *User) ([]*Comment, error) {
 	if offset.IsZero() {
 		offset = time.Now()
@@ -117,43 +211,59 @@ func ReadComments(ctx context.Context, offset time.Time, topic *Topic, user *Use
 		params = append([]any{topic.TopicID}, params...)
 	}
 	if user != nil {
-		query = fmt.Sprintf("SELECT %s FROM comments WHERE user_id=$1 AND created_at<$2 ORDER BY created_at DESC LIMIT $3", strings.Join(commentColumns, ","))
+		query = fmt.Sprintf("SELECT %s FROM comments WHERE user_id=$1 AND deleted_at IS NULL AND created_at<$2 ORDER BY created_at DESC LIMIT $3", strings.Join(commentColumns, ","))
 		params = append([]any{user.UserID}, params...)
 	}
 
 	var comments []*Comment
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
-		rows, err := tx.Query(ctx, query, params...)
-		if err != nil {
-			return err
-		}
-		defer rows.Close()
-
-		var userIds []string
-		for rows.Next() {
-			comment, err := commentFromRows(rows)
+		if topic != nil && user == nil {
+			nested, err := isTopicCommentsNested(ctx, tx, topic)
 			if err != nil {
 				return err
 			}
-			comment.User = user
-			if comment.User == nil {
-				userIds = append(userIds, comment.UserID)
+			if nested {
+				query = fmt.Sprintf("SELECT %s FROM comments WHERE topic_id=$1 AND parent_comment_id IS NULL AND created_at<$2 ORDER BY created_at LIMIT $3", strings.Join(commentColumns, ","))
 			}
-			comments = append(comments, comment)
 		}
-		if err := rows.Err(); err != nil {
+		var err error
+		comments, err = queryComments(ctx, tx, query, params...)
+		if err != nil {
 			return err
 		}
-		if len(userIds) > 0 {
-			userSet, err := readUserSet(ctx, tx, userIds)
-			if err != nil {
-				return err
-			}
-			for i, comment := range comments {
-				comments[i].User = userSet[comment.UserID]
-			}
+		for _, comment := range comments {
+			comment.User = user
 		}
-		return nil
+		return fillComments(ctx, tx, comments)
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return comments, nil
+}
+
+// ReadBestComments read the comments of a topic by score, the newer first for
+// the same score, parameters: offset is the count of comments to skip
+func ReadBestComments(ctx context.Context, topic *Topic, offset int) ([]*Comment, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	var comments []*Comment
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		nested, err := isTopicCommentsNested(ctx, tx, topic)
+		if err != nil {
+			return err
+		}
+		condition := "topic_id=$1"
+		if nested {
+			condition = "topic_id=$1 AND parent_comment_id IS NULL"
+		}
+		query := fmt.Sprintf("SELECT %s FROM comments WHERE %s ORDER BY score DESC,created_at DESC LIMIT $2 OFFSET $3", strings.Join(commentColumns, ","), condition)
+		comments, err = queryComments(ctx, tx, query, topic.TopicID, LIMIT, offset)
+		if err != nil {
+			return err
+		}
+		return fillComments(ctx, tx, comments)
 	})
 	if err != nil {
 		return nil, session.TransactionError(ctx, err)
@@ -161,9 +271,57 @@ func ReadComments(ctx context.Context, offset time.Time, topic *Topic, user *Use
 	return comments, nil
 }
 
-// DeleteComment delete a comment by ID
+func queryComments(ctx context.Context, tx pgx.Tx, query string, params ...any) ([]*Comment, error) {
+	rows, err := tx.Query(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*Comment
+	for rows.Next() {
+		comment, err := commentFromRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// DeleteComment turn a comment into a tombstone, which keeps its place in the topic
 func (comment *Comment) Delete(ctx context.Context, user *User) error {
-	if !comment.isPermit(user) {
+	if can, err := comment.isPermit(ctx, user); err != nil {
+		return err
+	} else if !can {
+		return session.ForbiddenError(ctx)
+	}
+	if comment.IsDeleted() {
+		return nil
+	}
+	deletedAt := sql.NullTime{Time: time.Now(), Valid: true}
+	deletedBy := sql.NullString{String: user.UserID, Valid: true}
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "UPDATE comments SET (deleted_at,deleted_by)=($1,$2) WHERE comment_id=$3", deletedAt, deletedBy, comment.CommentID)
+		if err != nil {
+			return err
+		}
+		if err := updateRepliesCount(ctx, tx, comment.ParentCommentID); err != nil {
+			return err
+		}
+		return updateCommentsCount(ctx, tx, comment.TopicID)
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	comment.DeletedAt, comment.DeletedBy = deletedAt, deletedBy
+	UpsertStatistic(ctx, StatisticTypeComments)
+	return nil
+}
+
+// Restore a deleted comment, only for moderators
+func (comment *Comment) Restore(ctx context.Context, user *User) error {
+	if user == nil {
 		return session.ForbiddenError(ctx)
 	}
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
@@ -173,23 +331,28 @@ func (comment *Comment) Delete(ctx context.Context, user *User) error {
 		} else if topic == nil {
 			return session.BadDataError(ctx)
 		}
-		count, err := fetchCommentsCount(ctx, tx, comment.TopicID)
-		if err != nil {
+		if can, err := user.canModerateCategory(ctx, tx, topic.CategoryID, PermissionCommentEditAny); err != nil {
 			return err
+		} else if !can {
+			return session.ForbiddenError(ctx)
 		}
-		topic.CommentsCount = count - 1
-		cols, posits := durable.PrepareColumnsAndExpressions([]string{"comments_count", "updated_at"}, 1)
-		query := fmt.Sprintf("UPDATE topics SET (%s)=(%s) WHERE topic_id=$1", cols, posits)
-		_, err = tx.Exec(ctx, query, topic.TopicID, topic.CommentsCount, topic.UpdatedAt)
+		if !comment.IsDeleted() {
+			return nil
+		}
+		_, err = tx.Exec(ctx, "UPDATE comments SET (deleted_at,deleted_by)=(NULL,NULL) WHERE comment_id=$1", comment.CommentID)
 		if err != nil {
 			return err
 		}
-		_, err = tx.Exec(ctx, "DELETE FROM comments WHERE comment_id=$1", comment.CommentID)
-		return err
+		if err := updateRepliesCount(ctx, tx, comment.ParentCommentID); err != nil {
+			return err
+		}
+		return updateCommentsCount(ctx, tx, comment.TopicID)
 	})
 	if err != nil {
 		return session.TransactionError(ctx, err)
 	}
+	comment.DeletedAt, comment.DeletedBy = sql.NullTime{}, sql.NullString{}
+	UpsertStatistic(ctx, StatisticTypeComments)
 	return nil
 }
 
@@ -220,22 +383,44 @@ func findComment(ctx context.Context, tx pgx.Tx, id string) (*Comment, error) {
 
 func fetchCommentsCount(ctx context.Context, tx pgx.Tx, topicID string) (int64, error) {
 	var count int64
-	query := "SELECT count(*) FROM comments"
+	query := "SELECT count(*) FROM comments WHERE deleted_at IS NULL"
 	params := []any{}
 	if uuid.FromStringOrNil(topicID).String() == topicID {
-		query = "SELECT count(*) FROM comments WHERE topic_id=$1"
+		query = "SELECT count(*) FROM comments WHERE topic_id=$1 AND deleted_at IS NULL"
 		params = []any{topicID}
 	}
 	err := tx.QueryRow(ctx, query, params...).Scan(&count)
 	return count, err
 }
 
-func (comment *Comment) isPermit(user *User) bool {
+// updateCommentsCount recount the comments of the topic, tombstones excluded
+func updateCommentsCount(ctx context.Context, tx pgx.Tx, topicID string) error {
+	count, err := fetchCommentsCount(ctx, tx, topicID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "UPDATE topics SET comments_count=$1 WHERE topic_id=$2", count, topicID)
+	return err
+}
+
+func (comment *Comment) isPermit(ctx context.Context, user *User) (bool, error) {
 	if user == nil {
-		return false
+		return false, nil
+	}
+	if comment.UserID == user.UserID {
+		return true, nil
 	}
-	if user.isAdmin() {
-		return true
+	var can bool
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		topic, err := findTopic(ctx, tx, comment.TopicID)
+		if err != nil || topic == nil {
+			return err
+		}
+		can, err = user.canModerateCategory(ctx, tx, topic.CategoryID, PermissionCommentEditAny)
+		return err
+	})
+	if err != nil {
+		return false, session.TransactionError(ctx, err)
 	}
-	return comment.UserID == user.UserID
+	return can, nil
 }
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"satellity/internal/durable"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// CommentRevision is a version of a comment body, version 1 is the original
+type CommentRevision struct {
+	RevisionID string
+	CommentID  string
+	UserID     sql.NullString
+	Version    int64
+	Body       string
+	CreatedAt  time.Time
+
+	User     *User
+	BodyDiff string
+}
+
+var commentRevisionColumns = []string{"revision_id", "comment_id", "user_id", "version", "body", "created_at"}
+
+func (r *CommentRevision) values() []interface{} {
+	return []interface{}{r.RevisionID, r.CommentID, r.UserID, r.Version, r.Body, r.CreatedAt}
+}
+
+func commentRevisionFromRows(row durable.Row) (*CommentRevision, error) {
+	var r CommentRevision
+	err := row.Scan(&r.RevisionID, &r.CommentID, &r.UserID, &r.Version, &r.Body, &r.CreatedAt)
+	return &r, err
+}
+
+// ReadRevisions read the revisions of a comment with the diffs to the previous
+// revision, a deleted comment has none
+func (comment *Comment) ReadRevisions(ctx context.Context) ([]*CommentRevision, error) {
+	if comment.IsDeleted() {
+		return nil, nil
+	}
+	var revisions []*CommentRevision
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, fmt.Sprintf("SELECT %s FROM comment_revisions WHERE comment_id=$1 ORDER BY comment_id,version", strings.Join(commentRevisionColumns, ",")), comment.CommentID)
+		if err != nil {
+			return err
+		}
+		var userIDs []string
+		for rows.Next() {
+			r, err := commentRevisionFromRows(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			if r.UserID.Valid {
+				userIDs = append(userIDs, r.UserID.String)
+			}
+			revisions = append(revisions, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		set, err := readUserSet(ctx, tx, userIDs)
+		if err != nil {
+			return err
+		}
+		for _, r := range revisions {
+			r.User = set[r.UserID.String]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	for i, r := range revisions {
+		var prev CommentRevision
+		if i > 0 {
+			prev = *revisions[i-1]
+		}
+		r.BodyDiff, err = revisionDiff(prev.Body, r.Body, prev.Version, r.Version)
+		if err != nil {
+			return nil, session.ServerError(ctx, err)
+		}
+	}
+	return revisions, nil
+}
+
+// recordCommentRevision append the comment as a new revision if the body changed.
+// The original is recorded first if the comment has no revisions.
+func recordCommentRevision(ctx context.Context, tx pgx.Tx, prev, comment *Comment, user *User) error {
+	row := tx.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM comment_revisions WHERE comment_id=$1 ORDER BY comment_id,version DESC LIMIT 1", strings.Join(commentRevisionColumns, ",")), comment.CommentID)
+	last, err := commentRevisionFromRows(row)
+	if err == pgx.ErrNoRows {
+		last = nil
+	} else if err != nil {
+		return err
+	}
+	var rows [][]interface{}
+	if last == nil {
+		last = &CommentRevision{
+			RevisionID: uuid.Must(uuid.NewV4()).String(),
+			CommentID:  prev.CommentID,
+			UserID:     sql.NullString{String: prev.UserID, Valid: true},
+			Version:    1,
+			Body:       prev.Body,
+			CreatedAt:  prev.UpdatedAt,
+		}
+		rows = append(rows, last.values())
+	}
+	if last.Body == comment.Body {
+		return nil
+	}
+	r := &CommentRevision{
+		RevisionID: uuid.Must(uuid.NewV4()).String(),
+		CommentID:  comment.CommentID,
+		UserID:     sql.NullString{String: user.UserID, Valid: true},
+		Version:    last.Version + 1,
+		Body:       comment.Body,
+		CreatedAt:  comment.UpdatedAt,
+	}
+	rows = append(rows, r.values())
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"comment_revisions"}, commentRevisionColumns, pgx.CopyFromRows(rows))
+	return err
+}
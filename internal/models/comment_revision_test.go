@@ -0,0 +1,61 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentRevision(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	moderator := createTestUser(ctx, "moderator@gmail.com", "moderator", "password")
+	assert.NotNil(moderator)
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+	topic, err := user.CreateTopic(ctx, "title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	comment, err := user.CreateComment(ctx, "comment body", topic)
+	assert.Nil(err)
+
+	revisions, err := comment.ReadRevisions(ctx)
+	assert.Nil(err)
+	assert.Len(revisions, 0)
+	err = comment.Update(ctx, "comment body", user)
+	assert.Nil(err)
+	err = comment.Update(ctx, "edited comment body", user)
+	assert.Nil(err)
+	revisions, err = comment.ReadRevisions(ctx)
+	assert.Nil(err)
+	assert.Len(revisions, 2)
+	assert.Equal("comment body", revisions[0].Body)
+	assert.Equal(int64(2), revisions[1].Version)
+	assert.Contains(revisions[1].BodyDiff, "+edited comment body")
+
+	err = category.AddModerator(ctx, moderator)
+	assert.Nil(err)
+	err = comment.Delete(ctx, moderator)
+	assert.Nil(err)
+	assert.True(comment.IsDeletedByModerator())
+	topic, _ = ReadTopic(ctx, topic.TopicID)
+	assert.Equal(int64(0), topic.CommentsCount)
+	revisions, err = comment.ReadRevisions(ctx)
+	assert.Nil(err)
+	assert.Len(revisions, 0)
+
+	err = comment.Restore(ctx, user)
+	assert.NotNil(err)
+	err = comment.Restore(ctx, moderator)
+	assert.Nil(err)
+	assert.False(comment.IsDeleted())
+	existing, err := ReadComment(ctx, comment.CommentID)
+	assert.Nil(err)
+	assert.False(existing.IsDeleted())
+	assert.Equal("edited comment body", existing.Body)
+	topic, _ = ReadTopic(ctx, topic.TopicID)
+	assert.Equal(int64(1), topic.CommentsCount)
+}
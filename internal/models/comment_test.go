@@ -75,13 +75,17 @@ func TestCommentCRUD(t *testing.T) {
 			assert.Equal(int64(0), topic.CommentsCount)
 			comments, err = ReadComments(ctx, time.Time{}, topic, nil)
 			assert.Nil(err)
-			assert.Len(comments, 0)
+			assert.Len(comments, 1)
+			assert.True(comments[0].IsDeleted())
+			assert.False(comments[0].IsDeletedByModerator())
 			comments, err = ReadComments(ctx, time.Time{}, nil, user)
 			assert.Nil(err)
 			assert.Len(comments, 0)
 			new, err = readTestComment(ctx, comment.CommentID)
 			assert.Nil(err)
-			assert.Nil(new)
+			assert.True(new.IsDeleted())
+			err = new.Update(ctx, "deleted comment body", user)
+			assert.NotNil(err)
 		})
 	}
 }
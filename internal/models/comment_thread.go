@@ -0,0 +1,144 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"satellity/internal/session"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Replies are nested up to commentDepthLimit, a reply to a comment at the limit
+// becomes its sibling. A subtree reads at most commentSubtreeLimit replies.
+const (
+	commentDepthLimit   = 5
+	commentSubtreeLimit = 500
+)
+
+// ReadReplies read the subtree of replies under the comment, in thread order,
+// the tombstones are kept to hold the threads together
+func (comment *Comment) ReadReplies(ctx context.Context) ([]*Comment, error) {
+	prefixed := make([]string, len(commentColumns))
+	for i, col := range commentColumns {
+		prefixed[i] = "c." + col
+	}
+	cols := strings.Join(commentColumns, ",")
+	query := fmt.Sprintf("WITH RECURSIVE tree AS (SELECT %s, ARRAY[created_at] AS path FROM comments WHERE parent_comment_id=$1 UNION ALL SELECT %s, tree.path||c.created_at FROM comments c INNER JOIN tree ON c.parent_comment_id=tree.comment_id) SELECT %s FROM tree ORDER BY path LIMIT $2", cols, strings.Join(prefixed, ","), cols)
+
+	var comments []*Comment
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		comments, err = queryComments(ctx, tx, query, comment.CommentID, commentSubtreeLimit)
+		if err != nil {
+			return err
+		}
+		return fillComments(ctx, tx, comments)
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return comments, nil
+}
+
+// isTopicCommentsNested is true if the category of the topic shows nested threads
+func isTopicCommentsNested(ctx context.Context, tx pgx.Tx, topic *Topic) (bool, error) {
+	category, err := findCategory(ctx, tx, topic.CategoryID)
+	if err != nil || category == nil {
+		return false, err
+	}
+	return category.CommentsView == CategoryCommentsViewNested, nil
+}
+
+// replyTo attach the new comment to its parent, which must be a live comment of the topic
+func (c *Comment) replyTo(ctx context.Context, parent *Comment, topic *Topic) error {
+	if parent == nil || parent.TopicID != topic.TopicID {
+		return session.BadDataError(ctx)
+	}
+	if parent.IsDeleted() {
+		return session.ForbiddenError(ctx)
+	}
+	if parent.Depth >= commentDepthLimit {
+		c.ParentCommentID = parent.ParentCommentID
+		c.Depth = parent.Depth
+		return nil
+	}
+	c.ParentCommentID = sql.NullString{String: parent.CommentID, Valid: true}
+	c.Depth = parent.Depth + 1
+	return nil
+}
+
+// fillComments set the users and mentions of the comments, and the parents of
+// replies with their users for quoting
+func fillComments(ctx context.Context, tx pgx.Tx, comments []*Comment) error {
+	if err := fillCommentMentions(ctx, tx, comments); err != nil {
+		return err
+	}
+	var parentIDs []string
+	for _, c := range comments {
+		if c.ParentCommentID.Valid {
+			parentIDs = append(parentIDs, c.ParentCommentID.String)
+		}
+	}
+	parents, err := readCommentSet(ctx, tx, parentIDs)
+	if err != nil {
+		return err
+	}
+	var userIDs []string
+	for _, c := range comments {
+		if c.User == nil {
+			userIDs = append(userIDs, c.UserID)
+		}
+		if p := parents[c.ParentCommentID.String]; p != nil {
+			c.Parent = p
+			userIDs = append(userIDs, p.UserID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+	set, err := readUserSet(ctx, tx, userIDs)
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if c.User == nil {
+			c.User = set[c.UserID]
+		}
+		if c.Parent != nil {
+			c.Parent.User = set[c.Parent.UserID]
+		}
+	}
+	return nil
+}
+
+func readCommentSet(ctx context.Context, tx pgx.Tx, ids []string) (map[string]*Comment, error) {
+	set := make(map[string]*Comment)
+	if len(ids) == 0 {
+		return set, nil
+	}
+	rows, err := tx.Query(ctx, fmt.Sprintf("SELECT %s FROM comments WHERE comment_id=ANY($1)", strings.Join(commentColumns, ",")), ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c, err := commentFromRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		set[c.CommentID] = c
+	}
+	return set, rows.Err()
+}
+
+// updateRepliesCount recount the direct replies of the parent, tombstones excluded
+func updateRepliesCount(ctx context.Context, tx pgx.Tx, parentID sql.NullString) error {
+	if !parentID.Valid {
+		return nil
+	}
+	_, err := tx.Exec(ctx, "UPDATE comments SET replies_count=(SELECT count(*) FROM comments c WHERE c.parent_comment_id=$1 AND c.deleted_at IS NULL) WHERE comment_id=$1", parentID.String)
+	return err
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentThread(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+	assert.Equal(CategoryCommentsViewFlat, category.CommentsView)
+	topic, err := user.CreateTopic(ctx, "title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	other, err := user.CreateTopic(ctx, "other title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+
+	root, err := user.CreateComment(ctx, "root comment", topic)
+	assert.Nil(err)
+	assert.False(root.ParentCommentID.Valid)
+	assert.Equal(0, root.Depth)
+	reply, err := user.CreateReply(ctx, "reply comment", other, root.CommentID)
+	assert.NotNil(err)
+	assert.Nil(reply)
+	reply, err = user.CreateReply(ctx, "reply comment", topic, uuid.Must(uuid.NewV4()).String())
+	assert.NotNil(err)
+	assert.Nil(reply)
+
+	parent := root
+	for i := 1; i <= commentDepthLimit+1; i++ {
+		reply, err = user.CreateReply(ctx, "reply comment", topic, parent.CommentID)
+		assert.Nil(err)
+		assert.NotNil(reply)
+		if i <= commentDepthLimit {
+			assert.Equal(parent.CommentID, reply.ParentCommentID.String)
+			assert.Equal(i, reply.Depth)
+		} else {
+			assert.Equal(parent.ParentCommentID.String, reply.ParentCommentID.String)
+			assert.Equal(commentDepthLimit, reply.Depth)
+		}
+		parent = reply
+	}
+	root, err = readTestComment(ctx, root.CommentID)
+	assert.Nil(err)
+	assert.Equal(int64(1), root.RepliesCount)
+	replies, err := root.ReadReplies(ctx)
+	assert.Nil(err)
+	assert.Len(replies, commentDepthLimit+1)
+	assert.Equal(root.CommentID, replies[0].ParentCommentID.String)
+	assert.NotNil(replies[0].User)
+
+	comments, err := ReadComments(ctx, time.Time{}, topic, nil)
+	assert.Nil(err)
+	assert.Len(comments, commentDepthLimit+2)
+	assert.Nil(comments[0].Parent)
+	assert.NotNil(comments[1].Parent)
+	assert.Equal(root.CommentID, comments[1].Parent.CommentID)
+	assert.NotNil(comments[1].Parent.User)
+
+	assert.NotNil(category.SetCommentsView(ctx, "tree"))
+	assert.Nil(category.SetCommentsView(ctx, CategoryCommentsViewNested))
+	assert.Equal(CategoryCommentsViewNested, category.CommentsView)
+	comments, err = ReadComments(ctx, time.Time{}, topic, nil)
+	assert.Nil(err)
+	assert.Len(comments, 1)
+	assert.Equal(root.CommentID, comments[0].CommentID)
+
+	err = replies[0].Delete(ctx, user)
+	assert.Nil(err)
+	root, err = readTestComment(ctx, root.CommentID)
+	assert.Nil(err)
+	assert.Equal(int64(0), root.RepliesCount)
+	reply, err = user.CreateReply(ctx, "reply comment", topic, replies[0].CommentID)
+	assert.NotNil(err)
+	assert.Nil(reply)
+	replies, err = root.ReadReplies(ctx)
+	assert.Nil(err)
+	assert.Len(replies, commentDepthLimit+1)
+	assert.True(replies[0].IsDeleted())
+}
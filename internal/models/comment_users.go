@@ -0,0 +1,108 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"satellity/internal/durable"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Votes of a user on a comment, the score of a comment is the sum of its votes
+const (
+	CommentVoteDown = -1
+	CommentVoteNone = 0
+	CommentVoteUp   = 1
+)
+
+// CommentUser contains the vote of a user on a comment
+type CommentUser struct {
+	CommentID string
+	UserID    string
+	Vote      int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+var commentUserColumns = []string{"comment_id", "user_id", "vote", "created_at", "updated_at"}
+
+func commentUserFromRow(row durable.Row) (*CommentUser, error) {
+	var cu CommentUser
+	err := row.Scan(&cu.CommentID, &cu.UserID, &cu.Vote, &cu.CreatedAt, &cu.UpdatedAt)
+	return &cu, err
+}
+
+// VoteBy set the vote of the user on the comment, CommentVoteNone withdraws it.
+// Votes on a comment are serialized by the lock of its row, which keeps the
+// score the sum of the votes under concurrent voting.
+func (comment *Comment) VoteBy(ctx context.Context, user *User, vote int) error {
+	if vote < CommentVoteDown || vote > CommentVoteUp {
+		return session.BadDataError(ctx)
+	}
+	if user == nil || user.UserID == comment.UserID || comment.IsDeleted() {
+		return session.ForbiddenError(ctx)
+	}
+	t := time.Now()
+	err := session.Database(ctx).RunInReadCommittedTransaction(ctx, func(tx pgx.Tx) error {
+		var score int
+		err := tx.QueryRow(ctx, "SELECT score FROM comments WHERE comment_id=$1 FOR UPDATE", comment.CommentID).Scan(&score)
+		if err == pgx.ErrNoRows {
+			return session.NotFoundError(ctx)
+		} else if err != nil {
+			return err
+		}
+		var prev int
+		err = tx.QueryRow(ctx, "SELECT vote FROM comment_users WHERE comment_id=$1 AND user_id=$2", comment.CommentID, user.UserID).Scan(&prev)
+		if err != nil && err != pgx.ErrNoRows {
+			return err
+		}
+		_, err = tx.Exec(ctx, "INSERT INTO comment_users (comment_id,user_id,vote,created_at,updated_at) VALUES ($1,$2,$3,$4,$4) ON CONFLICT (comment_id,user_id) DO UPDATE SET (vote,updated_at)=($3,$4)", comment.CommentID, user.UserID, vote, t)
+		if err != nil {
+			return err
+		}
+		comment.Score = score + vote - prev
+		_, err = tx.Exec(ctx, "UPDATE comments SET score=$1 WHERE comment_id=$2", comment.Score, comment.CommentID)
+		return err
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	comment.Vote = vote
+	return nil
+}
+
+// FillCommentVotes set the votes of the user on the comments
+func FillCommentVotes(ctx context.Context, comments []*Comment, user *User) error {
+	if user == nil || len(comments) == 0 {
+		return nil
+	}
+	ids := make([]string, len(comments))
+	for i, c := range comments {
+		ids[i] = c.CommentID
+	}
+	query := fmt.Sprintf("SELECT %s FROM comment_users WHERE user_id=$1 AND comment_id=ANY($2)", strings.Join(commentUserColumns, ","))
+	rows, err := session.Database(ctx).Query(ctx, query, user.UserID, ids)
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	defer rows.Close()
+
+	votes := make(map[string]int)
+	for rows.Next() {
+		cu, err := commentUserFromRow(rows)
+		if err != nil {
+			return session.TransactionError(ctx, err)
+		}
+		votes[cu.CommentID] = cu.Vote
+	}
+	if err := rows.Err(); err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	for _, c := range comments {
+		c.Vote = votes[c.CommentID]
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentVote(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	other := createTestUser(ctx, "other@gmail.com", "other", "password")
+	assert.NotNil(other)
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+	topic, err := user.CreateTopic(ctx, "title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	first, err := user.CreateComment(ctx, "first comment", topic)
+	assert.Nil(err)
+	second, err := user.CreateComment(ctx, "second comment", topic)
+	assert.Nil(err)
+
+	assert.NotNil(first.VoteBy(ctx, user, CommentVoteUp))
+	assert.NotNil(first.VoteBy(ctx, other, 2))
+	assert.Nil(second.VoteBy(ctx, other, CommentVoteUp))
+	assert.Equal(1, second.Score)
+	assert.Equal(CommentVoteUp, second.Vote)
+	assert.Nil(second.VoteBy(ctx, other, CommentVoteUp))
+	assert.Equal(1, second.Score)
+	assert.Nil(first.VoteBy(ctx, other, CommentVoteDown))
+	assert.Equal(-1, first.Score)
+	second, err = readTestComment(ctx, second.CommentID)
+	assert.Nil(err)
+	assert.Equal(1, second.Score)
+
+	comments, err := ReadBestComments(ctx, topic, 0)
+	assert.Nil(err)
+	assert.Len(comments, 2)
+	assert.Equal(second.CommentID, comments[0].CommentID)
+	assert.Equal(0, comments[0].Vote)
+	assert.Nil(FillCommentVotes(ctx, comments, other))
+	assert.Equal(CommentVoteUp, comments[0].Vote)
+	assert.Equal(CommentVoteDown, comments[1].Vote)
+	comments, err = ReadBestComments(ctx, topic, 1)
+	assert.Nil(err)
+	assert.Len(comments, 1)
+	assert.Equal(first.CommentID, comments[0].CommentID)
+
+	assert.Nil(second.VoteBy(ctx, other, CommentVoteNone))
+	assert.Equal(0, second.Score)
+	assert.Nil(other.DeleteAccount(ctx, AccountDeletionHard, "password"))
+	first, err = readTestComment(ctx, first.CommentID)
+	assert.Nil(err)
+	assert.Equal(0, first.Score)
+}
@@ -2,43 +2,21 @@ package models
 
 import (
 	"context"
-	"io/ioutil"
 	"log"
 	"satellity/internal/configs"
 	"satellity/internal/durable"
+	"satellity/internal/migrations"
 	"satellity/internal/session"
 )
 
 const (
 	testEnvironment = "test"
 	testDatabase    = "satellity_test"
-
-	dropCategoriesDDL        = `DROP TABLE IF EXISTS categories;`
-	dropCommentsDDL          = `DROP TABLE IF EXISTS comments;`
-	dropEmailVerificationDDL = `DROP TABLE IF EXISTS email_verifications;`
-	dropSessionsDDL          = `DROP TABLE IF EXISTS sessions;`
-	dropStatisticsDDL        = `DROP TABLE IF EXISTS statistics;`
-	dropTopicsDDL            = `DROP TABLE IF EXISTS topics;`
-	dropTopicUsersDDL        = `DROP TABLE IF EXISTS topic_users;`
-	dropUsersDDL             = `DROP TABLE IF EXISTS users;`
 )
 
 func teardownTestContext(ctx context.Context) {
-	tables := []string{
-		dropStatisticsDDL,
-		dropCommentsDDL,
-		dropTopicUsersDDL,
-		dropTopicsDDL,
-		dropCategoriesDDL,
-		dropEmailVerificationDDL,
-		dropSessionsDDL,
-		dropUsersDDL,
-	}
-	db := session.Database(ctx)
-	for _, q := range tables {
-		if _, err := db.Exec(ctx, q); err != nil {
-			log.Panicln(err)
-		}
+	if _, err := migrations.Down(ctx, session.Database(ctx), -1); err != nil {
+		log.Panicln(err)
 	}
 }
 
@@ -57,13 +35,9 @@ func setupTestContext() context.Context {
 		Port:     config.Database.Port,
 		Name:     config.Database.Name,
 	})
-	data, err := ioutil.ReadFile("./schema.sql")
-	if err != nil {
-		log.Panicln(err)
-	}
-	if _, err := db.Exec(context.Background(), string(data)); err != nil {
+	database := durable.WrapDatabase(db)
+	if _, err := migrations.Up(context.Background(), database); err != nil {
 		log.Panicln(err)
 	}
-	database := durable.WrapDatabase(db)
 	return session.WithDatabase(context.Background(), database)
 }
@@ -0,0 +1,270 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"satellity/internal/clouds"
+	"satellity/internal/configs"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Notification emails are batched, a user gets at most one every
+// EmailNotificationInterval with the unread notifications since the last one.
+// The comments of a busy thread are grouped into one notification already.
+// Unread notifications older than emailNotificationWindow are never emailed.
+const (
+	EmailDeliveryInterval     = 5 * time.Minute
+	EmailNotificationInterval = 30 * time.Minute
+
+	emailNotificationWindow = 24 * time.Hour
+	emailDeliveryBatch      = 100
+	emailDigestTopicsLimit  = 10
+	emailDeliveryLockKey    = 0x5a7e11002
+)
+
+// DeliverEmails send the notification emails, then the daily and weekly
+// digests, errors are logged. Only one instance delivers at a time, so a user
+// is never emailed twice.
+func DeliverEmails(ctx context.Context) {
+	_, err := session.Database(ctx).RunWithAdvisoryLock(ctx, emailDeliveryLockKey, func() error {
+		SendNotificationEmails(ctx)
+		SendDigestEmails(ctx, EmailDigestDaily)
+		SendDigestEmails(ctx, EmailDigestWeekly)
+		return nil
+	})
+	if err != nil {
+		session.TransactionError(ctx, err)
+	}
+}
+
+// SendNotificationEmails email the users their unread replies and mentions by
+// the preferences, return the count of emails sent. The error of a user is
+// logged, and the user is tried again in the next delivery.
+func SendNotificationEmails(ctx context.Context) (int, error) {
+	t := time.Now()
+	query := "SELECT DISTINCT n.user_id FROM notifications n INNER JOIN users u ON u.user_id=n.user_id LEFT JOIN email_preferences p ON p.user_id=n.user_id WHERE n.user_id>$5 AND n.read_at IS NULL AND n.updated_at>$1 AND n.notification_type=ANY($2) AND u.email IS NOT NULL AND (p.user_id IS NULL OR ((p.replies OR p.mentions) AND (p.notified_at IS NULL OR p.notified_at<$3) AND n.updated_at>COALESCE(p.notified_at,$1))) ORDER BY n.user_id LIMIT $4"
+	types := append(emailNotificationTypes(true, false), emailNotificationTypes(false, true)...)
+	var count int
+	var last string
+	for {
+		var ids []string
+		err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+			var err error
+			ids, err = queryIDs(ctx, tx, query, t.Add(-emailNotificationWindow), types, t.Add(-EmailNotificationInterval), emailDeliveryBatch, last)
+			return err
+		})
+		if err != nil {
+			return count, session.TransactionError(ctx, err)
+		}
+		if len(ids) == 0 {
+			return count, nil
+		}
+		last = ids[len(ids)-1]
+		for _, id := range ids {
+			if sent, _ := sendNotificationEmail(ctx, id, t); sent {
+				count++
+			}
+		}
+	}
+}
+
+// sendNotificationEmail email the unread notifications since the last email,
+// then mark the user notified at t, the user isn't marked if it fails
+func sendNotificationEmail(ctx context.Context, userID string, t time.Time) (bool, error) {
+	var user *User
+	var notifications []*Notification
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		user, err = findUserByID(ctx, tx, userID)
+		if err != nil || user == nil {
+			return err
+		}
+		p, err := readEmailPreference(ctx, tx, user.UserID)
+		if err != nil {
+			return err
+		}
+		since := t.Add(-emailNotificationWindow)
+		if p.NotifiedAt.Valid && p.NotifiedAt.Time.After(since) {
+			since = p.NotifiedAt.Time
+		}
+		query := fmt.Sprintf("SELECT %s FROM notifications WHERE user_id=$1 AND read_at IS NULL AND updated_at>$2 AND notification_type=ANY($3) ORDER BY user_id,updated_at DESC LIMIT $4", strings.Join(notificationColumns, ","))
+		notifications, err = queryNotifications(ctx, tx, query, user.UserID, since, emailNotificationTypes(p.Replies, p.Mentions), LIMIT)
+		return err
+	})
+	if err != nil {
+		return false, session.TransactionError(ctx, err)
+	}
+	if user == nil || !user.Email.Valid || len(notifications) == 0 {
+		return false, nil
+	}
+	unsubscribe, err := emailUnsubscribeURL(user.UserID, EmailUnsubscribeNotifications)
+	if err != nil {
+		return false, session.ServerError(ctx, err)
+	}
+	email := clouds.NotificationEmail{
+		Name:           user.Name(),
+		UnsubscribeURL: unsubscribe,
+	}
+	for _, n := range notifications {
+		if n.Topic == nil {
+			continue
+		}
+		email.Items = append(email.Items, clouds.EmailItem{
+			Title: n.Topic.Title,
+			Text:  notificationText(n),
+			URL:   topicURL(n.Topic.TopicID),
+		})
+	}
+	if len(email.Items) == 0 {
+		return false, nil
+	}
+	if err := clouds.SendNotificationEmail(ctx, user.Email.String, email); err != nil {
+		return false, session.ServerError(ctx, err)
+	}
+	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		p, err := readEmailPreference(ctx, tx, user.UserID)
+		if err != nil {
+			return err
+		}
+		p.NotifiedAt.Time, p.NotifiedAt.Valid = t, true
+		return upsertEmailPreference(ctx, tx, p)
+	})
+	if err != nil {
+		return false, session.TransactionError(ctx, err)
+	}
+	return true, nil
+}
+
+// SendDigestEmails email the popular topics of the period, day or week, to the
+// users who choose the digest of the period, return the count of emails sent.
+// The error of a user is logged, and the user is tried again in the next
+// delivery.
+func SendDigestEmails(ctx context.Context, period string) (int, error) {
+	if period != EmailDigestDaily && period != EmailDigestWeekly {
+		return 0, session.BadDataErrorWithFieldAndData(ctx, "period", "invalid", period)
+	}
+	topics, err := ReadRankedTopics(ctx, TopicOrderTop, period, 0)
+	if err != nil || len(topics) == 0 {
+		return 0, err
+	}
+	if len(topics) > emailDigestTopicsLimit {
+		topics = topics[:emailDigestTopicsLimit]
+	}
+	items := make([]clouds.EmailItem, len(topics))
+	for i, topic := range topics {
+		items[i] = clouds.EmailItem{
+			Title: topic.Title,
+			Text:  fmt.Sprintf("%d comments, %d likes", topic.CommentsCount, topic.LikesCount),
+			URL:   topicURL(topic.TopicID),
+		}
+	}
+
+	t := time.Now()
+	before := t.Add(-topicPeriods[period])
+	var count int
+	var last string
+	for {
+		var ids []string
+		var set map[string]*User
+		err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+			var err error
+			ids, err = queryIDs(ctx, tx, "SELECT p.user_id FROM email_preferences p INNER JOIN users u ON u.user_id=p.user_id WHERE p.user_id>$4 AND p.digest=$1 AND (p.digested_at IS NULL OR p.digested_at<$2) AND u.email IS NOT NULL ORDER BY p.user_id LIMIT $3", period, before, emailDeliveryBatch, last)
+			if err != nil || len(ids) == 0 {
+				return err
+			}
+			set, err = readUserSet(ctx, tx, ids)
+			return err
+		})
+		if err != nil {
+			return count, session.TransactionError(ctx, err)
+		}
+		if len(ids) == 0 {
+			return count, nil
+		}
+		last = ids[len(ids)-1]
+		for _, id := range ids {
+			if u := set[id]; u != nil && sendDigestEmail(ctx, u, period, items, t) == nil {
+				count++
+			}
+		}
+	}
+}
+
+// sendDigestEmail email the digest to the user, then move the digested_at a
+// period forward, so the digests keep the same time of the day or the week.
+// It starts again from t if the user missed a whole period.
+func sendDigestEmail(ctx context.Context, user *User, period string, items []clouds.EmailItem, t time.Time) error {
+	unsubscribe, err := emailUnsubscribeURL(user.UserID, EmailUnsubscribeDigest)
+	if err != nil {
+		return session.ServerError(ctx, err)
+	}
+	email := clouds.NotificationEmail{
+		Name:           user.Name(),
+		Items:          items,
+		UnsubscribeURL: unsubscribe,
+	}
+	if err := clouds.SendDigestEmail(ctx, user.Email.String, period, email); err != nil {
+		return session.ServerError(ctx, err)
+	}
+	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		p, err := readEmailPreference(ctx, tx, user.UserID)
+		if err != nil {
+			return err
+		}
+		next := t
+		if p.DigestedAt.Valid && p.DigestedAt.Time.Add(2*topicPeriods[period]).After(t) {
+			next = p.DigestedAt.Time.Add(topicPeriods[period])
+		}
+		p.DigestedAt.Time, p.DigestedAt.Valid = next, true
+		return upsertEmailPreference(ctx, tx, p)
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// emailNotificationTypes is the notification types emailed by the preferences
+func emailNotificationTypes(replies, mentions bool) []string {
+	types := []string{}
+	if replies {
+		types = append(types, NotificationTypeReplied, NotificationTypeCommented, NotificationTypeCreated)
+	}
+	if mentions {
+		types = append(types, NotificationTypeMentioned)
+	}
+	return types
+}
+
+// notificationText is a line about the notification, e.g. Jason and 4 others
+// replied to your comment
+func notificationText(n *Notification) string {
+	var name string
+	if len(n.Actors) > 0 {
+		name = n.Actors[0].Name()
+	}
+	if others := len(n.ActorIDs) - 1; others == 1 {
+		name += " and 1 other"
+	} else if others > 1 {
+		name += fmt.Sprintf(" and %d others", others)
+	}
+	switch n.NotificationType {
+	case NotificationTypeReplied:
+		return name + " replied to your comment"
+	case NotificationTypeCommented:
+		return name + " commented"
+	case NotificationTypeCreated:
+		return name + " posted a new topic"
+	case NotificationTypeMentioned:
+		return name + " mentioned you"
+	}
+	return name
+}
+
+func topicURL(id string) string {
+	return configs.AppConfig.Email.URL + "/topics/" + id
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"satellity/internal/configs"
+	"satellity/internal/session"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailDelivery(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	other := createTestUser(ctx, "other@gmail.com", "other", "password")
+	assert.NotNil(other)
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+	topic, err := user.CreateTopic(ctx, "title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+
+	_, err = other.CreateComment(ctx, "first comment", topic)
+	assert.Nil(err)
+	_, err = other.CreateComment(ctx, "second comment", topic)
+	assert.Nil(err)
+	locked, err := session.Database(ctx).RunWithAdvisoryLock(ctx, emailDeliveryLockKey, func() error {
+		DeliverEmails(ctx)
+		return nil
+	})
+	assert.Nil(err)
+	assert.True(locked)
+	p, err := user.ReadEmailPreference(ctx)
+	assert.Nil(err)
+	assert.False(p.NotifiedAt.Valid)
+	configs.AppConfig.Email.Secret = ""
+	count, err := SendNotificationEmails(ctx)
+	assert.Nil(err)
+	assert.Equal(0, count)
+	p, err = user.ReadEmailPreference(ctx)
+	assert.Nil(err)
+	assert.False(p.NotifiedAt.Valid)
+	configs.AppConfig.Email.Secret = "secret"
+	count, err = SendNotificationEmails(ctx)
+	assert.Nil(err)
+	assert.Equal(1, count)
+	_, err = other.CreateComment(ctx, "third comment", topic)
+	assert.Nil(err)
+	count, err = SendNotificationEmails(ctx)
+	assert.Nil(err)
+	assert.Equal(0, count)
+	p, err = user.ReadEmailPreference(ctx)
+	assert.Nil(err)
+	assert.True(p.NotifiedAt.Valid)
+
+	notifications, err := user.ReadNotifications(ctx, time.Time{})
+	assert.Nil(err)
+	assert.Len(notifications, 1)
+	assert.Equal("other commented", notificationText(notifications[0]))
+
+	count, err = SendDigestEmails(ctx, "month")
+	assert.NotNil(err)
+	_, err = other.UpdateEmailPreference(ctx, true, true, EmailDigestDaily)
+	assert.Nil(err)
+	count, err = SendDigestEmails(ctx, EmailDigestDaily)
+	assert.Nil(err)
+	assert.Equal(1, count)
+	count, err = SendDigestEmails(ctx, EmailDigestDaily)
+	assert.Nil(err)
+	assert.Equal(0, count)
+	count, err = SendDigestEmails(ctx, EmailDigestWeekly)
+	assert.Nil(err)
+	assert.Equal(0, count)
+
+	digested := time.Now().Add(-30 * time.Hour)
+	_, err = session.Database(ctx).Exec(ctx, "UPDATE email_preferences SET digested_at=$1 WHERE user_id=$2", digested, other.UserID)
+	assert.Nil(err)
+	count, err = SendDigestEmails(ctx, EmailDigestDaily)
+	assert.Nil(err)
+	assert.Equal(1, count)
+	p, err = other.ReadEmailPreference(ctx)
+	assert.Nil(err)
+	assert.WithinDuration(digested.Add(24*time.Hour), p.DigestedAt.Time, time.Second)
+	_, err = session.Database(ctx).Exec(ctx, "UPDATE email_preferences SET digested_at=$1 WHERE user_id=$2", time.Now().Add(-50*time.Hour), other.UserID)
+	assert.Nil(err)
+	count, err = SendDigestEmails(ctx, EmailDigestDaily)
+	assert.Nil(err)
+	assert.Equal(1, count)
+	p, err = other.ReadEmailPreference(ctx)
+	assert.Nil(err)
+	assert.WithinDuration(time.Now(), p.DigestedAt.Time, time.Minute)
+}
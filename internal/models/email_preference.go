@@ -0,0 +1,200 @@
+package models
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"satellity/internal/configs"
+	"satellity/internal/durable"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Email preferences, replies are the replies, and the comments and topics of
+// the subscriptions. The digest of popular topics is sent every day or week.
+// An unsubscribe link turns off the notification or digest emails.
+const (
+	EmailDigestNever  = "never"
+	EmailDigestDaily  = TopicPeriodDay
+	EmailDigestWeekly = TopicPeriodWeek
+
+	EmailUnsubscribeNotifications = "notifications"
+	EmailUnsubscribeDigest        = "digest"
+
+	emailTokenSplitter = ":"
+)
+
+// EmailPreference is the emails a user receives, NotifiedAt and DigestedAt are
+// the times of the last emails
+type EmailPreference struct {
+	UserID     string
+	Replies    bool
+	Mentions   bool
+	Digest     string
+	NotifiedAt sql.NullTime
+	DigestedAt sql.NullTime
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+var emailPreferenceColumns = []string{"user_id", "replies", "mentions", "digest", "notified_at", "digested_at", "created_at", "updated_at"}
+
+func (p *EmailPreference) values() []interface{} {
+	return []interface{}{p.UserID, p.Replies, p.Mentions, p.Digest, p.NotifiedAt, p.DigestedAt, p.CreatedAt, p.UpdatedAt}
+}
+
+func emailPreferenceFromRow(row durable.Row) (*EmailPreference, error) {
+	var p EmailPreference
+	err := row.Scan(&p.UserID, &p.Replies, &p.Mentions, &p.Digest, &p.NotifiedAt, &p.DigestedAt, &p.CreatedAt, &p.UpdatedAt)
+	return &p, err
+}
+
+// ReadEmailPreference read the email preference of the user, or the default one
+func (user *User) ReadEmailPreference(ctx context.Context) (*EmailPreference, error) {
+	var p *EmailPreference
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		p, err = readEmailPreference(ctx, tx, user.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return p, nil
+}
+
+// UpdateEmailPreference set the emails the user receives, digest is never, day or week
+func (user *User) UpdateEmailPreference(ctx context.Context, replies, mentions bool, digest string) (*EmailPreference, error) {
+	if digest != EmailDigestNever && digest != EmailDigestDaily && digest != EmailDigestWeekly {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "digest", "invalid", digest)
+	}
+	var p *EmailPreference
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		p, err = readEmailPreference(ctx, tx, user.UserID)
+		if err != nil {
+			return err
+		}
+		p.Replies, p.Mentions, p.Digest = replies, mentions, digest
+		return upsertEmailPreference(ctx, tx, p)
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return p, nil
+}
+
+// UnsubscribeEmail turn off the emails by the token of an unsubscribe link,
+// which needs no sign in
+func UnsubscribeEmail(ctx context.Context, token string) (*EmailPreference, error) {
+	userID, kind, err := parseEmailUnsubscribeToken(token)
+	if err != nil {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "token", "invalid", token)
+	}
+	var p *EmailPreference
+	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		user, err := findUserByID(ctx, tx, userID)
+		if err != nil {
+			return err
+		} else if user == nil {
+			return session.NotFoundError(ctx)
+		}
+		p, err = readEmailPreference(ctx, tx, user.UserID)
+		if err != nil {
+			return err
+		}
+		if kind == EmailUnsubscribeDigest {
+			p.Digest = EmailDigestNever
+		} else {
+			p.Replies, p.Mentions = false, false
+		}
+		return upsertEmailPreference(ctx, tx, p)
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return p, nil
+}
+
+func readEmailPreference(ctx context.Context, tx pgx.Tx, userID string) (*EmailPreference, error) {
+	row := tx.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM email_preferences WHERE user_id=$1", strings.Join(emailPreferenceColumns, ",")), userID)
+	p, err := emailPreferenceFromRow(row)
+	if err == pgx.ErrNoRows {
+		t := time.Now()
+		return &EmailPreference{UserID: userID, Replies: true, Mentions: true, Digest: EmailDigestNever, CreatedAt: t, UpdatedAt: t}, nil
+	}
+	return p, err
+}
+
+func upsertEmailPreference(ctx context.Context, tx pgx.Tx, p *EmailPreference) error {
+	p.UpdatedAt = time.Now()
+	cols, posits := durable.PrepareColumnsAndExpressions(emailPreferenceColumns, 0)
+	updates, params := durable.PrepareColumnsAndExpressions(emailPreferenceColumns[1:], 1)
+	query := fmt.Sprintf("INSERT INTO email_preferences (%s) VALUES (%s) ON CONFLICT (user_id) DO UPDATE SET (%s)=(%s)", cols, posits, updates, params)
+	_, err := tx.Exec(ctx, query, p.values()...)
+	return err
+}
+
+// emailUnsubscribeURL is the link to turn off the kind of emails of the user
+func emailUnsubscribeURL(userID, kind string) (string, error) {
+	token, err := emailUnsubscribeToken(userID, kind)
+	if err != nil {
+		return "", err
+	}
+	return configs.AppConfig.Email.URL + "/unsubscribe?token=" + url.QueryEscape(token), nil
+}
+
+// emailUnsubscribeToken is the user and the kind of emails, signed by the email secret
+func emailUnsubscribeToken(userID, kind string) (string, error) {
+	payload := userID + emailTokenSplitter + kind
+	signature, err := signEmailToken(payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func parseEmailUnsubscribeToken(token string) (string, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid token %s", token)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", "", err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", "", err
+	}
+	expected, err := signEmailToken(string(payload))
+	if err != nil {
+		return "", "", err
+	}
+	if !hmac.Equal(signature, expected) {
+		return "", "", fmt.Errorf("invalid signature %s", token)
+	}
+	fields := strings.SplitN(string(payload), emailTokenSplitter, 2)
+	if len(fields) != 2 || (fields[1] != EmailUnsubscribeNotifications && fields[1] != EmailUnsubscribeDigest) {
+		return "", "", fmt.Errorf("invalid payload %s", token)
+	}
+	return fields[0], fields[1], nil
+}
+
+// signEmailToken refuse a blank secret, anyone could forge the tokens with it
+func signEmailToken(payload string) ([]byte, error) {
+	secret := configs.AppConfig.Email.Secret
+	if secret == "" {
+		return nil, fmt.Errorf("blank email secret")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil), nil
+}
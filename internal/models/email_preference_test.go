@@ -0,0 +1,65 @@
+package models
+
+import (
+	"satellity/internal/configs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailPreferenceCRUD(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+
+	p, err := user.ReadEmailPreference(ctx)
+	assert.Nil(err)
+	assert.True(p.Replies)
+	assert.True(p.Mentions)
+	assert.Equal(EmailDigestNever, p.Digest)
+	p, err = user.UpdateEmailPreference(ctx, true, false, "month")
+	assert.NotNil(err)
+	assert.Nil(p)
+	p, err = user.UpdateEmailPreference(ctx, true, false, EmailDigestDaily)
+	assert.Nil(err)
+	assert.False(p.Mentions)
+	p, err = user.ReadEmailPreference(ctx)
+	assert.Nil(err)
+	assert.True(p.Replies)
+	assert.False(p.Mentions)
+	assert.Equal(EmailDigestDaily, p.Digest)
+
+	configs.AppConfig.Email.Secret = ""
+	_, err = emailUnsubscribeToken(user.UserID, EmailUnsubscribeDigest)
+	assert.NotNil(err)
+	configs.AppConfig.Email.Secret = "secret"
+	token, err := emailUnsubscribeToken(user.UserID, EmailUnsubscribeDigest)
+	assert.Nil(err)
+	id, kind, err := parseEmailUnsubscribeToken(token)
+	assert.Nil(err)
+	assert.Equal(user.UserID, id)
+	assert.Equal(EmailUnsubscribeDigest, kind)
+	forged, err := emailUnsubscribeToken(user.UserID, EmailUnsubscribeNotifications)
+	assert.Nil(err)
+	_, _, err = parseEmailUnsubscribeToken(strings.Split(forged, ".")[0] + "." + strings.Split(token, ".")[1])
+	assert.NotNil(err)
+	_, err = UnsubscribeEmail(ctx, "invalid.token")
+	assert.NotNil(err)
+
+	configs.AppConfig.Email.Secret = ""
+	_, err = UnsubscribeEmail(ctx, token)
+	assert.NotNil(err)
+	configs.AppConfig.Email.Secret = "secret"
+	p, err = UnsubscribeEmail(ctx, token)
+	assert.Nil(err)
+	assert.True(p.Replies)
+	assert.Equal(EmailDigestNever, p.Digest)
+	p, err = UnsubscribeEmail(ctx, forged)
+	assert.Nil(err)
+	assert.False(p.Replies)
+	assert.False(p.Mentions)
+}
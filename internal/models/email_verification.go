@@ -16,49 +16,76 @@ import (
 	"github.com/jackc/pgx/v4"
 )
 
+// A verification expires after emailVerificationLifetime or emailVerificationAttemptsLimit wrong codes
+const (
+	emailVerificationLifetime      = 24 * time.Hour
+	emailVerificationAttemptsLimit = 5
+)
+
+// Email verification purposes
+const (
+	EmailVerificationPurposeUser     = "USER"
+	EmailVerificationPurposePassword = "PASSWORD"
+	EmailVerificationPurposeEmail    = "EMAIL"
+	EmailVerificationPurposeLink     = "LINK"
+)
+
 // EmailVerification verify email
 type EmailVerification struct {
 	VerificationID string
 	Email          string
 	Code           string
+	Purpose        string
+	UserID         sql.NullString
+	Attempts       int
 	CreatedAt      time.Time
 }
 
-var emailVerificationColumns = []string{"verification_id", "email", "code", "created_at"}
+var emailVerificationColumns = []string{"verification_id", "email", "code", "purpose", "user_id", "attempts", "created_at"}
 
 func (e *EmailVerification) values() []interface{} {
-	return []interface{}{e.VerificationID, e.Email, e.Code, e.CreatedAt}
+	return []interface{}{e.VerificationID, e.Email, e.Code, e.Purpose, e.UserID, e.Attempts, e.CreatedAt}
 }
 
 func emailVerificationFromRows(row durable.Row) (*EmailVerification, error) {
 	var ev EmailVerification
-	err := row.Scan(&ev.VerificationID, &ev.Email, &ev.Code, &ev.CreatedAt)
+	err := row.Scan(&ev.VerificationID, &ev.Email, &ev.Code, &ev.Purpose, &ev.UserID, &ev.Attempts, &ev.CreatedAt)
 	return &ev, err
 }
 
 // CreateEmailVerification create an email verification
 func CreateEmailVerification(ctx context.Context, purpose, email, recaptcha string) (*EmailVerification, error) {
-	code, err := generateVerificationCode(ctx)
-	if err != nil {
-		return nil, err
+	switch purpose {
+	case EmailVerificationPurposeUser, EmailVerificationPurposePassword:
+	default:
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "purpose", "invalid", purpose)
 	}
-
 	success, err := clouds.VerifyRecaptcha(ctx, recaptcha)
 	if err != nil {
 		return nil, session.ServerError(ctx, err)
 	} else if !success {
 		return nil, session.RecaptchaVerifyError(ctx)
 	}
+	return createEmailVerification(ctx, purpose, email, "")
+}
+
+func createEmailVerification(ctx context.Context, purpose, email, userID string) (*EmailVerification, error) {
+	code, err := generateVerificationCode(ctx)
+	if err != nil {
+		return nil, err
+	}
 	ev := &EmailVerification{
 		VerificationID: uuid.Must(uuid.NewV4()).String(),
 		Email:          strings.TrimSpace(email),
 		Code:           code,
+		Purpose:        purpose,
+		UserID:         sql.NullString{String: userID, Valid: userID != ""},
 		CreatedAt:      time.Now(),
 	}
 
 	var should bool
 	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
-		_, err = tx.Exec(ctx, "DELETE FROM email_verifications WHERE created_at<$1", time.Now().Add(-24*time.Hour))
+		_, err = tx.Exec(ctx, "DELETE FROM email_verifications WHERE created_at<$1", time.Now().Add(-emailVerificationLifetime))
 		if err != nil {
 			return err
 		}
@@ -86,21 +113,22 @@ func CreateEmailVerification(ctx context.Context, purpose, email, recaptcha stri
 	return ev, nil
 }
 
-// VerifyEmailVerification verify an email verification
-func VerifyEmailVerification(ctx context.Context, verificationID, code, username, password, sessionPub string) (*User, error) {
+// VerifyEmailVerification verify an email verification, it signs in the user
+// if the email has an account already, twoFactorCode is required if the user
+// enabled two factor
+func VerifyEmailVerification(ctx context.Context, verificationID, code, username, password, twoFactorCode, sessionPub string) (*User, error) {
+	keys := ipAttemptKeys(ctx)
+	if err := checkLoginAttempts(ctx, keys...); err != nil {
+		return nil, err
+	}
 	var user *User
+	var failed bool
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
-		ev, err := findEmailVerification(ctx, tx, verificationID)
+		ev, err := matchEmailVerification(ctx, tx, verificationID, code)
 		if err != nil || ev == nil {
 			return err
 		}
-		if ev.Code != code {
-			ev, err = findEmailVerificationByEmailAndCode(ctx, tx, ev.Email, code)
-			if err != nil || ev == nil {
-				return err
-			}
-		}
-		if ev.CreatedAt.Add(time.Hour * 24).Before(time.Now()) {
+		if ev.Purpose != EmailVerificationPurposeUser {
 			return session.VerificationCodeInvalidError(ctx)
 		}
 		_, err = tx.Exec(ctx, "DELETE FROM email_verifications WHERE verification_id=$1", ev.VerificationID)
@@ -117,6 +145,10 @@ func VerifyEmailVerification(ctx context.Context, verificationID, code, username
 			return err
 		}
 		if user != nil {
+			if err := verifyTwoFactor(ctx, tx, user, twoFactorCode); err != nil {
+				failed = twoFactorCode != ""
+				return err
+			}
 			s, err := user.addSession(ctx, tx, sessionPub)
 			if err != nil {
 				return err
@@ -125,9 +157,14 @@ func VerifyEmailVerification(ctx context.Context, verificationID, code, username
 			return nil
 		}
 
-		user, err = createUser(ctx, tx, "", ev.Email, username, username, password, sessionPub, "", nil)
+		user, err = createUser(ctx, tx, "", ev.Email, username, username, password, sessionPub, nil)
 		return err
 	})
+	if failed {
+		if err := failLoginAttempt(ctx, append(keys, userAttemptKey(user.UserID))...); err != nil {
+			return nil, err
+		}
+	}
 	if err != nil {
 		return nil, session.TransactionError(ctx, err)
 	} else if user == nil {
@@ -136,20 +173,18 @@ func VerifyEmailVerification(ctx context.Context, verificationID, code, username
 	return user, nil
 }
 
+// Reset set the password of the user by a verification of the password purpose
 func Reset(ctx context.Context, verificationID, code, password string) error {
+	if err := checkLoginAttempts(ctx, ipAttemptKeys(ctx)...); err != nil {
+		return err
+	}
 	var user *User
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
-		ev, err := findEmailVerification(ctx, tx, verificationID)
+		ev, err := matchEmailVerification(ctx, tx, verificationID, code)
 		if err != nil || ev == nil {
 			return err
 		}
-		if ev.Code != code {
-			ev, err = findEmailVerificationByEmailAndCode(ctx, tx, ev.Email, code)
-			if err != nil || ev == nil {
-				return err
-			}
-		}
-		if ev.CreatedAt.Add(time.Hour * 24).Before(time.Now()) {
+		if ev.Purpose != EmailVerificationPurposePassword {
 			return session.VerificationCodeInvalidError(ctx)
 		}
 		_, err = tx.Exec(ctx, "DELETE FROM email_verifications WHERE verification_id=$1", ev.VerificationID)
@@ -164,7 +199,7 @@ func Reset(ctx context.Context, verificationID, code, password string) error {
 		if err != nil || user == nil {
 			return err
 		}
-		_, err = tx.Exec(ctx, "UPDATE users SET (encrypted_password, updated_at)=($2, $3) WHERE user_id=$1", user.UserID, sql.NullString{String: encryptedPassword, Valid: true}, time.Now())
+		_, err = tx.Exec(ctx, "UPDATE users SET (encrypted_password, password_reset_required, updated_at)=($2, false, $3) WHERE user_id=$1", user.UserID, sql.NullString{String: encryptedPassword, Valid: true}, time.Now())
 		return err
 	})
 	if err != nil {
@@ -172,10 +207,10 @@ func Reset(ctx context.Context, verificationID, code, password string) error {
 	} else if user == nil {
 		return session.VerificationCodeInvalidError(ctx)
 	}
-	return nil
+	return clearLoginAttempts(ctx, userAttemptKey(user.UserID))
 }
 
-func createUser(ctx context.Context, tx pgx.Tx, publicKey, email, username, nickname, password, sessionPub, githubID string, user *User) (*User, error) {
+func createUser(ctx context.Context, tx pgx.Tx, publicKey, email, username, nickname, password, sessionPub string, user *User) (*User, error) {
 	if user == nil {
 		t := time.Now()
 		user = &User{
@@ -198,9 +233,6 @@ func createUser(ctx context.Context, tx pgx.Tx, publicKey, email, username, nick
 		if password != "" {
 			user.EncryptedPassword = sql.NullString{String: password, Valid: true}
 		}
-		if githubID != "" {
-			user.GithubID = sql.NullString{String: githubID, Valid: true}
-		}
 
 		rows := [][]interface{}{user.values()}
 		_, err := tx.CopyFrom(ctx, pgx.Identifier{"users"}, userColumns, pgx.CopyFromRows(rows))
@@ -216,19 +248,29 @@ func createUser(ctx context.Context, tx pgx.Tx, publicKey, email, username, nick
 	return user, nil
 }
 
-func findEmailVerification(ctx context.Context, tx pgx.Tx, id string) (*EmailVerification, error) {
-	row := tx.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM email_verifications WHERE verification_id=$1", strings.Join(emailVerificationColumns, ",")), id)
-	ev, err := emailVerificationFromRows(row)
-	if err == pgx.ErrNoRows {
+// matchEmailVerification return nil if the code doesn't match the verification,
+// or it's expired or out of attempts. Wrong codes are counted on the verification
+// and the client ip outside of tx, so they survive a rollback.
+func matchEmailVerification(ctx context.Context, tx pgx.Tx, id, code string) (*EmailVerification, error) {
+	ev, err := findEmailVerification(ctx, tx, id)
+	if err != nil || ev == nil {
+		return nil, err
+	}
+	if ev.Attempts >= emailVerificationAttemptsLimit || ev.CreatedAt.Add(emailVerificationLifetime).Before(time.Now()) {
 		return nil, nil
-	} else if err != nil {
+	}
+	if ev.Code == code {
+		return ev, nil
+	}
+	_, err = session.Database(ctx).Exec(ctx, "UPDATE email_verifications SET attempts=attempts+1 WHERE verification_id=$1", ev.VerificationID)
+	if err != nil {
 		return nil, err
 	}
-	return ev, nil
+	return nil, failLoginAttempt(ctx, ipAttemptKeys(ctx)...)
 }
 
-func lastEmailVerification(ctx context.Context, tx pgx.Tx) (*EmailVerification, error) {
-	row := tx.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM email_verifications ORDER BY created_at DESC LIMIT 1", strings.Join(emailVerificationColumns, ",")))
+func findEmailVerification(ctx context.Context, tx pgx.Tx, id string) (*EmailVerification, error) {
+	row := tx.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM email_verifications WHERE verification_id=$1", strings.Join(emailVerificationColumns, ",")), id)
 	ev, err := emailVerificationFromRows(row)
 	if err == pgx.ErrNoRows {
 		return nil, nil
@@ -238,8 +280,8 @@ func lastEmailVerification(ctx context.Context, tx pgx.Tx) (*EmailVerification,
 	return ev, nil
 }
 
-func findEmailVerificationByEmailAndCode(ctx context.Context, tx pgx.Tx, email, code string) (*EmailVerification, error) {
-	row := tx.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM email_verifications WHERE email=$1 AND code=$2 ORDER BY email,code,created_at DESC LIMIT 1", strings.Join(emailVerificationColumns, ",")), email, code)
+func lastEmailVerification(ctx context.Context, tx pgx.Tx) (*EmailVerification, error) {
+	row := tx.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM email_verifications ORDER BY created_at DESC LIMIT 1", strings.Join(emailVerificationColumns, ",")))
 	ev, err := emailVerificationFromRows(row)
 	if err == pgx.ErrNoRows {
 		return nil, nil
@@ -255,9 +297,6 @@ func generateVerificationCode(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", session.ServerError(ctx, err)
 	}
-	c := binary.LittleEndian.Uint64(b[:]) % 10000
-	if c < 1000 {
-		c = 1000 + c
-	}
+	c := binary.LittleEndian.Uint64(b[:])%900000 + 100000
 	return fmt.Sprint(c), nil
 }
@@ -18,9 +18,11 @@ func TestEmailVerification(t *testing.T) {
 	assert.Nil(err)
 	assert.NotNil(ev)
 
+	err = Reset(ctx, ev.VerificationID, ev.Code, "newpassword")
+	assert.NotNil(err)
 	public, _, err := ed25519.GenerateKey(rand.Reader)
 	assert.Nil(err)
-	user, err := VerifyEmailVerification(ctx, ev.VerificationID, ev.Code, "jason", "nopassword", hex.EncodeToString(public))
+	user, err := VerifyEmailVerification(ctx, ev.VerificationID, ev.Code, "jason", "nopassword", "", hex.EncodeToString(public))
 	assert.Nil(err)
 	assert.NotNil(user)
 	user, err = ReadUser(ctx, user.UserID)
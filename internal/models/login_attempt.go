@@ -0,0 +1,99 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Failed attempts are counted per user and per ip, the key is locked after the
+// limit is reached, and the lock doubles with each further failure.
+const (
+	loginAttemptUserLimit = 5
+	loginAttemptIPLimit   = 20
+	loginAttemptLockBase  = time.Minute
+	loginAttemptLockMax   = 24 * time.Hour
+	loginAttemptWindow    = 24 * time.Hour
+)
+
+func userAttemptKey(userID string) string {
+	return "user:" + userID
+}
+
+// ipAttemptKeys is empty if the client ip is unknown
+func ipAttemptKeys(ctx context.Context) []string {
+	if addr := session.RemoteAddr(ctx); addr != "" {
+		return []string{"ip:" + addr}
+	}
+	return nil
+}
+
+func loginAttemptLimit(key string) int {
+	if strings.HasPrefix(key, "ip:") {
+		return loginAttemptIPLimit
+	}
+	return loginAttemptUserLimit
+}
+
+// checkLoginAttempts return TooManyAttemptsError if any of the keys is locked
+func checkLoginAttempts(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	var until sql.NullTime
+	err := session.Database(ctx).QueryRow(ctx, "SELECT max(locked_until) FROM login_attempts WHERE attempt_key=ANY($1) AND locked_until>$2", keys, time.Now()).Scan(&until)
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	if until.Valid {
+		return session.TooManyAttemptsError(ctx, until.Time)
+	}
+	return nil
+}
+
+// failLoginAttempt count a failure for each key, it runs outside the caller's
+// transaction so the failure is kept even if the transaction rolls back.
+func failLoginAttempt(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	t := time.Now()
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		for _, key := range keys {
+			var failures int
+			err := tx.QueryRow(ctx, "INSERT INTO login_attempts (attempt_key,failures,updated_at) VALUES ($1,1,$2) ON CONFLICT (attempt_key) DO UPDATE SET (failures,updated_at)=(CASE WHEN login_attempts.updated_at<$3 THEN 1 ELSE login_attempts.failures+1 END,$2) RETURNING failures", key, t, t.Add(-loginAttemptWindow)).Scan(&failures)
+			if err != nil {
+				return err
+			}
+			limit := loginAttemptLimit(key)
+			if failures < limit {
+				continue
+			}
+			lock := loginAttemptLockMax
+			if n := failures - limit; n < 16 && loginAttemptLockBase<<n < loginAttemptLockMax {
+				lock = loginAttemptLockBase << n
+			}
+			_, err = tx.Exec(ctx, "UPDATE login_attempts SET locked_until=$1 WHERE attempt_key=$2", t.Add(lock), key)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+func clearLoginAttempts(ctx context.Context, keys ...string) error {
+	_, err := session.Database(ctx).Exec(ctx, "DELETE FROM login_attempts WHERE attempt_key=ANY($1) OR updated_at<$2", keys, time.Now().Add(-loginAttemptWindow))
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"satellity/internal/session"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginAttempt(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+	ctx = session.WithRemoteAddr(ctx, "127.0.0.1")
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	public, _, _ := ed25519.GenerateKey(rand.Reader)
+	for i := 0; i < loginAttemptUserLimit-1; i++ {
+		_, err := CreateSession(ctx, "username", "wrong password", "", hex.EncodeToString(public))
+		assert.Equal(10012, err.(session.Error).Code)
+	}
+	existing, err := CreateSession(ctx, "username", "password", "", hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.NotNil(existing)
+	for i := 0; i < loginAttemptUserLimit; i++ {
+		_, err := CreateSession(ctx, "im.yuqlee@gmail.com", "wrong password", "", hex.EncodeToString(public))
+		assert.Equal(10012, err.(session.Error).Code)
+	}
+	_, err = CreateSession(ctx, "username", "password", "", hex.EncodeToString(public))
+	assert.NotNil(err)
+	assert.Equal(10018, err.(session.Error).Code)
+
+	ev, err := CreateEmailVerification(ctx, EmailVerificationPurposeUser, "other@gmail.com", "testrecaptcha")
+	assert.Nil(err)
+	assert.Len(ev.Code, 6)
+	for i := 0; i < emailVerificationAttemptsLimit; i++ {
+		_, err := VerifyEmailVerification(ctx, ev.VerificationID, "000000", "other", "password", "", hex.EncodeToString(public))
+		assert.Equal(10020, err.(session.Error).Code)
+	}
+	_, err = VerifyEmailVerification(ctx, ev.VerificationID, ev.Code, "other", "password", "", hex.EncodeToString(public))
+	assert.NotNil(err)
+	assert.Equal(10020, err.(session.Error).Code)
+}
@@ -0,0 +1,221 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"satellity/internal/durable"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// A post resolves at most mentionsLimit distinct usernames, the rest are kept
+// as plain text and notify nobody
+const mentionsLimit = 10
+
+// @username is a mention unless it follows a word, e.g. the host of an email
+var mentionRegexp = regexp.MustCompile(`(?:^|[^a-zA-Z0-9_@.])@([a-zA-Z0-9][a-zA-Z0-9_]{2,63})\b`)
+
+// Mention is a user mentioned in the body of a topic, or a comment if CommentID is valid
+type Mention struct {
+	MentionID string
+	TopicID   string
+	CommentID sql.NullString
+	UserID    string
+	CreatedAt time.Time
+
+	User *User
+}
+
+var mentionColumns = []string{"mention_id", "topic_id", "comment_id", "user_id", "created_at"}
+
+func mentionFromRows(row durable.Row) (*Mention, error) {
+	var m Mention
+	err := row.Scan(&m.MentionID, &m.TopicID, &m.CommentID, &m.UserID, &m.CreatedAt)
+	return &m, err
+}
+
+// parseMentions return the distinct lower case usernames mentioned in the body
+func parseMentions(body string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, match := range mentionRegexp.FindAllStringSubmatch(body, -1) {
+		username := strings.ToLower(match[1])
+		if seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+		if len(usernames) == mentionsLimit {
+			break
+		}
+	}
+	return usernames
+}
+
+// syncMentions resolve the mentions in the body of the topic, or the comment
+// if commentID isn't blank, and replace the stored ones. A removed mention is
+// kept as removed, so a user is notified only the first time mentioned in a
+// post however it's edited. It returns all the mentions and the users newly
+// mentioned.
+func syncMentions(ctx context.Context, tx pgx.Tx, topicID, commentID, body string) ([]*Mention, []*User, error) {
+	condition, source := "topic_id=$1 AND comment_id IS NULL", topicID
+	if commentID != "" {
+		condition, source = "comment_id=$1", commentID
+	}
+	existing, err := queryIDs(ctx, tx, fmt.Sprintf("SELECT user_id FROM mentions WHERE %s", condition), source)
+	if err != nil {
+		return nil, nil, err
+	}
+	var users []*User
+	if usernames := parseMentions(body); len(usernames) > 0 {
+		rows, err := tx.Query(ctx, fmt.Sprintf("SELECT %s FROM users WHERE LOWER(username)=ANY($1)", strings.Join(userColumns, ",")), usernames)
+		if err != nil {
+			return nil, nil, err
+		}
+		for rows.Next() {
+			u, err := userFromRow(rows)
+			if err != nil {
+				rows.Close()
+				return nil, nil, err
+			}
+			users = append(users, u)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, id := range existing {
+		known[id] = true
+	}
+	ids := make([]string, 0, len(users))
+	var mentions []*Mention
+	var added []*User
+	t := time.Now()
+	for _, u := range users {
+		ids = append(ids, u.UserID)
+		m := &Mention{
+			MentionID: uuid.Must(uuid.NewV4()).String(),
+			TopicID:   topicID,
+			CommentID: nullID(commentID),
+			UserID:    u.UserID,
+			CreatedAt: t,
+			User:      u,
+		}
+		mentions = append(mentions, m)
+		if known[u.UserID] {
+			continue
+		}
+		added = append(added, u)
+		_, err := tx.Exec(ctx, "INSERT INTO mentions (mention_id,topic_id,comment_id,user_id,created_at) VALUES ($1,$2,$3,$4,$5) ON CONFLICT DO NOTHING", m.MentionID, m.TopicID, m.CommentID, m.UserID, m.CreatedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE mentions SET removed_at=NULL WHERE %s AND removed_at IS NOT NULL AND user_id=ANY($2)", condition), source, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE mentions SET removed_at=$3 WHERE %s AND removed_at IS NULL AND NOT (user_id=ANY($2))", condition), source, ids, t)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mentions, added, nil
+}
+
+// notifyMentions notify the users newly mentioned by the actor
+func notifyMentions(ctx context.Context, users []*User, actor *User, topicID, commentID string) {
+	subjectID := topicID
+	if commentID != "" {
+		subjectID = commentID
+	}
+	for _, u := range users {
+		notify(ctx, NotificationTypeMentioned, u.UserID, actor, topicID, commentID, subjectID)
+	}
+}
+
+// fillTopicMentions set the mentions in the bodies of the topics
+func fillTopicMentions(ctx context.Context, tx pgx.Tx, topics []*Topic) error {
+	if len(topics) == 0 {
+		return nil
+	}
+	ids := make([]string, len(topics))
+	for i, t := range topics {
+		ids[i] = t.TopicID
+	}
+	set, err := readMentionSet(ctx, tx, "topic_id=ANY($1) AND comment_id IS NULL", ids)
+	if err != nil {
+		return err
+	}
+	for _, t := range topics {
+		t.Mentions = set[t.TopicID]
+	}
+	return nil
+}
+
+// fillCommentMentions set the mentions in the bodies of the comments
+func fillCommentMentions(ctx context.Context, tx pgx.Tx, comments []*Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	ids := make([]string, len(comments))
+	for i, c := range comments {
+		ids[i] = c.CommentID
+	}
+	set, err := readMentionSet(ctx, tx, "comment_id=ANY($1)", ids)
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		c.Mentions = set[c.CommentID]
+	}
+	return nil
+}
+
+// readMentionSet read the mentions not removed with their users, by the
+// comment_id or the topic_id of topic mentions
+func readMentionSet(ctx context.Context, tx pgx.Tx, condition string, ids []string) (map[string][]*Mention, error) {
+	rows, err := tx.Query(ctx, fmt.Sprintf("SELECT %s FROM mentions WHERE %s AND removed_at IS NULL ORDER BY created_at", strings.Join(mentionColumns, ","), condition), ids)
+	if err != nil {
+		return nil, err
+	}
+	var mentions []*Mention
+	var userIDs []string
+	for rows.Next() {
+		m, err := mentionFromRows(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		mentions = append(mentions, m)
+		userIDs = append(userIDs, m.UserID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	users, err := readUserSet(ctx, tx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string][]*Mention)
+	for _, m := range mentions {
+		m.User = users[m.UserID]
+		if m.User == nil {
+			continue
+		}
+		key := m.TopicID
+		if m.CommentID.Valid {
+			key = m.CommentID.String
+		}
+		set[key] = append(set[key], m)
+	}
+	return set, nil
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMentions(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"jason", "other_user"}, parseMentions("@Jason hi, cc @other_user and @jason."))
+	assert.Nil(parseMentions("mail me at name@example.com, or @ab"))
+	body := ""
+	for i := 0; i < mentionsLimit+5; i++ {
+		body += " @user" + string(rune('a'+i))
+	}
+	assert.Len(parseMentions(body), mentionsLimit)
+}
+
+func TestMentionCRUD(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	other := createTestUser(ctx, "other@gmail.com", "Other", "password")
+	assert.NotNil(other)
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+
+	topic, err := user.CreateTopic(ctx, "title", "hello @OTHER and @nobody", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	assert.Len(topic.Mentions, 1)
+	assert.Equal(other.UserID, topic.Mentions[0].UserID)
+	topic, err = ReadTopicFull(ctx, topic.TopicID, nil)
+	assert.Nil(err)
+	assert.Len(topic.Mentions, 1)
+	notifications, err := other.ReadNotifications(ctx, time.Time{})
+	assert.Nil(err)
+	assert.Len(notifications, 1)
+	assert.Equal(NotificationTypeMentioned, notifications[0].NotificationType)
+
+	comment, err := other.CreateComment(ctx, "thanks @username, and @other", topic)
+	assert.Nil(err)
+	assert.Len(comment.Mentions, 2)
+	assert.Nil(comment.Update(ctx, "thanks @username, again", other))
+	assert.Len(comment.Mentions, 1)
+	comments, err := ReadComments(ctx, time.Time{}, topic, nil)
+	assert.Nil(err)
+	assert.Len(comments, 1)
+	assert.Len(comments[0].Mentions, 1)
+	assert.Equal(user.UserID, comments[0].Mentions[0].UserID)
+	notifications, err = user.ReadNotifications(ctx, time.Time{})
+	assert.Nil(err)
+	assert.Len(notifications, 1)
+	assert.Equal(NotificationTypeMentioned, notifications[0].NotificationType)
+	assert.Equal(comment.CommentID, notifications[0].CommentID.String)
+
+	_, err = user.UpdateTopic(ctx, topic.TopicID, "title", "hello again", TopicTypePost, "", false)
+	assert.Nil(err)
+	topic, err = ReadTopicFull(ctx, topic.TopicID, nil)
+	assert.Nil(err)
+	assert.Len(topic.Mentions, 0)
+	assert.Nil(other.ReadAllNotifications(ctx))
+	topic, err = user.UpdateTopic(ctx, topic.TopicID, "title", "hello @other, again", TopicTypePost, "", false)
+	assert.Nil(err)
+	assert.Len(topic.Mentions, 1)
+	assert.Nil(other.CountUnreadNotifications(ctx))
+	assert.Equal(int64(0), other.UnreadNotificationsCount)
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"satellity/internal/durable"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// Notification types, the unread events of the same type on the same topic, or
+// the same comment for replies, are grouped, e.g. 5 people liked your topic
+const (
+	NotificationTypeCreated   = "created"
+	NotificationTypeCommented = "commented"
+	NotificationTypeReplied   = "replied"
+	NotificationTypeLiked     = "liked"
+	NotificationTypeMentioned = "mentioned"
+
+	notificationActorsLimit = 3
+)
+
+// Notification tells the user about the events on the topics and comments
+type Notification struct {
+	NotificationID   string
+	UserID           string
+	NotificationType string
+	TopicID          sql.NullString
+	CommentID        sql.NullString
+	GroupKey         string
+	ActorIDs         []string
+	ReadAt           sql.NullTime
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+
+	Actors []*User
+	Topic  *Topic
+}
+
+var notificationColumns = []string{"notification_id", "user_id", "notification_type", "topic_id", "comment_id", "group_key", "actor_ids", "read_at", "created_at", "updated_at"}
+
+func notificationFromRows(row durable.Row) (*Notification, error) {
+	var n Notification
+	err := row.Scan(&n.NotificationID, &n.UserID, &n.NotificationType, &n.TopicID, &n.CommentID, &n.GroupKey, &n.ActorIDs, &n.ReadAt, &n.CreatedAt, &n.UpdatedAt)
+	return &n, err
+}
+
+// IsRead is true if the user has read the notification
+func (n *Notification) IsRead() bool {
+	return n.ReadAt.Valid
+}
+
+// notify the recipient of an event by the actor on the subject, the events of
+// the same type and subject are merged into one unread notification. Nobody is
+// notified on a topic muted. It runs after the event is committed, and a
+// failure is only logged.
+func notify(ctx context.Context, typ, recipientID string, actor *User, topicID, commentID, subjectID string) {
+	if actor == nil || recipientID == "" || recipientID == actor.UserID || subjectID == "" {
+		return
+	}
+	t := time.Now()
+	query := "INSERT INTO notifications (notification_id,user_id,notification_type,topic_id,comment_id,group_key,actor_ids,created_at,updated_at) SELECT $1::varchar,$2::varchar,$3::varchar,$4::varchar,$5::varchar,$6::varchar,ARRAY[$7::varchar],$8::timestamptz,$8::timestamptz WHERE $4::varchar IS NULL OR topic_subscription($4,$2)<>'muted' ON CONFLICT (user_id,group_key) WHERE read_at IS NULL DO UPDATE SET (actor_ids,comment_id,updated_at)=(array_prepend($7::varchar,array_remove(notifications.actor_ids,$7::varchar)),EXCLUDED.comment_id,EXCLUDED.updated_at)"
+	_, err := session.Database(ctx).Exec(ctx, query, uuid.Must(uuid.NewV4()).String(), recipientID, typ, nullID(topicID), nullID(commentID), typ+":"+subjectID, actor.UserID, t)
+	if err != nil {
+		session.TransactionError(ctx, err)
+	}
+}
+
+// ReadNotifications read the notifications of the user, the latest first,
+// parameters: offset default time.Now()
+func (user *User) ReadNotifications(ctx context.Context, offset time.Time) ([]*Notification, error) {
+	if offset.IsZero() {
+		offset = time.Now()
+	}
+	var notifications []*Notification
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		notifications, err = queryNotifications(ctx, tx, fmt.Sprintf("SELECT %s FROM notifications WHERE user_id=$1 AND updated_at<$2 ORDER BY user_id,updated_at DESC LIMIT $3", strings.Join(notificationColumns, ",")), user.UserID, offset, LIMIT)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return notifications, nil
+}
+
+// queryNotifications read the notifications with their latest actors and topics
+func queryNotifications(ctx context.Context, tx pgx.Tx, query string, params ...any) ([]*Notification, error) {
+	rows, err := tx.Query(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	var notifications []*Notification
+	var actorIDs, topicIDs []string
+	for rows.Next() {
+		n, err := notificationFromRows(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		for i, id := range n.ActorIDs {
+			if i < notificationActorsLimit {
+				actorIDs = append(actorIDs, id)
+			}
+		}
+		if n.TopicID.Valid {
+			topicIDs = append(topicIDs, n.TopicID.String)
+		}
+		notifications = append(notifications, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	users, err := readUserSet(ctx, tx, actorIDs)
+	if err != nil {
+		return nil, err
+	}
+	topics, err := queryTopics(ctx, tx, nil, nil, fmt.Sprintf("SELECT %s FROM topics WHERE topic_id=ANY($1)", strings.Join(topicColumns, ",")), topicIDs)
+	if err != nil {
+		return nil, err
+	}
+	topicSet := make(map[string]*Topic)
+	for _, t := range topics {
+		topicSet[t.TopicID] = t
+	}
+	for _, n := range notifications {
+		for i, id := range n.ActorIDs {
+			if u := users[id]; u != nil && i < notificationActorsLimit {
+				n.Actors = append(n.Actors, u)
+			}
+		}
+		n.Topic = topicSet[n.TopicID.String]
+	}
+	return notifications, nil
+}
+
+// CountUnreadNotifications set the count of the unread notifications of the user
+func (user *User) CountUnreadNotifications(ctx context.Context) error {
+	err := session.Database(ctx).QueryRow(ctx, "SELECT count(*) FROM notifications WHERE user_id=$1 AND read_at IS NULL", user.UserID).Scan(&user.UnreadNotificationsCount)
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// ReadNotification mark a notification of the user as read
+func (user *User) ReadNotification(ctx context.Context, id string) error {
+	if uuid.FromStringOrNil(id).String() != id {
+		return session.NotFoundError(ctx)
+	}
+	tag, err := session.Database(ctx).Exec(ctx, "UPDATE notifications SET read_at=COALESCE(read_at,$1) WHERE notification_id=$2 AND user_id=$3", time.Now(), id, user.UserID)
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return session.NotFoundError(ctx)
+	}
+	return nil
+}
+
+// ReadAllNotifications mark all notifications of the user as read
+func (user *User) ReadAllNotifications(ctx context.Context) error {
+	_, err := session.Database(ctx).Exec(ctx, "UPDATE notifications SET read_at=$1 WHERE user_id=$2 AND read_at IS NULL", time.Now(), user.UserID)
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+func nullID(id string) sql.NullString {
+	return sql.NullString{String: id, Valid: id != ""}
+}
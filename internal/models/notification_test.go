@@ -0,0 +1,79 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationCRUD(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	first := createTestUser(ctx, "first@gmail.com", "first", "password")
+	assert.NotNil(first)
+	second := createTestUser(ctx, "second@gmail.com", "second", "password")
+	assert.NotNil(second)
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+	topic, err := user.CreateTopic(ctx, "title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+
+	_, err = topic.ActiondBy(ctx, user, TopicUserActionLiked, true)
+	assert.Nil(err)
+	_, err = topic.ActiondBy(ctx, first, TopicUserActionLiked, true)
+	assert.Nil(err)
+	_, err = topic.ActiondBy(ctx, second, TopicUserActionLiked, true)
+	assert.Nil(err)
+	_, err = topic.ActiondBy(ctx, first, TopicUserActionBookmarked, true)
+	assert.Nil(err)
+	notifications, err := user.ReadNotifications(ctx, time.Time{})
+	assert.Nil(err)
+	assert.Len(notifications, 1)
+	assert.Equal(NotificationTypeLiked, notifications[0].NotificationType)
+	assert.Len(notifications[0].Actors, 2)
+	assert.Equal(second.UserID, notifications[0].Actors[0].UserID)
+	assert.NotNil(notifications[0].Topic)
+
+	comment, err := first.CreateComment(ctx, "comment body", topic)
+	assert.Nil(err)
+	_, err = user.CreateReply(ctx, "reply body", topic, comment.CommentID)
+	assert.Nil(err)
+	assert.Nil(user.CountUnreadNotifications(ctx))
+	assert.Equal(int64(2), user.UnreadNotificationsCount)
+	notifications, err = first.ReadNotifications(ctx, time.Time{})
+	assert.Nil(err)
+	assert.Len(notifications, 1)
+	assert.Equal(NotificationTypeReplied, notifications[0].NotificationType)
+
+	notifications, err = user.ReadNotifications(ctx, time.Time{})
+	assert.Nil(err)
+	assert.Len(notifications, 2)
+	assert.Equal(NotificationTypeCommented, notifications[0].NotificationType)
+	assert.NotNil(user.ReadNotification(ctx, notifications[0].NotificationID[:8]))
+	assert.NotNil(first.ReadNotification(ctx, notifications[0].NotificationID))
+	assert.Nil(user.ReadNotification(ctx, notifications[0].NotificationID))
+	assert.Nil(user.CountUnreadNotifications(ctx))
+	assert.Equal(int64(1), user.UnreadNotificationsCount)
+	_, err = second.CreateComment(ctx, "comment body", topic)
+	assert.Nil(err)
+	notifications, err = user.ReadNotifications(ctx, time.Time{})
+	assert.Nil(err)
+	assert.Len(notifications, 3)
+	assert.False(notifications[0].IsRead())
+	assert.Len(notifications[0].Actors, 1)
+	assert.True(notifications[1].IsRead())
+
+	assert.Nil(user.ReadAllNotifications(ctx))
+	assert.Nil(user.CountUnreadNotifications(ctx))
+	assert.Equal(int64(0), user.UnreadNotificationsCount)
+	assert.Nil(second.DeleteAccount(ctx, AccountDeletionHard, "password"))
+	notifications, err = user.ReadNotifications(ctx, time.Time{})
+	assert.Nil(err)
+	assert.Len(notifications, 2)
+	assert.Equal([]string{first.UserID}, notifications[1].ActorIDs)
+}
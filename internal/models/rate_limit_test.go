@@ -0,0 +1,53 @@
+package models
+
+import (
+	"satellity/internal/durable"
+	"satellity/internal/session"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresLimiter(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	limiter := durable.NewPostgresLimiter(session.Database(ctx))
+	for i := 0; i < 3; i++ {
+		a, err := limiter.Take(ctx, "key", 1, 3)
+		assert.Nil(err)
+		assert.True(a.Allowed)
+		assert.Equal(3, a.Limit)
+		assert.Equal(2-i, a.Remaining)
+	}
+	a, err := limiter.Take(ctx, "key", 1, 3)
+	assert.Nil(err)
+	assert.False(a.Allowed)
+	assert.Equal(0, a.Remaining)
+	assert.True(a.RetryAfter > 0 && a.RetryAfter <= time.Second)
+	assert.True(a.Reset > 2*time.Second && a.Reset <= 3*time.Second)
+
+	a, err = limiter.Take(ctx, "other", 1, 3)
+	assert.Nil(err)
+	assert.True(a.Allowed)
+	assert.Equal(2, a.Remaining)
+
+	_, err = session.Database(ctx).Exec(ctx, "UPDATE rate_limits SET tat=$1 WHERE limit_key='key'", time.Now().Add(2*time.Second))
+	assert.Nil(err)
+	a, err = limiter.Take(ctx, "key", 1, 3)
+	assert.Nil(err)
+	assert.True(a.Allowed)
+	assert.Equal(0, a.Remaining)
+	a, err = limiter.Take(ctx, "key", 1, 3)
+	assert.Nil(err)
+	assert.False(a.Allowed)
+
+	_, err = session.Database(ctx).Exec(ctx, "UPDATE rate_limits SET tat=$1", time.Now().Add(-time.Hour))
+	assert.Nil(err)
+	a, err = limiter.Take(ctx, "key", 1, 3)
+	assert.Nil(err)
+	assert.True(a.Allowed)
+	assert.Equal(2, a.Remaining)
+}
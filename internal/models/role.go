@@ -0,0 +1,223 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"satellity/internal/durable"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Permission related CONST, a role grants a set of them
+const (
+	PermissionTopicCreate    = "topic.create"
+	PermissionCommentCreate  = "comment.create"
+	PermissionTopicEditAny   = "topic.edit_any"
+	PermissionTopicDelete    = "topic.delete"
+	PermissionCommentEditAny = "comment.edit_any"
+	PermissionCategoryManage = "category.manage"
+	PermissionUserBan        = "user.ban"
+	PermissionUserManage     = "user.manage"
+)
+
+// Permissions is all known permissions, in display order
+var Permissions = []string{
+	PermissionTopicCreate,
+	PermissionCommentCreate,
+	PermissionTopicEditAny,
+	PermissionTopicDelete,
+	PermissionCommentEditAny,
+	PermissionCategoryManage,
+	PermissionUserBan,
+	PermissionUserManage,
+}
+
+// Role is a named set of permissions, users.role references it by name
+type Role struct {
+	Name        string
+	Permissions []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+var roleColumns = []string{"name", "permissions", "created_at", "updated_at"}
+
+func roleFromRow(row durable.Row) (*Role, error) {
+	var r Role
+	err := row.Scan(&r.Name, &r.Permissions, &r.CreatedAt, &r.UpdatedAt)
+	return &r, err
+}
+
+// ReadRoles read all roles
+func ReadRoles(ctx context.Context) ([]*Role, error) {
+	rows, err := session.Database(ctx).Query(ctx, fmt.Sprintf("SELECT %s FROM roles ORDER BY created_at,name", strings.Join(roleColumns, ",")))
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	defer rows.Close()
+
+	var roles []*Role
+	for rows.Next() {
+		role, err := roleFromRow(rows)
+		if err != nil {
+			return nil, session.TransactionError(ctx, err)
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return roles, nil
+}
+
+// ReadRole read a role by name
+func ReadRole(ctx context.Context, name string) (*Role, error) {
+	var role *Role
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		role, err = findRole(ctx, tx, name)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return role, nil
+}
+
+// UpdatePermissions replace the permissions of the role, the admin role always
+// grants everything and can't be changed
+func (role *Role) UpdatePermissions(ctx context.Context, permissions []string, operator *User) error {
+	if can, err := operator.Can(ctx, PermissionUserManage); err != nil {
+		return err
+	} else if !can {
+		return session.ForbiddenError(ctx)
+	}
+	if role.Name == UserRoleAdmin {
+		return session.ForbiddenError(ctx)
+	}
+	set := make(map[string]bool)
+	for _, p := range permissions {
+		if !isPermission(p) {
+			return session.BadDataErrorWithFieldAndData(ctx, "permissions", "invalid", p)
+		}
+		set[p] = true
+	}
+	role.Permissions = []string{}
+	for _, p := range Permissions {
+		if set[p] {
+			role.Permissions = append(role.Permissions, p)
+		}
+	}
+	role.UpdatedAt = time.Now()
+	_, err := session.Database(ctx).Exec(ctx, "UPDATE roles SET (permissions,updated_at)=($1,$2) WHERE name=$3", role.Permissions, role.UpdatedAt, role.Name)
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// HasPermission check if the role grants the permission
+func (role *Role) HasPermission(permission string) bool {
+	if role.Name == UserRoleAdmin {
+		return true
+	}
+	for _, p := range role.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// AssignRole change the role of the user, only admins can grant admin
+func (user *User) AssignRole(ctx context.Context, name string, operator *User) error {
+	if can, err := operator.Can(ctx, PermissionUserManage); err != nil {
+		return err
+	} else if !can {
+		return session.ForbiddenError(ctx)
+	}
+	if (name == UserRoleAdmin || user.GetRole() == UserRoleAdmin) && operator.GetRole() != UserRoleAdmin {
+		return session.ForbiddenError(ctx)
+	}
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		role, err := findRole(ctx, tx, name)
+		if err != nil {
+			return err
+		} else if role == nil {
+			return session.BadDataErrorWithFieldAndData(ctx, "role", "invalid", name)
+		}
+		user.Role, user.role = role.Name, role
+		user.UpdatedAt = time.Now()
+		_, err = tx.Exec(ctx, "UPDATE users SET (role,updated_at)=($1,$2) WHERE user_id=$3", user.Role, user.UpdatedAt, user.UserID)
+		return err
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// Can check if the role of the user grants the permission
+func (user *User) Can(ctx context.Context, permission string) (bool, error) {
+	var can bool
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		can, err = user.can(ctx, tx, permission)
+		return err
+	})
+	if err != nil {
+		return false, session.TransactionError(ctx, err)
+	}
+	return can, nil
+}
+
+func (user *User) can(ctx context.Context, tx pgx.Tx, permission string) (bool, error) {
+	if user == nil {
+		return false, nil
+	}
+	if user.GetRole() == UserRoleAdmin {
+		return true, nil
+	}
+	if user.role == nil || user.role.Name != user.GetRole() {
+		role, err := findRole(ctx, tx, user.GetRole())
+		if err != nil || role == nil {
+			return false, err
+		}
+		user.role = role
+	}
+	return user.role.HasPermission(permission), nil
+}
+
+// canModerate is true if the role grants more than posting
+func (user *User) canModerate(ctx context.Context, tx pgx.Tx) (bool, error) {
+	for _, p := range Permissions {
+		if p == PermissionTopicCreate || p == PermissionCommentCreate {
+			continue
+		}
+		if can, err := user.can(ctx, tx, p); err != nil || can {
+			return can, err
+		}
+	}
+	return false, nil
+}
+
+func findRole(ctx context.Context, tx pgx.Tx, name string) (*Role, error) {
+	row := tx.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM roles WHERE name=$1", strings.Join(roleColumns, ",")), name)
+	role, err := roleFromRow(row)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return role, err
+}
+
+func isPermission(permission string) bool {
+	for _, p := range Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
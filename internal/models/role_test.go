@@ -0,0 +1,79 @@
+package models
+
+import (
+	"satellity/internal/configs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleCRUD(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	roles, err := ReadRoles(ctx)
+	assert.Nil(err)
+	assert.Len(roles, 4)
+	role, err := ReadRole(ctx, "guest")
+	assert.Nil(err)
+	assert.Nil(role)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	operator := createTestUser(ctx, "operator@gmail.com", "operator", "password")
+	assert.NotNil(operator)
+	can, err := user.Can(ctx, PermissionTopicCreate)
+	assert.Nil(err)
+	assert.True(can)
+	can, err = user.Can(ctx, PermissionTopicDelete)
+	assert.Nil(err)
+	assert.False(can)
+	err = user.AssignRole(ctx, UserRoleModerator, operator)
+	assert.NotNil(err)
+
+	configs.AppConfig.OperatorSet["operator@gmail.com"] = true
+	defer delete(configs.AppConfig.OperatorSet, "operator@gmail.com")
+	can, err = operator.Can(ctx, PermissionUserManage)
+	assert.Nil(err)
+	assert.True(can)
+	err = user.AssignRole(ctx, "guest", operator)
+	assert.NotNil(err)
+	err = user.AssignRole(ctx, UserRoleModerator, operator)
+	assert.Nil(err)
+	assert.Equal(UserRoleModerator, user.GetRole())
+	can, err = user.Can(ctx, PermissionTopicDelete)
+	assert.Nil(err)
+	assert.True(can)
+	can, err = user.Can(ctx, PermissionCategoryManage)
+	assert.Nil(err)
+	assert.False(can)
+	err = operator.AssignRole(ctx, UserRoleMember, user)
+	assert.NotNil(err)
+
+	existing, err := ReadUser(ctx, user.UserID)
+	assert.Nil(err)
+	assert.Equal(UserRoleModerator, existing.GetRole())
+	role, err = ReadRole(ctx, UserRoleModerator)
+	assert.Nil(err)
+	assert.NotNil(role)
+	err = role.UpdatePermissions(ctx, []string{"topic.publish"}, operator)
+	assert.NotNil(err)
+	err = role.UpdatePermissions(ctx, []string{PermissionCategoryManage, PermissionTopicCreate}, user)
+	assert.NotNil(err)
+	err = role.UpdatePermissions(ctx, []string{PermissionCategoryManage, PermissionTopicCreate}, operator)
+	assert.Nil(err)
+	assert.Equal([]string{PermissionTopicCreate, PermissionCategoryManage}, role.Permissions)
+	can, err = existing.Can(ctx, PermissionCategoryManage)
+	assert.Nil(err)
+	assert.True(can)
+	can, err = existing.Can(ctx, PermissionTopicDelete)
+	assert.Nil(err)
+	assert.False(can)
+
+	role, err = ReadRole(ctx, UserRoleAdmin)
+	assert.Nil(err)
+	err = role.UpdatePermissions(ctx, []string{}, operator)
+	assert.NotNil(err)
+	assert.True(role.HasPermission(PermissionUserBan))
+}
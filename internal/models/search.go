@@ -0,0 +1,312 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"satellity/internal/session"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// Search result types, a search matches both unless one is given
+const (
+	SearchTypeTopic   = "topic"
+	SearchTypeComment = "comment"
+)
+
+// Matches in snippets are wrapped by the selectors, which are replaced by <mark>
+// after the text is escaped. The text is searched by search_text, which swaps <
+// and > for the tag selectors, or the parser drops <tags> as html.
+const (
+	searchQueryLimit     = 256
+	searchReindexBatch   = 1000
+	searchStartSelector  = "\x02"
+	searchStopSelector   = "\x03"
+	searchTagStart       = "\x04"
+	searchTagStop        = "\x05"
+	searchTitleHeadline  = "HighlightAll=true,StartSel=\x02,StopSel=\x03"
+	searchBodyHeadline   = "MaxFragments=2,MaxWords=30,MinWords=10,StartSel=\x02,StopSel=\x03"
+	searchCursorSplitter = ":"
+)
+
+// SearchQuery is the full-text query with the optional filters, Cursor is the
+// cursor of the last result of the previous page
+type SearchQuery struct {
+	Query      string
+	Type       string
+	CategoryID string
+	UserID     string
+	From       time.Time
+	To         time.Time
+	Cursor     string
+}
+
+// SearchResult is a matched topic or comment, ordered by rank. The snippets are
+// escaped html with the matches in <mark>.
+type SearchResult struct {
+	Type         string
+	Rank         float32
+	Topic        *Topic
+	Comment      *Comment
+	TitleSnippet string
+	Snippet      string
+	Cursor       string
+}
+
+// Search the published topics, weighted title above body, and the comments
+func Search(ctx context.Context, q SearchQuery) ([]*SearchResult, error) {
+	q.Query = strings.TrimSpace(q.Query)
+	if q.Query == "" || len(q.Query) > searchQueryLimit {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "q", "invalid", q.Query)
+	}
+	if q.Type != "" && q.Type != SearchTypeTopic && q.Type != SearchTypeComment {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "type", "invalid", q.Type)
+	}
+
+	params := []any{q.Query}
+	param := func(v any) string {
+		params = append(params, v)
+		return fmt.Sprintf("$%d", len(params))
+	}
+	var topicFilters, commentFilters []string
+	if q.CategoryID != "" {
+		if uuid.FromStringOrNil(q.CategoryID).String() != q.CategoryID {
+			return nil, session.BadDataErrorWithFieldAndData(ctx, "category_id", "invalid", q.CategoryID)
+		}
+		p := param(q.CategoryID)
+		topicFilters = append(topicFilters, "t.category_id="+p)
+		commentFilters = append(commentFilters, "t.category_id="+p)
+	}
+	if q.UserID != "" {
+		if uuid.FromStringOrNil(q.UserID).String() != q.UserID {
+			return nil, session.BadDataErrorWithFieldAndData(ctx, "user_id", "invalid", q.UserID)
+		}
+		p := param(q.UserID)
+		topicFilters = append(topicFilters, "t.user_id="+p)
+		commentFilters = append(commentFilters, "c.user_id="+p)
+	}
+	if !q.From.IsZero() {
+		p := param(q.From)
+		topicFilters = append(topicFilters, "t.created_at>="+p)
+		commentFilters = append(commentFilters, "c.created_at>="+p)
+	}
+	if !q.To.IsZero() {
+		p := param(q.To)
+		topicFilters = append(topicFilters, "t.created_at<"+p)
+		commentFilters = append(commentFilters, "c.created_at<"+p)
+	}
+
+	var hits []string
+	if q.Type != SearchTypeComment {
+		hits = append(hits, "SELECT 'topic' AS kind, t.topic_id AS id, ts_rank(t.search_vector, q.query) AS rank FROM topics t, q WHERE t.draft=false AND t.search_vector @@ q.query"+searchFilters(topicFilters))
+	}
+	if q.Type != SearchTypeTopic {
+		hits = append(hits, "SELECT 'comment' AS kind, c.comment_id AS id, ts_rank(c.search_vector, q.query) AS rank FROM comments c INNER JOIN topics t ON t.topic_id=c.topic_id, q WHERE t.draft=false AND c.deleted_at IS NULL AND c.search_vector @@ q.query"+searchFilters(commentFilters))
+	}
+	query := fmt.Sprintf("WITH q AS (SELECT websearch_to_tsquery('english', search_text($1)) AS query) SELECT kind, id, rank FROM (%s) hits", strings.Join(hits, " UNION ALL "))
+	if q.Cursor != "" {
+		rank, id, err := parseSearchCursor(q.Cursor)
+		if err != nil {
+			return nil, session.BadDataErrorWithFieldAndData(ctx, "cursor", "invalid", q.Cursor)
+		}
+		query += fmt.Sprintf(" WHERE (rank,id)<(%s::real,%s)", param(rank), param(id))
+	}
+	query += fmt.Sprintf(" ORDER BY rank DESC,id DESC LIMIT %s", param(LIMIT))
+
+	var results []*SearchResult
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, params...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var r SearchResult
+			var id string
+			if err := rows.Scan(&r.Type, &id, &r.Rank); err != nil {
+				return err
+			}
+			r.Cursor = searchCursor(r.Rank, id)
+			if r.Type == SearchTypeTopic {
+				r.Topic = &Topic{TopicID: id}
+			} else {
+				r.Comment = &Comment{CommentID: id}
+			}
+			results = append(results, &r)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+		return fillSearchResults(ctx, tx, q.Query, results)
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return results, nil
+}
+
+// fillSearchResults read the matched topics and comments with the snippets, the
+// topic of a comment is read too
+func fillSearchResults(ctx context.Context, tx pgx.Tx, query string, results []*SearchResult) error {
+	var topicIDs, commentIDs []string
+	for _, r := range results {
+		if r.Topic != nil {
+			topicIDs = append(topicIDs, r.Topic.TopicID)
+		} else {
+			commentIDs = append(commentIDs, r.Comment.CommentID)
+		}
+	}
+	comments, err := readCommentSet(ctx, tx, commentIDs)
+	if err != nil {
+		return err
+	}
+	var userIDs []string
+	for _, c := range comments {
+		topicIDs = append(topicIDs, c.TopicID)
+		userIDs = append(userIDs, c.UserID)
+	}
+	topics, err := queryTopics(ctx, tx, nil, nil, fmt.Sprintf("SELECT %s FROM topics WHERE topic_id=ANY($1)", strings.Join(topicColumns, ",")), topicIDs)
+	if err != nil {
+		return err
+	}
+	topicSet := make(map[string]*Topic)
+	for _, t := range topics {
+		topicSet[t.TopicID] = t
+	}
+	users, err := readUserSet(ctx, tx, userIDs)
+	if err != nil {
+		return err
+	}
+
+	snippets := make(map[string][2]string)
+	rows, err := tx.Query(ctx, "SELECT topic_id, ts_headline('english', search_text(title), q, $3), ts_headline('english', search_text(body), q, $4) FROM topics, websearch_to_tsquery('english', search_text($1)) q WHERE topic_id=ANY($2)", query, topicIDs, searchTitleHeadline, searchBodyHeadline)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id, title, body string
+		if err := rows.Scan(&id, &title, &body); err != nil {
+			rows.Close()
+			return err
+		}
+		snippets[id] = [2]string{highlightSnippet(title), highlightSnippet(body)}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows, err = tx.Query(ctx, "SELECT comment_id, ts_headline('english', search_text(body), q, $3) FROM comments, websearch_to_tsquery('english', search_text($1)) q WHERE comment_id=ANY($2)", query, commentIDs, searchBodyHeadline)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, body string
+		if err := rows.Scan(&id, &body); err != nil {
+			return err
+		}
+		snippets[id] = [2]string{"", highlightSnippet(body)}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		if r.Comment != nil {
+			id := r.Comment.CommentID
+			r.Snippet = snippets[id][1]
+			r.Comment = comments[id]
+			if r.Comment != nil {
+				r.Comment.User = users[r.Comment.UserID]
+				r.Topic = topicSet[r.Comment.TopicID]
+			}
+		} else {
+			id := r.Topic.TopicID
+			r.Snippet = snippets[id][1]
+			r.Topic = topicSet[id]
+		}
+		if r.Topic != nil {
+			r.TitleSnippet = snippets[r.Topic.TopicID][0]
+		}
+	}
+	return nil
+}
+
+// ReindexSearch rebuild the search vectors of all topics and comments in
+// batches, return the count of rows reindexed
+func ReindexSearch(ctx context.Context) (int64, error) {
+	tables := []struct {
+		name, key, vector string
+	}{
+		{"topics", "topic_id", "topic_search_vector(title, body)"},
+		{"comments", "comment_id", "comment_search_vector(body)"},
+	}
+	var count int64
+	for _, table := range tables {
+		last := ""
+		for {
+			var ids []string
+			err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+				var err error
+				ids, err = queryIDs(ctx, tx, fmt.Sprintf("SELECT %s FROM %s WHERE %s>$1 ORDER BY %s LIMIT %d", table.key, table.name, table.key, table.key, searchReindexBatch), last)
+				if err != nil || len(ids) == 0 {
+					return err
+				}
+				_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE %s SET search_vector=%s WHERE %s=ANY($1)", table.name, table.vector, table.key), ids)
+				return err
+			})
+			if err != nil {
+				return count, session.TransactionError(ctx, err)
+			}
+			if len(ids) == 0 {
+				break
+			}
+			count += int64(len(ids))
+			last = ids[len(ids)-1]
+		}
+	}
+	return count, nil
+}
+
+func searchFilters(filters []string) string {
+	if len(filters) == 0 {
+		return ""
+	}
+	return " AND " + strings.Join(filters, " AND ")
+}
+
+// highlightSnippet escape the headline, turn the selectors into <mark> and the
+// tag selectors back into escaped < and >
+func highlightSnippet(s string) string {
+	r := strings.NewReplacer(searchStartSelector, "<mark>", searchStopSelector, "</mark>", searchTagStart, "&lt;", searchTagStop, "&gt;")
+	return r.Replace(html.EscapeString(s))
+}
+
+func searchCursor(rank float32, id string) string {
+	s := strconv.FormatFloat(float64(rank), 'g', -1, 32) + searchCursorSplitter + id
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func parseSearchCursor(cursor string) (float32, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", err
+	}
+	parts := strings.SplitN(string(data), searchCursorSplitter, 2)
+	if len(parts) != 2 || uuid.FromStringOrNil(parts[1]).String() != parts[1] {
+		return 0, "", fmt.Errorf("invalid cursor %s", cursor)
+	}
+	rank, err := strconv.ParseFloat(parts[0], 32)
+	if err != nil {
+		return 0, "", err
+	}
+	return float32(rank), parts[1], nil
+}
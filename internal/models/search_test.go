@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	other := createTestUser(ctx, "other@gmail.com", "other", "password")
+	assert.NotNil(other)
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+	titled, err := user.CreateTopic(ctx, "Postgres <search> tips", "some body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	bodied, err := other.CreateTopic(ctx, "another title", "how postgres search works", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	_, err = user.CreateTopic(ctx, "draft postgres", "search draft", TopicTypePost, category.CategoryID, true)
+	assert.Nil(err)
+	comment, err := other.CreateComment(ctx, "search in postgres is fast", titled)
+	assert.Nil(err)
+
+	_, err = Search(ctx, SearchQuery{Query: " "})
+	assert.NotNil(err)
+	_, err = Search(ctx, SearchQuery{Query: "postgres", Type: "user"})
+	assert.NotNil(err)
+	_, err = Search(ctx, SearchQuery{Query: "postgres", Cursor: "invalid"})
+	assert.NotNil(err)
+
+	results, err := Search(ctx, SearchQuery{Query: "postgres search"})
+	assert.Nil(err)
+	assert.Len(results, 3)
+	assert.Equal(SearchTypeTopic, results[0].Type)
+	assert.Equal(titled.TopicID, results[0].Topic.TopicID)
+	assert.Equal("<mark>Postgres</mark> &lt;<mark>search</mark>&gt; tips", results[0].TitleSnippet)
+	assert.NotNil(results[0].Topic.User)
+
+	results, err = Search(ctx, SearchQuery{Query: "postgres", Type: SearchTypeComment})
+	assert.Nil(err)
+	assert.Len(results, 1)
+	assert.Equal(comment.CommentID, results[0].Comment.CommentID)
+	assert.Equal(titled.TopicID, results[0].Topic.TopicID)
+	assert.Contains(results[0].Snippet, "<mark>postgres</mark>")
+
+	results, err = Search(ctx, SearchQuery{Query: "postgres", UserID: other.UserID, Type: SearchTypeTopic})
+	assert.Nil(err)
+	assert.Len(results, 1)
+	assert.Equal(bodied.TopicID, results[0].Topic.TopicID)
+	results, err = Search(ctx, SearchQuery{Query: "postgres", To: time.Now().Add(-time.Hour)})
+	assert.Nil(err)
+	assert.Len(results, 0)
+
+	results, err = Search(ctx, SearchQuery{Query: "postgres"})
+	assert.Nil(err)
+	assert.Len(results, 3)
+	page, err := Search(ctx, SearchQuery{Query: "postgres", Cursor: results[0].Cursor})
+	assert.Nil(err)
+	assert.Len(page, 2)
+	assert.Equal(results[1].Cursor, page[0].Cursor)
+
+	count, err := ReindexSearch(ctx)
+	assert.Nil(err)
+	assert.Equal(int64(4), count)
+}
@@ -13,40 +13,75 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Session related CONST
+const (
+	sessionsLimit         = 8
+	sessionLifetime       = 30 * 24 * time.Hour
+	sessionActiveInterval = time.Minute
+)
+
 // Session contains user's current login infomation
 type Session struct {
-	SessionID string
-	UserID    string
-	PublicKey string // ed25519 Public Key
-	CreatedAt time.Time
+	SessionID    string
+	UserID       string
+	PublicKey    string // ed25519 Public Key
+	CreatedAt    time.Time
+	LastActiveAt time.Time
+	ExpiresAt    time.Time
 }
 
-var sessionColumns = []string{"session_id", "user_id", "public_key", "created_at"}
+var sessionColumns = []string{"session_id", "user_id", "public_key", "created_at", "last_active_at", "expires_at"}
 
 func (s *Session) values() []interface{} {
-	return []interface{}{s.SessionID, s.UserID, s.PublicKey, s.CreatedAt}
+	return []interface{}{s.SessionID, s.UserID, s.PublicKey, s.CreatedAt, s.LastActiveAt, s.ExpiresAt}
 }
 
 func sessionFromRows(row durable.Row) (*Session, error) {
 	var s Session
-	err := row.Scan(&s.SessionID, &s.UserID, &s.PublicKey, &s.CreatedAt)
+	err := row.Scan(&s.SessionID, &s.UserID, &s.PublicKey, &s.CreatedAt, &s.LastActiveAt, &s.ExpiresAt)
 	return &s, err
 }
 
-// CreateSession create a new user session
-func CreateSession(ctx context.Context, identity, password, pubED25519 string) (*User, error) {
+// CreateSession create a new user session, code is required if the user enabled two factor
+func CreateSession(ctx context.Context, identity, password, code, pubED25519 string) (*User, error) {
+	keys := ipAttemptKeys(ctx)
+	if err := checkLoginAttempts(ctx, keys...); err != nil {
+		return nil, err
+	}
 	user, err := ReadUserByUsernameOrEmail(ctx, identity)
 	if err != nil {
 		return nil, err
 	} else if user == nil {
+		if err := failLoginAttempt(ctx, keys...); err != nil {
+			return nil, err
+		}
 		return nil, session.IdentityNonExistError(ctx)
 	}
+	keys = append(keys, userAttemptKey(user.UserID))
+	if err := checkLoginAttempts(ctx, keys...); err != nil {
+		return nil, err
+	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.EncryptedPassword.String), []byte(password)); err != nil {
+		if err := failLoginAttempt(ctx, keys...); err != nil {
+			return nil, err
+		}
 		return nil, session.InvalidPasswordError(ctx)
 	}
+	if user.PasswordResetRequired {
+		return nil, session.PasswordResetRequiredError(ctx)
+	}
 
+	var failed bool
 	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
-		_, err := tx.Exec(ctx, "DELETE FROM sessions WHERE session_id IN (SELECT session_id FROM sessions WHERE user_id=$1 ORDER BY user_id, created_at DESC OFFSET 8)", user.UserID)
+		if err := verifyTwoFactor(ctx, tx, user, code); err != nil {
+			failed = code != ""
+			return err
+		}
+		_, err := tx.Exec(ctx, "DELETE FROM sessions WHERE session_id IN (SELECT session_id FROM sessions WHERE user_id=$1 ORDER BY user_id, created_at DESC OFFSET $2)", user.UserID, sessionsLimit)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM sessions WHERE expires_at<$1", time.Now())
 		if err != nil {
 			return err
 		}
@@ -57,18 +92,32 @@ func CreateSession(ctx context.Context, identity, password, pubED25519 string) (
 		user.SessionID = s.SessionID
 		return nil
 	})
+	if failed {
+		if err := failLoginAttempt(ctx, keys...); err != nil {
+			return nil, err
+		}
+	}
 	if err != nil {
 		return nil, session.TransactionError(ctx, err)
 	}
+	if err := clearLoginAttempts(ctx, userAttemptKey(user.UserID)); err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
 func (user *User) addSession(ctx context.Context, tx pgx.Tx, secret string) (*Session, error) {
+	if user.IsSuspended() {
+		return nil, session.UserSuspendedError(ctx, user.SuspendedUntil.Time, user.SuspensionReason)
+	}
+	t := time.Now()
 	s := &Session{
-		SessionID: uuid.Must(uuid.NewV4()).String(),
-		UserID:    user.UserID,
-		PublicKey: secret,
-		CreatedAt: time.Now(),
+		SessionID:    uuid.Must(uuid.NewV4()).String(),
+		UserID:       user.UserID,
+		PublicKey:    secret,
+		CreatedAt:    t,
+		LastActiveAt: t,
+		ExpiresAt:    t.Add(sessionLifetime),
 	}
 
 	rows := [][]interface{}{s.values()}
@@ -91,3 +140,59 @@ func readSession(ctx context.Context, tx pgx.Tx, uid, sid string) (*Session, err
 	}
 	return s, err
 }
+
+// ReadSessions read all active sessions of the user, the latest active first
+func (user *User) ReadSessions(ctx context.Context) ([]*Session, error) {
+	query := fmt.Sprintf("SELECT %s FROM sessions WHERE user_id=$1 AND expires_at>$2 ORDER BY last_active_at DESC", strings.Join(sessionColumns, ","))
+	rows, err := session.Database(ctx).Query(ctx, query, user.UserID, time.Now())
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+	for rows.Next() {
+		s, err := sessionFromRows(rows)
+		if err != nil {
+			return nil, session.TransactionError(ctx, err)
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return sessions, nil
+}
+
+// DeleteSession revoke a session of the user, revoke the current session means sign out
+func (user *User) DeleteSession(ctx context.Context, id string) error {
+	if uuid.FromStringOrNil(id).String() != id {
+		return nil
+	}
+	_, err := session.Database(ctx).Exec(ctx, "DELETE FROM sessions WHERE user_id=$1 AND session_id=$2", user.UserID, id)
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// DeleteSessions revoke all sessions of the user, aka sign out everywhere
+func (user *User) DeleteSessions(ctx context.Context) error {
+	_, err := session.Database(ctx).Exec(ctx, "DELETE FROM sessions WHERE user_id=$1", user.UserID)
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// touch extends an active session, it writes at most once per sessionActiveInterval
+func (s *Session) touch(ctx context.Context, tx pgx.Tx) error {
+	t := time.Now()
+	if s.LastActiveAt.Add(sessionActiveInterval).After(t) {
+		return nil
+	}
+	s.LastActiveAt = t
+	s.ExpiresAt = t.Add(sessionLifetime)
+	_, err := tx.Exec(ctx, "UPDATE sessions SET (last_active_at,expires_at)=($1,$2) WHERE session_id=$3", s.LastActiveAt, s.ExpiresAt, s.SessionID)
+	return err
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"satellity/internal/session"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionCRUD(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	sessions, err := user.ReadSessions(ctx)
+	assert.Nil(err)
+	assert.Len(sessions, 1)
+	assert.True(sessions[0].ExpiresAt.After(time.Now()))
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(err)
+	existing, err := CreateSession(ctx, "im.yuqlee@gmail.com", "password", "", hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.NotNil(existing)
+	sessions, err = user.ReadSessions(ctx)
+	assert.Nil(err)
+	assert.Len(sessions, 2)
+	assert.Equal(existing.SessionID, sessions[0].SessionID)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, &jwt.MapClaims{
+		"uid": existing.UserID,
+		"sid": existing.SessionID,
+	})
+	ss, err := token.SignedString(private)
+	assert.Nil(err)
+	current, err := AuthenticateUser(ctx, ss)
+	assert.Nil(err)
+	assert.NotNil(current)
+	assert.Equal(existing.SessionID, current.SessionID)
+
+	_, err = session.Database(ctx).Exec(ctx, "UPDATE sessions SET expires_at=$1 WHERE session_id=$2", time.Now().Add(-time.Minute), existing.SessionID)
+	assert.Nil(err)
+	current, err = AuthenticateUser(ctx, ss)
+	assert.Nil(err)
+	assert.Nil(current)
+	sessions, err = user.ReadSessions(ctx)
+	assert.Nil(err)
+	assert.Len(sessions, 1)
+
+	err = user.DeleteSession(ctx, uuid.Must(uuid.NewV4()).String())
+	assert.Nil(err)
+	err = user.DeleteSession(ctx, "invalid")
+	assert.Nil(err)
+	sessions, err = user.ReadSessions(ctx)
+	assert.Nil(err)
+	assert.Len(sessions, 1)
+	err = user.DeleteSession(ctx, sessions[0].SessionID)
+	assert.Nil(err)
+	sessions, err = user.ReadSessions(ctx)
+	assert.Nil(err)
+	assert.Len(sessions, 0)
+
+	for i := 0; i < 3; i++ {
+		public, _, _ := ed25519.GenerateKey(rand.Reader)
+		_, err = CreateSession(ctx, "username", "password", "", hex.EncodeToString(public))
+		assert.Nil(err)
+	}
+	sessions, err = user.ReadSessions(ctx)
+	assert.Nil(err)
+	assert.Len(sessions, 3)
+	err = user.DeleteSessions(ctx)
+	assert.Nil(err)
+	sessions, err = user.ReadSessions(ctx)
+	assert.Nil(err)
+	assert.Len(sessions, 0)
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"context"
+	"satellity/internal/session"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Subscription levels of topics and categories. Watching a topic notifies every
+// new comment, tracking only the replies, mentions and likes, muted nothing. A
+// topic without its own level follows its category, or tracking by default.
+// Watching or tracking a category notifies its new topics too.
+const (
+	SubscriptionWatching = "watching"
+	SubscriptionTracking = "tracking"
+	SubscriptionMuted    = "muted"
+	SubscriptionDefault  = ""
+)
+
+// Subscription is the effective level of a user on a topic or category,
+// Inherited is true if the topic follows its category or the default
+type Subscription struct {
+	TopicID    string
+	CategoryID string
+	UserID     string
+	Level      string
+	Inherited  bool
+}
+
+// ReadSubscription read the subscription of the user to the topic
+func (topic *Topic) ReadSubscription(ctx context.Context, user *User) (*Subscription, error) {
+	var s *Subscription
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		s, err = readTopicSubscription(ctx, tx, topic, user)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return s, nil
+}
+
+// Subscribe set the level of the user on the topic, SubscriptionDefault follows the category
+func (topic *Topic) Subscribe(ctx context.Context, user *User, level string) (*Subscription, error) {
+	if !isSubscriptionLevel(level) {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "level", "invalid", level)
+	}
+	var s *Subscription
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		t := time.Now()
+		_, err := tx.Exec(ctx, "INSERT INTO topic_users (topic_id,user_id,subscription,created_at,updated_at) VALUES ($1,$2,$3,$4,$4) ON CONFLICT (topic_id,user_id) DO UPDATE SET (subscription,updated_at)=($3,$4)", topic.TopicID, user.UserID, level, t)
+		if err != nil {
+			return err
+		}
+		s, err = readTopicSubscription(ctx, tx, topic, user)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return s, nil
+}
+
+// ReadSubscription read the subscription of the user to the category
+func (category *Category) ReadSubscription(ctx context.Context, user *User) (*Subscription, error) {
+	var s *Subscription
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		s, err = readCategorySubscription(ctx, tx, category, user)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return s, nil
+}
+
+// Subscribe set the level of the user on the category, SubscriptionDefault
+// removes it
+func (category *Category) Subscribe(ctx context.Context, user *User, level string) (*Subscription, error) {
+	if !isSubscriptionLevel(level) {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "level", "invalid", level)
+	}
+	var s *Subscription
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		if level == SubscriptionDefault {
+			_, err = tx.Exec(ctx, "DELETE FROM category_users WHERE category_id=$1 AND user_id=$2", category.CategoryID, user.UserID)
+		} else {
+			t := time.Now()
+			_, err = tx.Exec(ctx, "INSERT INTO category_users (category_id,user_id,subscription,created_at,updated_at) VALUES ($1,$2,$3,$4,$4) ON CONFLICT (category_id,user_id) DO UPDATE SET (subscription,updated_at)=($3,$4)", category.CategoryID, user.UserID, level, t)
+		}
+		if err != nil {
+			return err
+		}
+		s, err = readCategorySubscription(ctx, tx, category, user)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return s, nil
+}
+
+func readTopicSubscription(ctx context.Context, tx pgx.Tx, topic *Topic, user *User) (*Subscription, error) {
+	s := &Subscription{TopicID: topic.TopicID, CategoryID: topic.CategoryID, UserID: user.UserID}
+	var own bool
+	err := tx.QueryRow(ctx, "SELECT topic_subscription($1,$2), EXISTS (SELECT 1 FROM topic_users WHERE topic_id=$1 AND user_id=$2 AND subscription<>'')", topic.TopicID, user.UserID).Scan(&s.Level, &own)
+	s.Inherited = !own
+	return s, err
+}
+
+func readCategorySubscription(ctx context.Context, tx pgx.Tx, category *Category, user *User) (*Subscription, error) {
+	s := &Subscription{CategoryID: category.CategoryID, UserID: user.UserID, Level: SubscriptionTracking, Inherited: true}
+	err := tx.QueryRow(ctx, "SELECT subscription FROM category_users WHERE category_id=$1 AND user_id=$2", category.CategoryID, user.UserID).Scan(&s.Level)
+	if err == pgx.ErrNoRows {
+		return s, nil
+	}
+	s.Inherited = false
+	return s, err
+}
+
+// watchTopic subscribe the user to a topic created or commented on, unless a
+// level is chosen
+func watchTopic(ctx context.Context, tx pgx.Tx, topicID, userID string) error {
+	t := time.Now()
+	_, err := tx.Exec(ctx, "INSERT INTO topic_users (topic_id,user_id,subscription,created_at,updated_at) VALUES ($1,$2,$3,$4,$4) ON CONFLICT (topic_id,user_id) DO UPDATE SET (subscription,updated_at)=($3,$4) WHERE topic_users.subscription=''", topicID, userID, SubscriptionWatching, t)
+	return err
+}
+
+// readTopicWatcherIDs read the users watching the topic, by its own level or
+// its category
+func readTopicWatcherIDs(ctx context.Context, tx pgx.Tx, topic *Topic) ([]string, error) {
+	return queryIDs(ctx, tx, "SELECT user_id FROM topic_users WHERE topic_id=$1 AND subscription=$3 UNION SELECT cu.user_id FROM category_users cu WHERE cu.category_id=$2 AND cu.subscription=$3 AND NOT EXISTS (SELECT 1 FROM topic_users tu WHERE tu.topic_id=$1 AND tu.user_id=cu.user_id AND tu.subscription<>'')", topic.TopicID, topic.CategoryID, SubscriptionWatching)
+}
+
+// readCategorySubscriberIDs read the users watching or tracking the category
+func readCategorySubscriberIDs(ctx context.Context, tx pgx.Tx, categoryID string) ([]string, error) {
+	return queryIDs(ctx, tx, "SELECT user_id FROM category_users WHERE category_id=$1 AND subscription IN ($2,$3)", categoryID, SubscriptionWatching, SubscriptionTracking)
+}
+
+func isSubscriptionLevel(level string) bool {
+	switch level {
+	case SubscriptionWatching, SubscriptionTracking, SubscriptionMuted, SubscriptionDefault:
+		return true
+	}
+	return false
+}
+
+// publishTopic subscribe the author to the topic just published, and return
+// the subscribers of its category
+func publishTopic(ctx context.Context, tx pgx.Tx, topic *Topic) ([]string, error) {
+	if err := watchTopic(ctx, tx, topic.TopicID, topic.UserID); err != nil {
+		return nil, err
+	}
+	return readCategorySubscriberIDs(ctx, tx, topic.CategoryID)
+}
+
+// notifySubscribers notify the subscribers of a new topic, but the mentioned
+// ones who have been notified
+func notifySubscribers(ctx context.Context, ids []string, mentioned []*User, actor *User, topicID string) {
+	notified := make(map[string]bool)
+	for _, u := range mentioned {
+		notified[u.UserID] = true
+	}
+	for _, id := range ids {
+		if !notified[id] {
+			notify(ctx, NotificationTypeCreated, id, actor, topicID, "", topicID)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionCRUD(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	first := createTestUser(ctx, "first@gmail.com", "first", "password")
+	assert.NotNil(first)
+	second := createTestUser(ctx, "second@gmail.com", "second", "password")
+	assert.NotNil(second)
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+
+	s, err := category.Subscribe(ctx, second, "unknown")
+	assert.NotNil(err)
+	assert.Nil(s)
+	s, err = category.ReadSubscription(ctx, second)
+	assert.Nil(err)
+	assert.Equal(SubscriptionTracking, s.Level)
+	assert.True(s.Inherited)
+	s, err = category.Subscribe(ctx, second, SubscriptionWatching)
+	assert.Nil(err)
+	assert.Equal(SubscriptionWatching, s.Level)
+	assert.False(s.Inherited)
+
+	topic, err := user.CreateTopic(ctx, "title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	s, err = topic.ReadSubscription(ctx, user)
+	assert.Nil(err)
+	assert.Equal(SubscriptionWatching, s.Level)
+	assert.False(s.Inherited)
+	s, err = topic.ReadSubscription(ctx, first)
+	assert.Nil(err)
+	assert.Equal(SubscriptionTracking, s.Level)
+	assert.True(s.Inherited)
+	notifications, err := second.ReadNotifications(ctx, time.Time{})
+	assert.Nil(err)
+	assert.Len(notifications, 1)
+	assert.Equal(NotificationTypeCreated, notifications[0].NotificationType)
+
+	_, err = first.CreateComment(ctx, "comment body", topic)
+	assert.Nil(err)
+	s, err = topic.ReadSubscription(ctx, first)
+	assert.Nil(err)
+	assert.Equal(SubscriptionWatching, s.Level)
+	notifications, err = second.ReadNotifications(ctx, time.Time{})
+	assert.Nil(err)
+	assert.Len(notifications, 2)
+	assert.Equal(NotificationTypeCommented, notifications[0].NotificationType)
+
+	assert.Nil(user.ReadAllNotifications(ctx))
+	s, err = topic.Subscribe(ctx, user, SubscriptionMuted)
+	assert.Nil(err)
+	assert.Equal(SubscriptionMuted, s.Level)
+	s, err = topic.Subscribe(ctx, second, SubscriptionTracking)
+	assert.Nil(err)
+	assert.Equal(SubscriptionTracking, s.Level)
+	_, err = first.CreateComment(ctx, "another comment", topic)
+	assert.Nil(err)
+	_, err = topic.ActiondBy(ctx, first, TopicUserActionLiked, true)
+	assert.Nil(err)
+	assert.Nil(user.CountUnreadNotifications(ctx))
+	assert.Equal(int64(0), user.UnreadNotificationsCount)
+	assert.Nil(second.CountUnreadNotifications(ctx))
+	assert.Equal(int64(2), second.UnreadNotificationsCount)
+
+	s, err = topic.Subscribe(ctx, second, SubscriptionDefault)
+	assert.Nil(err)
+	assert.Equal(SubscriptionWatching, s.Level)
+	assert.True(s.Inherited)
+	s, err = category.Subscribe(ctx, second, SubscriptionDefault)
+	assert.Nil(err)
+	assert.Equal(SubscriptionTracking, s.Level)
+	assert.True(s.Inherited)
+}
@@ -34,8 +34,9 @@ type Topic struct {
 	ViewsCount     int64
 	CategoryID     string
 	UserID         string
-	Score          int
+	Score          float64
 	Draft          bool
+	Locked         bool
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 
@@ -43,17 +44,18 @@ type Topic struct {
 	IsBookmarkedBy bool
 	User           *User
 	Category       *Category
+	Mentions       []*Mention
 }
 
-var topicColumns = []string{"topic_id", "title", "body", "topic_type", "comments_count", "bookmarks_count", "likes_count", "views_count", "category_id", "user_id", "score", "draft", "created_at", "updated_at"}
+var topicColumns = []string{"topic_id", "title", "body", "topic_type", "comments_count", "bookmarks_count", "likes_count", "views_count", "category_id", "user_id", "score", "draft", "locked", "created_at", "updated_at"}
 
 func (t *Topic) values() []interface{} {
-	return []interface{}{t.TopicID, t.Title, t.Body, t.TopicType, t.CommentsCount, t.BookmarksCount, t.LikesCount, t.ViewsCount, t.CategoryID, t.UserID, t.Score, t.Draft, t.CreatedAt, t.UpdatedAt}
+	return []interface{}{t.TopicID, t.Title, t.Body, t.TopicType, t.CommentsCount, t.BookmarksCount, t.LikesCount, t.ViewsCount, t.CategoryID, t.UserID, t.Score, t.Draft, t.Locked, t.CreatedAt, t.UpdatedAt}
 }
 
 func topicFromRows(row durable.Row) (*Topic, error) {
 	var t Topic
-	err := row.Scan(&t.TopicID, &t.Title, &t.Body, &t.TopicType, &t.CommentsCount, &t.BookmarksCount, &t.LikesCount, &t.ViewsCount, &t.CategoryID, &t.UserID, &t.Score, &t.Draft, &t.CreatedAt, &t.UpdatedAt)
+	err := row.Scan(&t.TopicID, &t.Title, &t.Body, &t.TopicType, &t.CommentsCount, &t.BookmarksCount, &t.LikesCount, &t.ViewsCount, &t.CategoryID, &t.UserID, &t.Score, &t.Draft, &t.Locked, &t.CreatedAt, &t.UpdatedAt)
 	return &t, err
 }
 
@@ -98,6 +100,8 @@ func (user *User) CreateTopic(ctx context.Context, title, body, typ, categoryID
 		CreatedAt: t,
 		UpdatedAt: t,
 	}
+	var mentioned []*User
+	var subscribers []string
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
 		category, err := findCategory(ctx, tx, categoryID)
 		if err != nil {
@@ -111,6 +115,14 @@ func (user *User) CreateTopic(ctx context.Context, title, body, typ, categoryID
 			topic.values(),
 		}
 		_, err = tx.CopyFrom(ctx, pgx.Identifier{"topics"}, topicColumns, pgx.CopyFromRows(rows))
+		if err != nil || topic.Draft {
+			return err
+		}
+		topic.Mentions, mentioned, err = syncMentions(ctx, tx, topic.TopicID, "", topic.Body)
+		if err != nil {
+			return err
+		}
+		subscribers, err = publishTopic(ctx, tx, topic)
 		return err
 	})
 	if err != nil {
@@ -119,6 +131,8 @@ func (user *User) CreateTopic(ctx context.Context, title, body, typ, categoryID
 	if !topic.Draft {
 		UpsertStatistic(ctx, StatisticTypeTopics)
 		EmitToCategory(ctx, topic.CategoryID)
+		notifyMentions(ctx, mentioned, user, topic.TopicID, "")
+		notifySubscribers(ctx, subscribers, mentioned, user, topic.TopicID)
 	}
 	return topic, nil
 }
@@ -145,18 +159,23 @@ func (user *User) UpdateTopic(ctx context.Context, id, title, body, typ, categor
 
 	var topic *Topic
 	var prevCategoryID string
+	var mentioned []*User
+	var subscribers []string
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
 		var err error
 		topic, err = findTopic(ctx, tx, id)
 		if err != nil || topic == nil {
 			return err
 		}
-		if !topic.isPermit(user) {
+		if can, err := topic.isPermit(ctx, tx, user); err != nil {
+			return err
+		} else if !can {
 			return session.ForbiddenError(ctx)
 		}
 		if draft && !topic.Draft {
 			return session.ForbiddenError(ctx)
 		}
+		prev := *topic
 		topic.Draft = draft
 
 		topic.Title = title
@@ -169,6 +188,13 @@ func (user *User) UpdateTopic(ctx context.Context, id, title, body, typ, categor
 			} else if category == nil {
 				return session.BadDataError(ctx)
 			}
+			if topic.UserID != user.UserID {
+				if can, err := user.canModerateCategory(ctx, tx, category.CategoryID, PermissionTopicEditAny); err != nil {
+					return err
+				} else if !can {
+					return session.ForbiddenError(ctx)
+				}
+			}
 			topic.CategoryID = category.CategoryID
 		}
 		topic.TopicType = typ
@@ -176,7 +202,20 @@ func (user *User) UpdateTopic(ctx context.Context, id, title, body, typ, categor
 		cols, params := durable.PrepareColumnsAndExpressions([]string{"title", "body", "category_id", "draft", "updated_at"}, 1)
 		values := []interface{}{topic.TopicID, topic.Title, topic.Body, topic.CategoryID, topic.Draft, topic.UpdatedAt}
 		_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE topics SET (%s)=(%s) WHERE topic_id=$1", cols, params), values...)
-		return err
+		if err != nil || topic.Draft {
+			return err
+		}
+		topic.Mentions, mentioned, err = syncMentions(ctx, tx, topic.TopicID, "", topic.Body)
+		if err != nil {
+			return err
+		}
+		if prev.Draft {
+			subscribers, err = publishTopic(ctx, tx, topic)
+			if err != nil {
+				return err
+			}
+		}
+		return recordTopicRevision(ctx, tx, &prev, topic, user)
 	})
 	if err != nil {
 		return nil, session.TransactionError(ctx, err)
@@ -190,6 +229,8 @@ func (user *User) UpdateTopic(ctx context.Context, id, title, body, typ, categor
 		if prevCategoryID != "" {
 			EmitToCategory(ctx, prevCategoryID)
 		}
+		notifyMentions(ctx, mentioned, user, topic.TopicID, "")
+		notifySubscribers(ctx, subscribers, mentioned, user, topic.TopicID)
 	}
 	return topic, nil
 }
@@ -234,20 +275,29 @@ func findTopic(ctx context.Context, tx pgx.Tx, id string) (*Topic, error) {
 	return t, err
 }
 
+// Delete a topic, authors can only delete their drafts
 func (topic *Topic) Delete(ctx context.Context, user *User) error {
 	if user == nil {
 		return session.ForbiddenError(ctx)
 	}
-	if !(user.isAdmin() || (topic.Draft && topic.UserID == user.UserID)) {
-		return session.ForbiddenError(ctx)
-	}
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
 		topic, err := findTopic(ctx, tx, topic.TopicID)
 		if err != nil || topic == nil {
 			return err
 		}
+		if !topic.Draft || topic.UserID != user.UserID {
+			if can, err := user.canModerateCategory(ctx, tx, topic.CategoryID, PermissionTopicDelete); err != nil {
+				return err
+			} else if !can {
+				return session.ForbiddenError(ctx)
+			}
+		}
 
 		_, err = tx.Exec(ctx, "DELETE FROM topics WHERE topic_id=$1", topic.TopicID)
+		if err != nil || topic.Draft {
+			return err
+		}
+		_, err = emitToCategory(ctx, tx, topic.CategoryID)
 		return err
 	})
 	if err != nil {
@@ -295,55 +345,65 @@ func ReadTopics(ctx context.Context, offset time.Time, category *Category, user
 
 	var topics []*Topic
 	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
-		rows, err := tx.Query(ctx, query, params...)
+		var err error
+		topics, err = queryTopics(ctx, tx, category, user, query, params...)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return topics, nil
+}
+
+// queryTopics read the topics with their users and categories, which are the
+// given ones if not nil
+func queryTopics(ctx context.Context, tx pgx.Tx, category *Category, user *User, query string, params ...any) ([]*Topic, error) {
+	rows, err := tx.Query(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var topics []*Topic
+	var userIDs, categoryIDs []string
+	for rows.Next() {
+		topic, err := topicFromRows(rows)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		defer rows.Close()
-
-		var userIDs, categoryIDs []string
-		for rows.Next() {
-			topic, err := topicFromRows(rows)
-			if err != nil {
-				return err
-			}
-			topic.Category = category
-			topic.User = user
-			if topic.Category == nil {
-				categoryIDs = append(categoryIDs, topic.CategoryID)
-			}
-			if topic.User == nil {
-				userIDs = append(userIDs, topic.UserID)
-			}
-			topics = append(topics, topic)
+		topic.Category = category
+		topic.User = user
+		if topic.Category == nil {
+			categoryIDs = append(categoryIDs, topic.CategoryID)
 		}
-		if rows.Err() != nil {
-			return rows.Err()
+		if topic.User == nil {
+			userIDs = append(userIDs, topic.UserID)
 		}
-		if len(userIDs) > 0 {
-			userSet, err := readUserSet(ctx, tx, userIDs)
-			if err != nil {
-				return err
-			}
-			for i, topic := range topics {
-				topics[i].User = userSet[topic.UserID]
-			}
+		topics = append(topics, topic)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	rows.Close()
+	if len(userIDs) > 0 {
+		userSet, err := readUserSet(ctx, tx, userIDs)
+		if err != nil {
+			return nil, err
 		}
-		if len(categoryIDs) > 0 {
-			categorySet, err := readCategorySet(ctx, tx, categoryIDs)
-			if err != nil {
-				return err
-			}
-			for i, topic := range topics {
-				topics[i].Category = categorySet[topic.CategoryID]
-			}
+		for i, topic := range topics {
+			topics[i].User = userSet[topic.UserID]
 		}
-		return nil
-	})
-	if err != nil {
-		return nil, session.TransactionError(ctx, err)
 	}
-	return topics, nil
+	if len(categoryIDs) > 0 {
+		categorySet, err := readCategorySet(ctx, tx, categoryIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i, topic := range topics {
+			topics[i].Category = categorySet[topic.CategoryID]
+		}
+	}
+	return topics, fillTopicMentions(ctx, tx, topics)
 }
 
 func (category *Category) latestTopic(ctx context.Context, tx pgx.Tx) (*Topic, error) {
@@ -367,6 +427,9 @@ func (topic *Topic) FillOut(ctx context.Context, user *User) error {
 			return err
 		}
 		topic.Category = category
+		if err := fillTopicMentions(ctx, tx, []*Topic{topic}); err != nil {
+			return err
+		}
 		if user != nil {
 			tu, err := findTopicUser(ctx, tx, topic.TopicID, user.UserID)
 			if err != nil || tu == nil {
@@ -403,12 +466,30 @@ func fetchTopicsCount(ctx context.Context, tx pgx.Tx, categoryID string) (int64,
 	return count, err
 }
 
-func (topic *Topic) isPermit(user *User) bool {
+func (topic *Topic) isPermit(ctx context.Context, tx pgx.Tx, user *User) (bool, error) {
 	if user == nil {
-		return false
+		return false, nil
 	}
-	if user.isAdmin() {
-		return true
+	if topic.UserID == user.UserID {
+		return true, nil
 	}
-	return topic.UserID == user.UserID
+	return user.canModerateCategory(ctx, tx, topic.CategoryID, PermissionTopicEditAny)
+}
+
+// Lock a topic to stop new comments, only for moderators
+func (topic *Topic) Lock(ctx context.Context, user *User, locked bool) error {
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		if can, err := user.canModerateCategory(ctx, tx, topic.CategoryID, PermissionTopicEditAny); err != nil {
+			return err
+		} else if !can {
+			return session.ForbiddenError(ctx)
+		}
+		topic.Locked = locked
+		_, err := tx.Exec(ctx, "UPDATE topics SET locked=$1 WHERE topic_id=$2", topic.Locked, topic.TopicID)
+		return err
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
 }
@@ -0,0 +1,267 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"satellity/internal/durable"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Topics are ordered by created_at (new), by the time decayed score (hot), or
+// by the points in a period (top)
+const (
+	TopicOrderNew = "new"
+	TopicOrderHot = "hot"
+	TopicOrderTop = "top"
+
+	TopicPeriodDay   = "day"
+	TopicPeriodWeek  = "week"
+	TopicPeriodMonth = "month"
+	TopicPeriodYear  = "year"
+	TopicPeriodAll   = "all"
+)
+
+// The points of a topic are the weighted sum of its likes, comments, views and
+// bookmarks, the hot score is points / (hours + 2) ^ gravity. Topics older than
+// TopicRankingWindow have no hot score, the scores are recomputed every
+// TopicRankingInterval.
+const (
+	RankingWeightLikes     = "likes"
+	RankingWeightComments  = "comments"
+	RankingWeightViews     = "views"
+	RankingWeightBookmarks = "bookmarks"
+	RankingWeightGravity   = "gravity"
+
+	TopicRankingInterval = 10 * time.Minute
+	TopicRankingWindow   = 30 * 24 * time.Hour
+
+	topicRankingBatch   = 500
+	topicRankingLockKey = 0x5a7e11001
+)
+
+// RankingWeights is all known weights, in display order
+var RankingWeights = []string{
+	RankingWeightLikes,
+	RankingWeightComments,
+	RankingWeightViews,
+	RankingWeightBookmarks,
+	RankingWeightGravity,
+}
+
+var topicPeriods = map[string]time.Duration{
+	TopicPeriodDay:   24 * time.Hour,
+	TopicPeriodWeek:  7 * 24 * time.Hour,
+	TopicPeriodMonth: 30 * 24 * time.Hour,
+	TopicPeriodYear:  365 * 24 * time.Hour,
+	TopicPeriodAll:   0,
+}
+
+// RankingWeight is a tunable weight of the topic ranking
+type RankingWeight struct {
+	Name      string
+	Weight    float64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+var rankingWeightColumns = []string{"name", "weight", "created_at", "updated_at"}
+
+func rankingWeightFromRow(row durable.Row) (*RankingWeight, error) {
+	var w RankingWeight
+	err := row.Scan(&w.Name, &w.Weight, &w.CreatedAt, &w.UpdatedAt)
+	return &w, err
+}
+
+// ReadRankingWeights read all ranking weights
+func ReadRankingWeights(ctx context.Context) ([]*RankingWeight, error) {
+	var weights []*RankingWeight
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		weights, err = readRankingWeights(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return weights, nil
+}
+
+// UpdateRankingWeights change the weights by name, the others are kept. Weights
+// can't be negative and gravity must be positive, hot scores change with the
+// next recompute.
+func UpdateRankingWeights(ctx context.Context, weights map[string]float64, operator *User) ([]*RankingWeight, error) {
+	if can, err := operator.Can(ctx, PermissionCategoryManage); err != nil {
+		return nil, err
+	} else if !can {
+		return nil, session.ForbiddenError(ctx)
+	}
+	for name, weight := range weights {
+		if !isRankingWeight(name) || weight < 0 || (name == RankingWeightGravity && weight == 0) {
+			return nil, session.BadDataErrorWithFieldAndData(ctx, name, "invalid", fmt.Sprint(weight))
+		}
+	}
+	var result []*RankingWeight
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		t := time.Now()
+		for name, weight := range weights {
+			_, err := tx.Exec(ctx, "INSERT INTO ranking_weights (name,weight,created_at,updated_at) VALUES ($1,$2,$3,$3) ON CONFLICT (name) DO UPDATE SET (weight,updated_at)=($2,$3)", name, weight, t)
+			if err != nil {
+				return err
+			}
+		}
+		var err error
+		result, err = readRankingWeights(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return result, nil
+}
+
+// RankTopics recompute the hot scores of the topics in TopicRankingWindow, and
+// clear the scores of the older ones. Only one instance ranks at a time, in
+// short read committed batches, so it doesn't fail the transactions of users
+// on the topics.
+func RankTopics(ctx context.Context) error {
+	_, err := session.Database(ctx).RunWithAdvisoryLock(ctx, topicRankingLockKey, func() error {
+		return rankTopics(ctx)
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+func rankTopics(ctx context.Context) error {
+	db := session.Database(ctx)
+	var set map[string]float64
+	err := db.RunInReadCommittedTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		set, err = readRankingWeightSet(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	t := time.Now()
+	query := fmt.Sprintf("UPDATE topics SET score=CASE WHEN created_at>$1 THEN (%s)/power(extract(epoch FROM $2::timestamptz-created_at)/3600+2,$7::float8) ELSE 0 END WHERE topic_id=ANY($8)", topicPointsExpression(3))
+	var last string
+	for {
+		var ids []string
+		err := db.RunInReadCommittedTransaction(ctx, func(tx pgx.Tx) error {
+			var err error
+			ids, err = queryIDs(ctx, tx, "SELECT topic_id FROM topics WHERE topic_id>$1 AND draft=false AND (created_at>$2 OR score<>0) ORDER BY topic_id LIMIT $3", last, t.Add(-TopicRankingWindow), topicRankingBatch)
+			if err != nil || len(ids) == 0 {
+				return err
+			}
+			_, err = tx.Exec(ctx, query, t.Add(-TopicRankingWindow), t, set[RankingWeightLikes], set[RankingWeightComments], set[RankingWeightViews], set[RankingWeightBookmarks], set[RankingWeightGravity], ids)
+			return err
+		})
+		if err != nil || len(ids) < topicRankingBatch {
+			return err
+		}
+		last = ids[len(ids)-1]
+	}
+}
+
+// ReadRankedTopics read the published topics by the order hot or top, created
+// in the period, parameters: offset is the count of topics to skip
+func ReadRankedTopics(ctx context.Context, order, period string, offset int) ([]*Topic, error) {
+	if period == "" {
+		period = TopicPeriodAll
+	}
+	duration, ok := topicPeriods[period]
+	if !ok {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "period", "invalid", period)
+	}
+	if order != TopicOrderHot && order != TopicOrderTop {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "order", "invalid", order)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var since time.Time
+	if duration > 0 {
+		since = time.Now().Add(-duration)
+	}
+
+	var topics []*Topic
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		query := fmt.Sprintf("SELECT %s FROM topics WHERE draft=false AND created_at>$1 ORDER BY score DESC,created_at DESC LIMIT $2 OFFSET $3", strings.Join(topicColumns, ","))
+		params := []any{since, LIMIT, offset}
+		if order == TopicOrderTop {
+			set, err := readRankingWeightSet(ctx, tx)
+			if err != nil {
+				return err
+			}
+			query = fmt.Sprintf("SELECT %s FROM topics WHERE draft=false AND created_at>$1 ORDER BY %s DESC,created_at DESC LIMIT $2 OFFSET $3", strings.Join(topicColumns, ","), topicPointsExpression(4))
+			params = append(params, set[RankingWeightLikes], set[RankingWeightComments], set[RankingWeightViews], set[RankingWeightBookmarks])
+		}
+		var err error
+		topics, err = queryTopics(ctx, tx, nil, nil, query, params...)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return topics, nil
+}
+
+// topicPointsExpression is the weighted sum of the counters, the weights of
+// likes, comments, views and bookmarks are the parameters from $offset
+func topicPointsExpression(offset int) string {
+	return fmt.Sprintf("likes_count*$%d::float8+comments_count*$%d::float8+views_count*$%d::float8+bookmarks_count*$%d::float8", offset, offset+1, offset+2, offset+3)
+}
+
+func readRankingWeights(ctx context.Context, tx pgx.Tx) ([]*RankingWeight, error) {
+	rows, err := tx.Query(ctx, fmt.Sprintf("SELECT %s FROM ranking_weights", strings.Join(rankingWeightColumns, ",")))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	set := make(map[string]*RankingWeight)
+	for rows.Next() {
+		w, err := rankingWeightFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		set[w.Name] = w
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var weights []*RankingWeight
+	for _, name := range RankingWeights {
+		if w := set[name]; w != nil {
+			weights = append(weights, w)
+		}
+	}
+	return weights, nil
+}
+
+func readRankingWeightSet(ctx context.Context, tx pgx.Tx) (map[string]float64, error) {
+	weights, err := readRankingWeights(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]float64)
+	for _, w := range weights {
+		set[w.Name] = w.Weight
+	}
+	return set, nil
+}
+
+func isRankingWeight(name string) bool {
+	for _, n := range RankingWeights {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
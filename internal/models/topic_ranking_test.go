@@ -0,0 +1,72 @@
+package models
+
+import (
+	"satellity/internal/configs"
+	"satellity/internal/session"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicRanking(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	operator := createTestUser(ctx, "operator@gmail.com", "operator", "password")
+	assert.NotNil(operator)
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+	quiet, err := user.CreateTopic(ctx, "quiet title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	liked, err := user.CreateTopic(ctx, "liked title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	_, err = liked.ActiondBy(ctx, operator, TopicUserActionLiked, true)
+	assert.Nil(err)
+	_, err = user.CreateTopic(ctx, "draft title", "body", TopicTypePost, category.CategoryID, true)
+	assert.Nil(err)
+
+	weights, err := ReadRankingWeights(ctx)
+	assert.Nil(err)
+	assert.Len(weights, len(RankingWeights))
+	_, err = UpdateRankingWeights(ctx, map[string]float64{RankingWeightLikes: 3}, operator)
+	assert.NotNil(err)
+	configs.AppConfig.OperatorSet["operator@gmail.com"] = true
+	defer delete(configs.AppConfig.OperatorSet, "operator@gmail.com")
+	_, err = UpdateRankingWeights(ctx, map[string]float64{"unknown": 3}, operator)
+	assert.NotNil(err)
+	_, err = UpdateRankingWeights(ctx, map[string]float64{RankingWeightGravity: 0}, operator)
+	assert.NotNil(err)
+	weights, err = UpdateRankingWeights(ctx, map[string]float64{RankingWeightLikes: 3}, operator)
+	assert.Nil(err)
+	assert.Equal(RankingWeightLikes, weights[0].Name)
+	assert.Equal(float64(3), weights[0].Weight)
+
+	locked, err := session.Database(ctx).RunWithAdvisoryLock(ctx, topicRankingLockKey, func() error {
+		return RankTopics(ctx)
+	})
+	assert.Nil(err)
+	assert.True(locked)
+	liked, err = ReadTopic(ctx, liked.TopicID)
+	assert.Nil(err)
+	assert.Equal(float64(0), liked.Score)
+	assert.Nil(RankTopics(ctx))
+	liked, err = ReadTopic(ctx, liked.TopicID)
+	assert.Nil(err)
+	assert.Greater(liked.Score, float64(0))
+	topics, err := ReadRankedTopics(ctx, TopicOrderHot, "", 0)
+	assert.Nil(err)
+	assert.Len(topics, 2)
+	assert.Equal(liked.TopicID, topics[0].TopicID)
+	assert.NotNil(topics[0].User)
+	topics, err = ReadRankedTopics(ctx, TopicOrderTop, TopicPeriodWeek, 1)
+	assert.Nil(err)
+	assert.Len(topics, 1)
+	assert.Equal(quiet.TopicID, topics[0].TopicID)
+	_, err = ReadRankedTopics(ctx, TopicOrderTop, "decade", 0)
+	assert.NotNil(err)
+	_, err = ReadRankedTopics(ctx, "random", TopicPeriodWeek, 0)
+	assert.NotNil(err)
+}
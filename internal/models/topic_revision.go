@@ -0,0 +1,208 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"satellity/internal/durable"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// TopicRevision is a published version of a topic, version 1 is the original
+type TopicRevision struct {
+	RevisionID string
+	TopicID    string
+	UserID     sql.NullString
+	Version    int64
+	Title      string
+	Body       string
+	CategoryID string
+	CreatedAt  time.Time
+
+	User               *User
+	PreviousCategoryID string
+	TitleDiff          string
+	BodyDiff           string
+}
+
+var topicRevisionColumns = []string{"revision_id", "topic_id", "user_id", "version", "title", "body", "category_id", "created_at"}
+
+func (r *TopicRevision) values() []interface{} {
+	return []interface{}{r.RevisionID, r.TopicID, r.UserID, r.Version, r.Title, r.Body, r.CategoryID, r.CreatedAt}
+}
+
+func topicRevisionFromRows(row durable.Row) (*TopicRevision, error) {
+	var r TopicRevision
+	err := row.Scan(&r.RevisionID, &r.TopicID, &r.UserID, &r.Version, &r.Title, &r.Body, &r.CategoryID, &r.CreatedAt)
+	return &r, err
+}
+
+// ReadRevisions read the revisions of a published topic, each one with the diffs
+// to the previous revision
+func (topic *Topic) ReadRevisions(ctx context.Context) ([]*TopicRevision, error) {
+	if topic.Draft {
+		return nil, nil
+	}
+	var revisions []*TopicRevision
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		revisions, err = readTopicRevisions(ctx, tx, topic.TopicID)
+		if err != nil {
+			return err
+		}
+		var userIDs []string
+		for _, r := range revisions {
+			if r.UserID.Valid {
+				userIDs = append(userIDs, r.UserID.String)
+			}
+		}
+		set, err := readUserSet(ctx, tx, userIDs)
+		if err != nil {
+			return err
+		}
+		for _, r := range revisions {
+			r.User = set[r.UserID.String]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	for i, r := range revisions {
+		var prev TopicRevision
+		if i > 0 {
+			prev = *revisions[i-1]
+		}
+		if i > 0 && prev.CategoryID != r.CategoryID {
+			r.PreviousCategoryID = prev.CategoryID
+		}
+		r.TitleDiff, err = revisionDiff(prev.Title, r.Title, prev.Version, r.Version)
+		if err != nil {
+			return nil, session.ServerError(ctx, err)
+		}
+		r.BodyDiff, err = revisionDiff(prev.Body, r.Body, prev.Version, r.Version)
+		if err != nil {
+			return nil, session.ServerError(ctx, err)
+		}
+	}
+	return revisions, nil
+}
+
+// Revert the topic to the title, body and category of a revision, which is
+// recorded as a new revision
+func (topic *Topic) Revert(ctx context.Context, revisionID string, user *User) (*Topic, error) {
+	var revision *TopicRevision
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		revision, err = findTopicRevision(ctx, tx, revisionID)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	if revision == nil || revision.TopicID != topic.TopicID {
+		return nil, session.NotFoundError(ctx)
+	}
+	return user.UpdateTopic(ctx, topic.TopicID, revision.Title, revision.Body, topic.TopicType, revision.CategoryID, false)
+}
+
+// recordTopicRevision append the published topic as a new revision if it differs
+// from the last one. The original is recorded first if the topic has no revisions.
+func recordTopicRevision(ctx context.Context, tx pgx.Tx, prev, topic *Topic, user *User) error {
+	last, err := lastTopicRevision(ctx, tx, topic.TopicID)
+	if err != nil {
+		return err
+	}
+	var rows [][]interface{}
+	if last == nil && !prev.Draft {
+		last = &TopicRevision{
+			RevisionID: uuid.Must(uuid.NewV4()).String(),
+			TopicID:    prev.TopicID,
+			UserID:     sql.NullString{String: prev.UserID, Valid: true},
+			Version:    1,
+			Title:      prev.Title,
+			Body:       prev.Body,
+			CategoryID: prev.CategoryID,
+			CreatedAt:  prev.UpdatedAt,
+		}
+		rows = append(rows, last.values())
+	}
+	r := &TopicRevision{
+		RevisionID: uuid.Must(uuid.NewV4()).String(),
+		TopicID:    topic.TopicID,
+		UserID:     sql.NullString{String: user.UserID, Valid: true},
+		Version:    1,
+		Title:      topic.Title,
+		Body:       topic.Body,
+		CategoryID: topic.CategoryID,
+		CreatedAt:  topic.UpdatedAt,
+	}
+	if last != nil {
+		if last.Title == r.Title && last.Body == r.Body && last.CategoryID == r.CategoryID {
+			return nil
+		}
+		r.Version = last.Version + 1
+	}
+	rows = append(rows, r.values())
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"topic_revisions"}, topicRevisionColumns, pgx.CopyFromRows(rows))
+	return err
+}
+
+func readTopicRevisions(ctx context.Context, tx pgx.Tx, topicID string) ([]*TopicRevision, error) {
+	rows, err := tx.Query(ctx, fmt.Sprintf("SELECT %s FROM topic_revisions WHERE topic_id=$1 ORDER BY topic_id,version", strings.Join(topicRevisionColumns, ",")), topicID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*TopicRevision
+	for rows.Next() {
+		r, err := topicRevisionFromRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+func lastTopicRevision(ctx context.Context, tx pgx.Tx, topicID string) (*TopicRevision, error) {
+	row := tx.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM topic_revisions WHERE topic_id=$1 ORDER BY topic_id,version DESC LIMIT 1", strings.Join(topicRevisionColumns, ",")), topicID)
+	r, err := topicRevisionFromRows(row)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+func findTopicRevision(ctx context.Context, tx pgx.Tx, id string) (*TopicRevision, error) {
+	if uuid.FromStringOrNil(id).String() != id {
+		return nil, nil
+	}
+	row := tx.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM topic_revisions WHERE revision_id=$1", strings.Join(topicRevisionColumns, ",")), id)
+	r, err := topicRevisionFromRows(row)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// revisionDiff is a unified diff by lines, empty if nothing changed
+func revisionDiff(a, b string, from, to int64) (string, error) {
+	if a == b {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a),
+		B:        difflib.SplitLines(b),
+		FromFile: fmt.Sprintf("v%d", from),
+		ToFile:   fmt.Sprintf("v%d", to),
+		Context:  3,
+	})
+}
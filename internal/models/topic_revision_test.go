@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicRevision(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	other := createTestUser(ctx, "other@gmail.com", "other", "password")
+	assert.NotNil(other)
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+	topic, err := user.CreateTopic(ctx, "title", "body", TopicTypePost, category.CategoryID, true)
+	assert.Nil(err)
+	topic, err = user.UpdateTopic(ctx, topic.TopicID, "draft title", "draft body", TopicTypePost, category.CategoryID, true)
+	assert.Nil(err)
+	revisions, err := topic.ReadRevisions(ctx)
+	assert.Nil(err)
+	assert.Len(revisions, 0)
+
+	topic, err = user.UpdateTopic(ctx, topic.TopicID, "published title", "line 1\nline 2", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	revisions, err = topic.ReadRevisions(ctx)
+	assert.Nil(err)
+	assert.Len(revisions, 1)
+	assert.Equal(int64(1), revisions[0].Version)
+	assert.Equal("published title", revisions[0].Title)
+
+	topic, err = user.UpdateTopic(ctx, topic.TopicID, "published title", "line 1\nline 2", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	topic, err = user.UpdateTopic(ctx, topic.TopicID, "edited title", "line 1\nline 3", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	revisions, err = topic.ReadRevisions(ctx)
+	assert.Nil(err)
+	assert.Len(revisions, 2)
+	assert.Equal(int64(2), revisions[1].Version)
+	assert.Equal(user.UserID, revisions[1].User.UserID)
+	assert.Contains(revisions[1].TitleDiff, "-published title")
+	assert.Contains(revisions[1].TitleDiff, "+edited title")
+	assert.Contains(revisions[1].BodyDiff, "-line 2")
+	assert.Contains(revisions[1].BodyDiff, "+line 3")
+	assert.NotContains(revisions[1].BodyDiff, "-line 1")
+
+	_, err = topic.Revert(ctx, revisions[0].RevisionID, other)
+	assert.NotNil(err)
+	_, err = topic.Revert(ctx, "invalid", user)
+	assert.NotNil(err)
+	topic, err = topic.Revert(ctx, revisions[0].RevisionID, user)
+	assert.Nil(err)
+	assert.Equal("published title", topic.Title)
+	assert.Equal("line 1\nline 2", topic.Body)
+	revisions, err = topic.ReadRevisions(ctx)
+	assert.Nil(err)
+	assert.Len(revisions, 3)
+	assert.Equal(revisions[0].Body, revisions[2].Body)
+}
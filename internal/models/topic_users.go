@@ -24,21 +24,22 @@ type TopicUser struct {
 	UserID       string
 	LikedAt      sql.NullTime
 	BookmarkedAt sql.NullTime
+	Subscription string
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 
 	isNew bool
 }
 
-var topicUserColumns = []string{"topic_id", "user_id", "liked_at", "bookmarked_at", "created_at", "updated_at"}
+var topicUserColumns = []string{"topic_id", "user_id", "liked_at", "bookmarked_at", "subscription", "created_at", "updated_at"}
 
 func (tu *TopicUser) values() []interface{} {
-	return []interface{}{tu.TopicID, tu.UserID, tu.LikedAt, tu.BookmarkedAt, tu.CreatedAt, tu.UpdatedAt}
+	return []interface{}{tu.TopicID, tu.UserID, tu.LikedAt, tu.BookmarkedAt, tu.Subscription, tu.CreatedAt, tu.UpdatedAt}
 }
 
 func topicUserFromRow(row durable.Row) (*TopicUser, error) {
 	var tu TopicUser
-	err := row.Scan(&tu.TopicID, &tu.UserID, &tu.LikedAt, &tu.BookmarkedAt, &tu.CreatedAt, &tu.UpdatedAt)
+	err := row.Scan(&tu.TopicID, &tu.UserID, &tu.LikedAt, &tu.BookmarkedAt, &tu.Subscription, &tu.CreatedAt, &tu.UpdatedAt)
 	return &tu, err
 }
 
@@ -63,6 +64,7 @@ func (topic *Topic) ActiondBy(ctx context.Context, user *User, action string, st
 			isNew: true,
 		}
 	}
+	liked := tu.LikedAt.Valid
 	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
 		var lcount, bcount int64
 		if action == TopicUserActionLiked {
@@ -112,6 +114,9 @@ func (topic *Topic) ActiondBy(ctx context.Context, user *User, action string, st
 	if err != nil {
 		return topic, session.TransactionError(ctx, err)
 	}
+	if action == TopicUserActionLiked && state && !liked {
+		notify(ctx, NotificationTypeLiked, topic.UserID, user, topic.TopicID, "", topic.TopicID)
+	}
 	return topic, nil
 }
 
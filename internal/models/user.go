@@ -15,7 +15,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gofrs/uuid"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/jackc/pgx/v4"
@@ -24,38 +23,46 @@ import (
 
 // Constants for user
 const (
-	UserRoleAdmin  = "admin"
-	UserRoleMember = "member"
+	UserRoleAdmin     = "admin"
+	UserRoleModerator = "moderator"
+	UserRoleMember    = "member"
+	UserRoleReadOnly  = "read_only"
 )
 
 // User contains info of a register user
 type User struct {
-	UserID            string
-	PublicKey         sql.NullString
-	Email             sql.NullString
-	Username          sql.NullString
-	Nickname          string
-	AvatarURL         string
-	Biography         string
-	EncryptedPassword sql.NullString
-	GithubID          sql.NullString
-	Role              string
-	CreatedAt         time.Time
-	UpdatedAt         time.Time
-
-	SessionID string
-	isNew     bool
+	UserID                string
+	PublicKey             sql.NullString
+	Email                 sql.NullString
+	Username              sql.NullString
+	Nickname              string
+	AvatarURL             string
+	Biography             string
+	EncryptedPassword     sql.NullString
+	PasswordResetRequired bool
+	Role                  string
+	SuspendedAt           sql.NullTime
+	SuspendedUntil        sql.NullTime
+	SuspensionReason      string
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+
+	SessionID                string
+	APIToken                 *APIToken
+	UnreadNotificationsCount int64
+	isNew                    bool
+	role                     *Role
 }
 
-var userColumns = []string{"user_id", "public_key", "email", "username", "nickname", "avatar_url", "biography", "encrypted_password", "github_id", "role", "created_at", "updated_at"}
+var userColumns = []string{"user_id", "public_key", "email", "username", "nickname", "avatar_url", "biography", "encrypted_password", "password_reset_required", "role", "suspended_at", "suspended_until", "suspension_reason", "created_at", "updated_at"}
 
 func (u *User) values() []interface{} {
-	return []interface{}{u.UserID, u.PublicKey, u.Email, u.Username, u.Nickname, u.AvatarURL, u.Biography, u.EncryptedPassword, u.GithubID, u.Role, u.CreatedAt, u.UpdatedAt}
+	return []interface{}{u.UserID, u.PublicKey, u.Email, u.Username, u.Nickname, u.AvatarURL, u.Biography, u.EncryptedPassword, u.PasswordResetRequired, u.Role, u.SuspendedAt, u.SuspendedUntil, u.SuspensionReason, u.CreatedAt, u.UpdatedAt}
 }
 
 func userFromRow(row durable.Row) (*User, error) {
 	var u User
-	err := row.Scan(&u.UserID, &u.PublicKey, &u.Email, &u.Username, &u.Nickname, &u.AvatarURL, &u.Biography, &u.EncryptedPassword, &u.GithubID, &u.Role, &u.CreatedAt, &u.UpdatedAt)
+	err := row.Scan(&u.UserID, &u.PublicKey, &u.Email, &u.Username, &u.Nickname, &u.AvatarURL, &u.Biography, &u.EncryptedPassword, &u.PasswordResetRequired, &u.Role, &u.SuspendedAt, &u.SuspendedUntil, &u.SuspensionReason, &u.CreatedAt, &u.UpdatedAt)
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
@@ -83,7 +90,7 @@ func CreateUser(ctx context.Context, email, username, nickname, biography, passw
 
 	var user *User
 	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
-		user, err = createUser(ctx, tx, "", email, username, nickname, password, sessionPub, "", nil)
+		user, err = createUser(ctx, tx, "", email, username, nickname, password, sessionPub, nil)
 		return err
 	})
 	if err != nil {
@@ -93,51 +100,6 @@ func CreateUser(ctx context.Context, email, username, nickname, biography, passw
 	return user, nil
 }
 
-func CreateWeb3User(ctx context.Context, nickname, publicKey, sessionPub, sig string) (*User, error) {
-	sigBuf, _ := hex.DecodeString(sig)
-	data := fmt.Sprintf("Satellity:%s:%s:%s", nickname, publicKey, sessionPub)
-	data = "0x" + hex.EncodeToString(crypto.Keccak256Hash([]byte(data)).Bytes())
-	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(data), data)
-	hash := crypto.Keccak256Hash([]byte(msg))
-	sigPublicKey, err := crypto.Ecrecover(hash.Bytes(), sigBuf)
-	if err != nil {
-		return nil, session.BadDataErrorWithFieldAndData(ctx, "signature", "invalid", sig)
-	}
-	pubKey, err := crypto.UnmarshalPubkey(sigPublicKey)
-	if err != nil {
-		return nil, session.BadDataErrorWithFieldAndData(ctx, "signature", "invalid", sig)
-	}
-	address := crypto.PubkeyToAddress(*pubKey)
-	if publicKey != address.Hex() {
-		return nil, session.BadDataErrorWithFieldAndData(ctx, "signature", "invalid", sig)
-	}
-
-	nickname = strings.TrimSpace(nickname)
-	if nickname == "" {
-		nickname = publicKey
-	}
-	var user *User
-	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
-		old, err := findUserByPublicKey(ctx, tx, publicKey)
-		if err != nil {
-			return err
-		}
-		if old != nil {
-			user = old
-			return nil
-		}
-		user, err = createUser(ctx, tx, publicKey, "", "", nickname, "", sessionPub, "", nil)
-		return err
-	})
-	if err != nil {
-		return nil, session.TransactionError(ctx, err)
-	}
-	if user.isNew {
-		UpsertStatistic(ctx, StatisticTypeUsers)
-	}
-	return user, nil
-}
-
 // UpdateProfile update user's profile
 func (u *User) UpdateProfile(ctx context.Context, nickname, biography string, avatar string) error {
 	nickname, biography = strings.TrimSpace(nickname), strings.TrimSpace(biography)
@@ -205,8 +167,12 @@ func AuthenticateUser(ctx context.Context, tokenString string) (*User, error) {
 			} else if s == nil {
 				return nil
 			}
+			if s.ExpiresAt.Before(time.Now()) {
+				s = nil
+				return nil
+			}
 			user.SessionID = s.SessionID
-			return nil
+			return s.touch(ctx, tx)
 		})
 		if err != nil {
 			return nil, session.TransactionError(ctx, err)
@@ -224,6 +190,9 @@ func AuthenticateUser(ctx context.Context, tokenString string) (*User, error) {
 		log.Println("err:::", err, token.Valid)
 		return nil, nil
 	}
+	if user.IsSuspended() {
+		return nil, session.UserSuspendedError(ctx, user.SuspendedUntil.Time, user.SuspensionReason)
+	}
 	return user, nil
 }
 
@@ -343,7 +312,7 @@ func (u *User) GetAvatar() string {
 	return fmt.Sprintf("https://www.gravatar.com/avatar/%x?s=180&d=wavatar", md5.Sum([]byte(strings.ToLower(u.Email.String))))
 }
 
-// Role of an user, contains admin and member for now.
+// GetRole is the name of the user's role, operators in the config are always admin
 func (u *User) GetRole() string {
 	if configs.AppConfig.OperatorSet[u.Email.String] {
 		return UserRoleAdmin
@@ -362,10 +331,6 @@ func (u *User) Name() string {
 	return u.Username.String
 }
 
-func (u *User) isAdmin() bool {
-	return u.GetRole() == UserRoleAdmin
-}
-
 func findUserByID(ctx context.Context, tx pgx.Tx, id string) (*User, error) {
 	if _, err := uuid.FromString(id); err != nil {
 		return nil, nil
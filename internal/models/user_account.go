@@ -0,0 +1,258 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Account deletion modes
+const (
+	AccountDeletionAnonymize = "anonymize"
+	AccountDeletionHard      = "delete"
+)
+
+// GhostUserID owns the topics and comments of anonymized accounts
+const GhostUserID = "00000000-0000-0000-0000-000000000000"
+
+// UserArchive is the personal data export of a user
+type UserArchive struct {
+	User         *User
+	Topics       []*Topic
+	Comments     []*Comment
+	TopicUsers   []*TopicUser
+	CommentUsers []*CommentUser
+	CreatedAt    time.Time
+}
+
+// Export read the profile, topics including drafts, comments, likes, bookmarks and votes of the user
+func (user *User) Export(ctx context.Context) (*UserArchive, error) {
+	archive := &UserArchive{User: user, CreatedAt: time.Now()}
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, fmt.Sprintf("SELECT %s FROM topics WHERE user_id=$1 ORDER BY user_id,draft,created_at", strings.Join(topicColumns, ",")), user.UserID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			topic, err := topicFromRows(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			archive.Topics = append(archive.Topics, topic)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(ctx, fmt.Sprintf("SELECT %s FROM comments WHERE user_id=$1 ORDER BY user_id,created_at", strings.Join(commentColumns, ",")), user.UserID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			comment, err := commentFromRows(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			archive.Comments = append(archive.Comments, comment)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(ctx, fmt.Sprintf("SELECT %s FROM topic_users WHERE user_id=$1 ORDER BY created_at", strings.Join(topicUserColumns, ",")), user.UserID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			tu, err := topicUserFromRow(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			archive.TopicUsers = append(archive.TopicUsers, tu)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(ctx, fmt.Sprintf("SELECT %s FROM comment_users WHERE user_id=$1 ORDER BY user_id,comment_id", strings.Join(commentUserColumns, ",")), user.UserID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			cu, err := commentUserFromRow(rows)
+			if err != nil {
+				return err
+			}
+			archive.CommentUsers = append(archive.CommentUsers, cu)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return archive, nil
+}
+
+// DeleteAccount delete the user, published topics and comments are moved to the
+// ghost user when anonymize, or deleted with the user otherwise, except the
+// comments replied by others which are kept as tombstones. Password is
+// required if the user has one, the wrong ones are counted as failed logins.
+func (user *User) DeleteAccount(ctx context.Context, mode, password string) error {
+	switch mode {
+	case AccountDeletionAnonymize, AccountDeletionHard:
+	default:
+		return session.BadDataErrorWithFieldAndData(ctx, "mode", "invalid", mode)
+	}
+	if user.UserID == GhostUserID {
+		return session.ForbiddenError(ctx)
+	}
+	if user.EncryptedPassword.Valid {
+		keys := append(ipAttemptKeys(ctx), userAttemptKey(user.UserID))
+		if err := checkLoginAttempts(ctx, keys...); err != nil {
+			return err
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.EncryptedPassword.String), []byte(password)); err != nil {
+			if err := failLoginAttempt(ctx, keys...); err != nil {
+				return err
+			}
+			return session.InvalidPasswordError(ctx)
+		}
+	}
+
+	var categoryIDs []string
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		categoryIDs, err = queryIDs(ctx, tx, "SELECT DISTINCT category_id FROM topics WHERE user_id=$1", user.UserID)
+		if err != nil {
+			return err
+		}
+		// topics commented, liked or bookmarked by the user need their counters repaired
+		topicIDs, err := queryIDs(ctx, tx, "SELECT topic_id FROM comments WHERE user_id=$1 UNION SELECT topic_id FROM topic_users WHERE user_id=$1", user.UserID)
+		if err != nil {
+			return err
+		}
+		// and so do the comments voted by the user
+		commentIDs, err := queryIDs(ctx, tx, "SELECT comment_id FROM comment_users WHERE user_id=$1", user.UserID)
+		if err != nil {
+			return err
+		}
+		queries := []string{
+			"DELETE FROM topics WHERE user_id=$1 AND draft=true",
+			"DELETE FROM topic_users WHERE user_id=$1",
+			"DELETE FROM comment_users WHERE user_id=$1",
+			"UPDATE notifications SET actor_ids=array_remove(actor_ids,$1::varchar) WHERE $1::varchar=ANY(actor_ids)",
+			"DELETE FROM notifications WHERE cardinality(actor_ids)=0",
+		}
+		if mode == AccountDeletionAnonymize {
+			if err := ensureGhostUser(ctx, tx); err != nil {
+				return err
+			}
+			queries = append(queries,
+				fmt.Sprintf("UPDATE topics SET user_id='%s' WHERE user_id=$1", GhostUserID),
+				fmt.Sprintf("UPDATE comments SET user_id='%s' WHERE user_id=$1", GhostUserID),
+				fmt.Sprintf("UPDATE comments SET deleted_by='%s' WHERE deleted_by=$1", GhostUserID),
+				fmt.Sprintf("UPDATE topic_revisions SET user_id='%s' WHERE user_id=$1", GhostUserID),
+				fmt.Sprintf("UPDATE comment_revisions SET user_id='%s' WHERE user_id=$1", GhostUserID),
+			)
+		}
+		// the parents of the comments deleted with the user need their replies recounted
+		parentIDs, err := queryIDs(ctx, tx, "SELECT DISTINCT parent_comment_id FROM comments WHERE user_id=$1 AND parent_comment_id IS NOT NULL", user.UserID)
+		if err != nil {
+			return err
+		}
+		if mode == AccountDeletionHard {
+			if err := tombstoneUserComments(ctx, tx, user.UserID); err != nil {
+				return err
+			}
+		}
+		queries = append(queries, "DELETE FROM users WHERE user_id=$1")
+		for _, q := range queries {
+			if _, err := tx.Exec(ctx, q, user.UserID); err != nil {
+				return err
+			}
+		}
+		for _, id := range parentIDs {
+			if err := updateRepliesCount(ctx, tx, sql.NullString{String: id, Valid: true}); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(ctx, "UPDATE topics SET (comments_count,likes_count,bookmarks_count)=((SELECT count(*) FROM comments c WHERE c.topic_id=topics.topic_id AND c.deleted_at IS NULL),(SELECT count(*) FROM topic_users tu WHERE tu.topic_id=topics.topic_id AND tu.liked_at IS NOT NULL),(SELECT count(*) FROM topic_users tu WHERE tu.topic_id=topics.topic_id AND tu.bookmarked_at IS NOT NULL)) WHERE topic_id=ANY($1)", topicIDs)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "UPDATE comments SET score=(SELECT COALESCE(sum(vote),0) FROM comment_users cu WHERE cu.comment_id=comments.comment_id) WHERE comment_id=ANY($1)", commentIDs)
+		return err
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	for _, id := range categoryIDs {
+		if _, err := EmitToCategory(ctx, id); err != nil {
+			return err
+		}
+	}
+	UpsertStatistic(ctx, StatisticTypeUsers)
+	UpsertStatistic(ctx, StatisticTypeTopics)
+	UpsertStatistic(ctx, StatisticTypeComments)
+	return nil
+}
+
+// tombstoneUserComments keep the comments of the user which have replies of
+// others underneath, blanked and deleted by the ghost user, so the replies
+// aren't orphaned when the rest are deleted with the user
+func tombstoneUserComments(ctx context.Context, tx pgx.Tx, userID string) error {
+	ids, err := queryIDs(ctx, tx, "WITH RECURSIVE up AS (SELECT p.comment_id, p.parent_comment_id FROM comments r INNER JOIN comments p ON p.comment_id=r.parent_comment_id WHERE p.user_id=$1 AND r.user_id<>$1 UNION SELECT c.comment_id, c.parent_comment_id FROM comments c INNER JOIN up ON c.comment_id=up.parent_comment_id WHERE c.user_id=$1) SELECT comment_id FROM up", userID)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	if err := ensureGhostUser(ctx, tx); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "UPDATE comments SET (user_id,body,deleted_at,deleted_by)=($2,'',COALESCE(deleted_at,$3),$2) WHERE comment_id=ANY($1)", ids, GhostUserID, time.Now())
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM comment_revisions WHERE comment_id=ANY($1)", ids)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM mentions WHERE comment_id=ANY($1)", ids)
+	return err
+}
+
+func ensureGhostUser(ctx context.Context, tx pgx.Tx) error {
+	t := time.Now()
+	_, err := tx.Exec(ctx, "INSERT INTO users (user_id,nickname,role,created_at,updated_at) VALUES ($1,$2,$3,$4,$4) ON CONFLICT DO NOTHING", GhostUserID, "ghost", UserRoleReadOnly, t)
+	return err
+}
+
+func queryIDs(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"satellity/internal/session"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserAccount(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	other := createTestUser(ctx, "other@gmail.com", "other", "password")
+	assert.NotNil(other)
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+	topic, err := user.CreateTopic(ctx, "title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	_, err = user.CreateTopic(ctx, "draft", "body", TopicTypePost, category.CategoryID, true)
+	assert.Nil(err)
+	otherTopic, err := other.CreateTopic(ctx, "other title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	_, err = user.CreateComment(ctx, "comment body", otherTopic)
+	assert.Nil(err)
+	deleted, err := user.CreateComment(ctx, "deleted body", otherTopic)
+	assert.Nil(err)
+	err = deleted.Delete(ctx, user)
+	assert.Nil(err)
+	_, err = otherTopic.ActiondBy(ctx, user, TopicUserActionLiked, true)
+	assert.Nil(err)
+
+	archive, err := user.Export(ctx)
+	assert.Nil(err)
+	assert.Equal(user.UserID, archive.User.UserID)
+	assert.Len(archive.Topics, 2)
+	assert.Len(archive.Comments, 2)
+	assert.Len(archive.TopicUsers, 1)
+	assert.True(archive.TopicUsers[0].LikedAt.Valid)
+
+	err = user.DeleteAccount(ctx, "unknown", "password")
+	assert.NotNil(err)
+	err = user.DeleteAccount(ctx, AccountDeletionAnonymize, "wrong password")
+	assert.NotNil(err)
+	err = user.DeleteAccount(ctx, AccountDeletionAnonymize, "password")
+	assert.Nil(err)
+	existing, err := ReadUser(ctx, user.UserID)
+	assert.Nil(err)
+	assert.Nil(existing)
+	topic, err = ReadTopic(ctx, topic.TopicID)
+	assert.Nil(err)
+	assert.Equal(GhostUserID, topic.UserID)
+	otherTopic, err = ReadTopic(ctx, otherTopic.TopicID)
+	assert.Nil(err)
+	assert.Equal(int64(1), otherTopic.CommentsCount)
+	assert.Equal(int64(0), otherTopic.LikesCount)
+	deleted, err = ReadComment(ctx, deleted.CommentID)
+	assert.Nil(err)
+	assert.Equal(GhostUserID, deleted.UserID)
+	assert.True(deleted.IsDeleted())
+	assert.False(deleted.IsDeletedByModerator())
+	ghost, err := ReadUser(ctx, GhostUserID)
+	assert.Nil(err)
+	assert.Equal(UserRoleReadOnly, ghost.GetRole())
+	err = ghost.DeleteAccount(ctx, AccountDeletionHard, "")
+	assert.NotNil(err)
+
+	other, _ = ReadUser(ctx, other.UserID)
+	for i := 0; i < loginAttemptUserLimit; i++ {
+		err = other.DeleteAccount(ctx, AccountDeletionHard, "wrong password")
+		assert.NotNil(err)
+	}
+	err = other.DeleteAccount(ctx, AccountDeletionHard, "password")
+	assert.Equal(10018, err.(session.Error).Code)
+	assert.Nil(clearLoginAttempts(ctx, userAttemptKey(other.UserID)))
+	err = other.DeleteAccount(ctx, AccountDeletionHard, "password")
+	assert.Nil(err)
+	otherTopic, err = ReadTopic(ctx, otherTopic.TopicID)
+	assert.Nil(err)
+	assert.Nil(otherTopic)
+	category, err = ReadCategory(ctx, category.CategoryID)
+	assert.Nil(err)
+	assert.Equal(int64(1), category.TopicsCount)
+	assert.Equal(topic.TopicID, category.LastTopicID.String)
+}
+
+func TestDeleteAccountThread(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	other := createTestUser(ctx, "other@gmail.com", "other", "password")
+	assert.NotNil(other)
+	deleting := createTestUser(ctx, "deleting@gmail.com", "deleting", "password")
+	assert.NotNil(deleting)
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+	assert.Nil(category.SetCommentsView(ctx, CategoryCommentsViewNested))
+	topic, err := user.CreateTopic(ctx, "title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+
+	root, err := user.CreateComment(ctx, "root comment", topic)
+	assert.Nil(err)
+	kept, err := deleting.CreateReply(ctx, "replied comment", topic, root.CommentID)
+	assert.Nil(err)
+	reply, err := other.CreateReply(ctx, "reply comment", topic, kept.CommentID)
+	assert.Nil(err)
+	leaf, err := deleting.CreateReply(ctx, "leaf comment", topic, reply.CommentID)
+	assert.Nil(err)
+	lone, err := deleting.CreateReply(ctx, "lone comment", topic, root.CommentID)
+	assert.Nil(err)
+	reply, err = readTestComment(ctx, reply.CommentID)
+	assert.Nil(err)
+	assert.Equal(int64(1), reply.RepliesCount)
+	root, err = readTestComment(ctx, root.CommentID)
+	assert.Nil(err)
+	assert.Equal(int64(2), root.RepliesCount)
+
+	err = deleting.DeleteAccount(ctx, AccountDeletionHard, "password")
+	assert.Nil(err)
+	kept, err = readTestComment(ctx, kept.CommentID)
+	assert.Nil(err)
+	assert.NotNil(kept)
+	assert.Equal(GhostUserID, kept.UserID)
+	assert.Equal("", kept.Body)
+	assert.True(kept.IsDeleted())
+	assert.Equal(root.CommentID, kept.ParentCommentID.String)
+	reply, err = readTestComment(ctx, reply.CommentID)
+	assert.Nil(err)
+	assert.Equal(kept.CommentID, reply.ParentCommentID.String)
+	assert.Equal(2, reply.Depth)
+	assert.Equal(int64(0), reply.RepliesCount)
+	leaf, err = readTestComment(ctx, leaf.CommentID)
+	assert.Nil(err)
+	assert.Nil(leaf)
+	lone, err = readTestComment(ctx, lone.CommentID)
+	assert.Nil(err)
+	assert.Nil(lone)
+	root, err = readTestComment(ctx, root.CommentID)
+	assert.Nil(err)
+	assert.Equal(int64(0), root.RepliesCount)
+	replies, err := root.ReadReplies(ctx)
+	assert.Nil(err)
+	assert.Len(replies, 2)
+	topic, err = ReadTopic(ctx, topic.TopicID)
+	assert.Nil(err)
+	assert.Equal(int64(2), topic.CommentsCount)
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"satellity/internal/clouds"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// CreateEmailChange send a verification code to the new email, password is
+// required if the user has one, the wrong ones are counted as failed logins
+func (user *User) CreateEmailChange(ctx context.Context, email, password string) (*EmailVerification, error) {
+	if !user.Email.Valid {
+		return nil, session.ForbiddenError(ctx)
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if err := validateEmailFormat(ctx, email); err != nil {
+		return nil, err
+	}
+	if email == strings.ToLower(user.Email.String) {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "email", "invalid", email)
+	}
+	if user.EncryptedPassword.Valid {
+		keys := append(ipAttemptKeys(ctx), userAttemptKey(user.UserID))
+		if err := checkLoginAttempts(ctx, keys...); err != nil {
+			return nil, err
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.EncryptedPassword.String), []byte(password)); err != nil {
+			if err := failLoginAttempt(ctx, keys...); err != nil {
+				return nil, err
+			}
+			return nil, session.InvalidPasswordError(ctx)
+		}
+		if err := clearLoginAttempts(ctx, userAttemptKey(user.UserID)); err != nil {
+			return nil, err
+		}
+	}
+	var count int64
+	err := session.Database(ctx).QueryRow(ctx, "SELECT count(*) FROM users WHERE LOWER(email)=$1", email).Scan(&count)
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	if count > 0 {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "email", "taken", email)
+	}
+	return createEmailVerification(ctx, EmailVerificationPurposeEmail, email, user.UserID)
+}
+
+// ChangeEmail verify the code sent to the new email, then replace the email
+// of the user and notify the old one
+func (user *User) ChangeEmail(ctx context.Context, verificationID, code string) error {
+	if err := checkLoginAttempts(ctx, ipAttemptKeys(ctx)...); err != nil {
+		return err
+	}
+	var email string
+	t := time.Now()
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		ev, err := matchEmailVerification(ctx, tx, verificationID, code)
+		if err != nil {
+			return err
+		}
+		if ev == nil || ev.Purpose != EmailVerificationPurposeEmail || ev.UserID.String != user.UserID {
+			return session.VerificationCodeInvalidError(ctx)
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM email_verifications WHERE verification_id=$1", ev.VerificationID)
+		if err != nil {
+			return err
+		}
+		email = strings.ToLower(ev.Email)
+		var count int64
+		if err := tx.QueryRow(ctx, "SELECT count(*) FROM users WHERE LOWER(email)=$1 AND user_id<>$2", email, user.UserID).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return session.BadDataErrorWithFieldAndData(ctx, "email", "taken", email)
+		}
+		_, err = tx.Exec(ctx, "UPDATE users SET (email,updated_at)=($1,$2) WHERE user_id=$3", email, t, user.UserID)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "users_emailx" {
+			return session.BadDataErrorWithFieldAndData(ctx, "email", "taken", email)
+		}
+		return err
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	old := user.Email.String
+	user.Email = sql.NullString{String: email, Valid: true}
+	user.UpdatedAt = t
+	// the email has been changed already, a failed notice is only logged
+	if err := clouds.SendEmailChangedNotice(ctx, old, email); err != nil {
+		session.ServerError(ctx, err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"satellity/internal/session"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserEmail(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	other := createTestUser(ctx, "other@gmail.com", "other", "password")
+	assert.NotNil(other)
+
+	ev, err := user.CreateEmailChange(ctx, "new@gmail.com", "wrong password")
+	assert.NotNil(err)
+	assert.Nil(ev)
+	ev, err = user.CreateEmailChange(ctx, "invalid email", "password")
+	assert.NotNil(err)
+	ev, err = user.CreateEmailChange(ctx, "Other@gmail.com", "password")
+	assert.NotNil(err)
+	ev, err = user.CreateEmailChange(ctx, "New@gmail.com", "password")
+	assert.Nil(err)
+	assert.NotNil(ev)
+	assert.Equal("new@gmail.com", ev.Email)
+	assert.Equal(EmailVerificationPurposeEmail, ev.Purpose)
+
+	err = other.ChangeEmail(ctx, ev.VerificationID, ev.Code)
+	assert.NotNil(err)
+	err = user.ChangeEmail(ctx, ev.VerificationID, "0000")
+	assert.NotNil(err)
+	err = user.ChangeEmail(ctx, ev.VerificationID, ev.Code)
+	assert.Nil(err)
+	assert.Equal("new@gmail.com", user.Email.String)
+	err = user.ChangeEmail(ctx, ev.VerificationID, ev.Code)
+	assert.NotNil(err)
+
+	existing, err := ReadUser(ctx, user.UserID)
+	assert.Nil(err)
+	assert.Equal("new@gmail.com", existing.Email.String)
+	existing, err = ReadUserByUsernameOrEmail(ctx, "im.yuqlee@gmail.com")
+	assert.Nil(err)
+	assert.Nil(existing)
+
+	for i := 0; i < loginAttemptUserLimit; i++ {
+		_, err = other.CreateEmailChange(ctx, "another@gmail.com", "wrong password")
+		assert.NotNil(err)
+	}
+	ev, err = other.CreateEmailChange(ctx, "another@gmail.com", "password")
+	assert.Nil(ev)
+	assert.Equal(10018, err.(session.Error).Code)
+}
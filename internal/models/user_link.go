@@ -0,0 +1,283 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"satellity/internal/oauth"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// LinkIdentity attach an identity of the oauth provider to the user
+func (user *User) LinkIdentity(ctx context.Context, provider, code string) (*UserIdentity, error) {
+	p, err := oauth.Lookup(provider)
+	if err != nil {
+		return nil, session.ServerError(ctx, err)
+	} else if p == nil {
+		return nil, session.NotFoundError(ctx)
+	}
+	identity, err := p.Authenticate(ctx, code)
+	if err != nil {
+		return nil, session.ServerError(ctx, err)
+	}
+
+	var ui *UserIdentity
+	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		existing, err := findUserIdentity(ctx, tx, identity.Provider, identity.Subject)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.UserID != user.UserID {
+			return session.BadDataErrorWithFieldAndData(ctx, "identity", "taken", identity.Provider)
+		}
+		if existing != nil {
+			ui = existing
+			return ui.refresh(ctx, tx, identity)
+		}
+		ui, err = createUserIdentity(ctx, tx, user, identity)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return ui, nil
+}
+
+// UnlinkIdentity detach an oauth identity, the user must keep at least one way to sign in
+func (user *User) UnlinkIdentity(ctx context.Context, id string) error {
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		cmd, err := tx.Exec(ctx, "DELETE FROM user_identities WHERE identity_id=$1 AND user_id=$2", id, user.UserID)
+		if err != nil || cmd.RowsAffected() == 0 {
+			return err
+		}
+		return ensureSignInMethod(ctx, tx, user.UserID)
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// LinkWallet attach the ethereum address which signs the EIP-4361 message, the
+// Request ID of the message must be the public key of the current session
+func (user *User) LinkWallet(ctx context.Context, message, sig string) error {
+	m, publicKey, err := verifyWeb3Message(ctx, message, sig)
+	if err != nil {
+		return err
+	}
+	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		s, err := readSession(ctx, tx, user.UserID, user.SessionID)
+		if err != nil {
+			return err
+		}
+		if s == nil || m.RequestID != s.PublicKey {
+			return session.BadDataErrorWithFieldAndData(ctx, "message", "invalid", "request id")
+		}
+		if err := consumeWeb3Nonce(ctx, tx, m.Nonce); err != nil {
+			return err
+		}
+		existing, err := findUserByPublicKey(ctx, tx, publicKey)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.UserID != user.UserID {
+			return session.BadDataErrorWithFieldAndData(ctx, "public_key", "taken", publicKey)
+		}
+		user.PublicKey = sql.NullString{String: publicKey, Valid: true}
+		user.UpdatedAt = time.Now()
+		_, err = tx.Exec(ctx, "UPDATE users SET (public_key,updated_at)=($1,$2) WHERE user_id=$3", user.PublicKey, user.UpdatedAt, user.UserID)
+		return err
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// UnlinkWallet detach the ethereum address
+func (user *User) UnlinkWallet(ctx context.Context) error {
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		user.PublicKey = sql.NullString{}
+		user.UpdatedAt = time.Now()
+		_, err := tx.Exec(ctx, "UPDATE users SET (public_key,updated_at)=($1,$2) WHERE user_id=$3", user.PublicKey, user.UpdatedAt, user.UserID)
+		if err != nil {
+			return err
+		}
+		return ensureSignInMethod(ctx, tx, user.UserID)
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// CreateEmailLink send a verification code to the email to link, for a user
+// signed up by wallet or oauth
+func (user *User) CreateEmailLink(ctx context.Context, email string) (*EmailVerification, error) {
+	if user.Email.Valid {
+		return nil, session.ForbiddenError(ctx)
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if err := validateEmailFormat(ctx, email); err != nil {
+		return nil, err
+	}
+	var count int64
+	err := session.Database(ctx).QueryRow(ctx, "SELECT count(*) FROM users WHERE LOWER(email)=$1", email).Scan(&count)
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	if count > 0 {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "email", "taken", email)
+	}
+	return createEmailVerification(ctx, EmailVerificationPurposeLink, email, user.UserID)
+}
+
+// LinkEmail attach a verified email and password to a user signed up by wallet
+// or oauth, the verification must be created by CreateEmailLink of the user
+func (user *User) LinkEmail(ctx context.Context, verificationID, code, password string) error {
+	if user.Email.Valid {
+		return session.ForbiddenError(ctx)
+	}
+	password, err := validateAndEncryptPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+	if err := checkLoginAttempts(ctx, ipAttemptKeys(ctx)...); err != nil {
+		return err
+	}
+	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		ev, err := matchEmailVerification(ctx, tx, verificationID, code)
+		if err != nil {
+			return err
+		}
+		if ev == nil || ev.Purpose != EmailVerificationPurposeLink || ev.UserID.String != user.UserID {
+			return session.VerificationCodeInvalidError(ctx)
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM email_verifications WHERE verification_id=$1", ev.VerificationID)
+		if err != nil {
+			return err
+		}
+		email := strings.ToLower(ev.Email)
+		var count int64
+		if err := tx.QueryRow(ctx, "SELECT count(*) FROM users WHERE LOWER(email)=$1", email).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return session.BadDataErrorWithFieldAndData(ctx, "email", "taken", email)
+		}
+		user.Email = sql.NullString{String: email, Valid: true}
+		user.EncryptedPassword = sql.NullString{String: password, Valid: true}
+		user.UpdatedAt = time.Now()
+		_, err = tx.Exec(ctx, "UPDATE users SET (email,encrypted_password,updated_at)=($1,$2,$3) WHERE user_id=$4", user.Email, user.EncryptedPassword, user.UpdatedAt, user.UserID)
+		return err
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// UnlinkEmail detach the email and password
+func (user *User) UnlinkEmail(ctx context.Context) error {
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		user.Email = sql.NullString{}
+		user.EncryptedPassword = sql.NullString{}
+		user.UpdatedAt = time.Now()
+		_, err := tx.Exec(ctx, "UPDATE users SET (email,encrypted_password,updated_at)=($1,$2,$3) WHERE user_id=$4", user.Email, user.EncryptedPassword, user.UpdatedAt, user.UserID)
+		if err != nil {
+			return err
+		}
+		return ensureSignInMethod(ctx, tx, user.UserID)
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// MergeUser move topics, comments, topic_users, comment_users, notifications and
+// identities of the source user into the user, then delete the source. Email,
+// wallet, username and password of the source fill the blank ones of the user.
+// Only admins can merge an admin, into or away.
+func (user *User) MergeUser(ctx context.Context, source *User, operator *User) error {
+	if can, err := operator.Can(ctx, PermissionUserManage); err != nil {
+		return err
+	} else if !can {
+		return session.ForbiddenError(ctx)
+	}
+	if source == nil || source.UserID == user.UserID {
+		return session.BadDataError(ctx)
+	}
+	if (source.GetRole() == UserRoleAdmin || user.GetRole() == UserRoleAdmin) && operator.GetRole() != UserRoleAdmin {
+		return session.ForbiddenError(ctx)
+	}
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		queries := []string{
+			"UPDATE topics SET user_id=$1 WHERE user_id=$2",
+			"UPDATE comments SET user_id=$1 WHERE user_id=$2",
+			"UPDATE comments SET deleted_by=$1 WHERE deleted_by=$2",
+			"UPDATE topic_revisions SET user_id=$1 WHERE user_id=$2",
+			"UPDATE comment_revisions SET user_id=$1 WHERE user_id=$2",
+			"UPDATE topic_users t SET (liked_at,bookmarked_at,subscription)=(COALESCE(t.liked_at,s.liked_at),COALESCE(t.bookmarked_at,s.bookmarked_at),COALESCE(NULLIF(t.subscription,''),s.subscription)) FROM topic_users s WHERE t.user_id=$1 AND s.user_id=$2 AND t.topic_id=s.topic_id",
+			"DELETE FROM topic_users s WHERE s.user_id=$2 AND EXISTS (SELECT 1 FROM topic_users t WHERE t.user_id=$1 AND t.topic_id=s.topic_id)",
+			"UPDATE topic_users SET user_id=$1 WHERE user_id=$2",
+			"UPDATE topics SET (likes_count,bookmarks_count)=((SELECT count(*) FROM topic_users tu WHERE tu.topic_id=topics.topic_id AND tu.liked_at IS NOT NULL),(SELECT count(*) FROM topic_users tu WHERE tu.topic_id=topics.topic_id AND tu.bookmarked_at IS NOT NULL)) WHERE topic_id IN (SELECT topic_id FROM topic_users WHERE user_id=$1)",
+			"DELETE FROM comment_users s WHERE s.user_id=$2 AND EXISTS (SELECT 1 FROM comment_users t WHERE t.user_id=$1 AND t.comment_id=s.comment_id)",
+			"UPDATE comment_users SET user_id=$1 WHERE user_id=$2",
+			"DELETE FROM comment_users cu WHERE cu.user_id=$1 AND EXISTS (SELECT 1 FROM comments c WHERE c.comment_id=cu.comment_id AND c.user_id=$1)",
+			"UPDATE comments SET score=(SELECT COALESCE(sum(vote),0) FROM comment_users cu WHERE cu.comment_id=comments.comment_id) WHERE user_id=$1 OR comment_id IN (SELECT comment_id FROM comment_users WHERE user_id=$1)",
+			"DELETE FROM notifications s WHERE s.user_id=$2 AND s.read_at IS NULL AND EXISTS (SELECT 1 FROM notifications t WHERE t.user_id=$1 AND t.read_at IS NULL AND t.group_key=s.group_key)",
+			"UPDATE notifications SET user_id=$1 WHERE user_id=$2",
+			"UPDATE notifications SET actor_ids=array_remove(actor_ids,$2::varchar) WHERE $2::varchar=ANY(actor_ids) AND $1::varchar=ANY(actor_ids)",
+			"UPDATE notifications SET actor_ids=array_replace(actor_ids,$2::varchar,$1::varchar) WHERE $2::varchar=ANY(actor_ids)",
+			"DELETE FROM notifications WHERE user_id=$1 AND actor_ids=ARRAY[$1::varchar]",
+			"UPDATE mentions t SET removed_at=NULL FROM mentions s WHERE t.user_id=$1 AND s.user_id=$2 AND t.topic_id=s.topic_id AND t.comment_id IS NOT DISTINCT FROM s.comment_id AND s.removed_at IS NULL",
+			"DELETE FROM mentions s WHERE s.user_id=$2 AND EXISTS (SELECT 1 FROM mentions t WHERE t.user_id=$1 AND t.topic_id=s.topic_id AND t.comment_id IS NOT DISTINCT FROM s.comment_id)",
+			"UPDATE mentions SET user_id=$1 WHERE user_id=$2",
+			"INSERT INTO category_users (category_id,user_id,subscription,created_at,updated_at) SELECT category_id,$1,subscription,created_at,updated_at FROM category_users WHERE user_id=$2 ON CONFLICT DO NOTHING",
+			"UPDATE user_identities SET user_id=$1 WHERE user_id=$2",
+			"INSERT INTO category_moderators (category_id,user_id,created_at) SELECT category_id,$1,created_at FROM category_moderators WHERE user_id=$2 ON CONFLICT DO NOTHING",
+		}
+		for _, q := range queries {
+			if _, err := tx.Exec(ctx, q, user.UserID, source.UserID); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM users WHERE user_id=$1", source.UserID); err != nil {
+			return err
+		}
+		if !user.Email.Valid && source.Email.Valid {
+			user.Email, user.EncryptedPassword = source.Email, source.EncryptedPassword
+		}
+		if !user.PublicKey.Valid {
+			user.PublicKey = source.PublicKey
+		}
+		if !user.Username.Valid {
+			user.Username = source.Username
+		}
+		user.UpdatedAt = time.Now()
+		_, err := tx.Exec(ctx, "UPDATE users SET (email,encrypted_password,public_key,username,updated_at)=($1,$2,$3,$4,$5) WHERE user_id=$6", user.Email, user.EncryptedPassword, user.PublicKey, user.Username, user.UpdatedAt, user.UserID)
+		return err
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	UpsertStatistic(ctx, StatisticTypeUsers)
+	return nil
+}
+
+// ensureSignInMethod fails the transaction when the user has no email, wallet or identity left
+func ensureSignInMethod(ctx context.Context, tx pgx.Tx, userID string) error {
+	var count int64
+	query := "SELECT (SELECT count(*) FROM users WHERE user_id=$1 AND (email IS NOT NULL OR public_key IS NOT NULL)) + (SELECT count(*) FROM user_identities WHERE user_id=$1)"
+	if err := tx.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return session.BadDataErrorWithFieldAndData(ctx, "identity", "last sign in method", userID)
+	}
+	return nil
+}
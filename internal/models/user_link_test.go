@@ -0,0 +1,104 @@
+package models
+
+import (
+	"satellity/internal/configs"
+	"satellity/internal/session"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserLinkCRUD(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	err := user.UnlinkEmail(ctx)
+	assert.NotNil(err)
+
+	privateKey, err := crypto.HexToECDSA("0123456789012345678901234567890123456789012345678901234567890123")
+	assert.Nil(err)
+	publicKey := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+	s, err := readTestSession(ctx, user.UserID, user.SessionID)
+	assert.Nil(err)
+	assert.NotNil(s)
+	message, signature := signTestWeb3Message(ctx, privateKey, time.Now(), "")
+	err = user.LinkWallet(ctx, message, signature)
+	assert.NotNil(err)
+	message, signature = signTestWeb3Message(ctx, privateKey, time.Now(), "0x00")
+	err = user.LinkWallet(ctx, message, signature)
+	assert.NotNil(err)
+	message, signature = signTestWeb3Message(ctx, privateKey, time.Now(), s.PublicKey)
+	err = user.LinkWallet(ctx, message, "0x00")
+	assert.NotNil(err)
+	err = user.LinkWallet(ctx, message, signature)
+	assert.Nil(err)
+	assert.Equal(publicKey, user.PublicKey.String)
+	err = user.UnlinkEmail(ctx)
+	assert.Nil(err)
+	assert.False(user.Email.Valid)
+	err = user.UnlinkWallet(ctx)
+	assert.NotNil(err)
+	existing, err := ReadUser(ctx, user.UserID)
+	assert.Nil(err)
+	assert.Equal(publicKey, existing.PublicKey.String)
+
+	ev, err := user.CreateEmailLink(ctx, "link@gmail.com")
+	assert.Nil(err)
+	assert.Equal(EmailVerificationPurposeLink, ev.Purpose)
+	err = Reset(ctx, ev.VerificationID, ev.Code, "newpassword")
+	assert.NotNil(err)
+	err = user.LinkEmail(ctx, ev.VerificationID, ev.Code, "newpassword")
+	assert.Nil(err)
+	assert.Equal("link@gmail.com", user.Email.String)
+
+	category, _ := CreateCategory(ctx, "name", "alias", "Description", 0)
+	assert.NotNil(category)
+	topic, err := user.CreateTopic(ctx, "title", "body", TopicTypePost, category.CategoryID, false)
+	assert.Nil(err)
+	comment, err := user.CreateComment(ctx, "comment body", topic)
+	assert.Nil(err)
+	err = comment.Delete(ctx, user)
+	assert.Nil(err)
+
+	target := createTestUser(ctx, "target@gmail.com", "target", "password")
+	assert.NotNil(target)
+	operator := createTestUser(ctx, "operator@gmail.com", "operator", "password")
+	assert.NotNil(operator)
+	err = target.MergeUser(ctx, user, target)
+	assert.NotNil(err)
+	configs.AppConfig.OperatorSet["operator@gmail.com"] = true
+	defer delete(configs.AppConfig.OperatorSet, "operator@gmail.com")
+	err = target.MergeUser(ctx, target, operator)
+	assert.NotNil(err)
+	moderator := createTestUser(ctx, "moderator@gmail.com", "moderator", "password")
+	assert.NotNil(moderator)
+	role, err := ReadRole(ctx, UserRoleModerator)
+	assert.Nil(err)
+	err = role.UpdatePermissions(ctx, append(role.Permissions, PermissionUserManage), operator)
+	assert.Nil(err)
+	err = moderator.AssignRole(ctx, UserRoleModerator, operator)
+	assert.Nil(err)
+	err = target.MergeUser(ctx, operator, moderator)
+	assert.Equal(403, err.(session.Error).Code)
+	err = operator.MergeUser(ctx, target, moderator)
+	assert.Equal(403, err.(session.Error).Code)
+	err = target.MergeUser(ctx, user, operator)
+	assert.Nil(err)
+	existing, err = ReadUser(ctx, user.UserID)
+	assert.Nil(err)
+	assert.Nil(existing)
+	existing, err = ReadUser(ctx, target.UserID)
+	assert.Nil(err)
+	assert.Equal(publicKey, existing.PublicKey.String)
+	assert.Equal("target@gmail.com", existing.Email.String)
+	comment, err = ReadComment(ctx, comment.CommentID)
+	assert.Nil(err)
+	assert.Equal(target.UserID, comment.UserID)
+	assert.True(comment.IsDeleted())
+	assert.False(comment.IsDeletedByModerator())
+}
@@ -0,0 +1,202 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"satellity/internal/durable"
+	"satellity/internal/oauth"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// usernameSuffixes keeps oauth usernames apart from the registered ones, e.g.: jadeydi_GH,
+// by the type of the provider whatever its configured name
+var usernameSuffixes = map[string]string{
+	oauth.TypeGithub: "GH",
+	oauth.TypeGitlab: "GL",
+}
+
+// UserIdentity links an external sign in identity to a user, a user may have many
+type UserIdentity struct {
+	IdentityID string
+	UserID     string
+	Provider   string
+	Subject    string
+	Login      string
+	Email      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+var userIdentityColumns = []string{"identity_id", "user_id", "provider", "subject", "login", "email", "created_at", "updated_at"}
+
+func (ui *UserIdentity) values() []interface{} {
+	return []interface{}{ui.IdentityID, ui.UserID, ui.Provider, ui.Subject, ui.Login, ui.Email, ui.CreatedAt, ui.UpdatedAt}
+}
+
+func userIdentityFromRow(row durable.Row) (*UserIdentity, error) {
+	var ui UserIdentity
+	err := row.Scan(&ui.IdentityID, &ui.UserID, &ui.Provider, &ui.Subject, &ui.Login, &ui.Email, &ui.CreatedAt, &ui.UpdatedAt)
+	return &ui, err
+}
+
+// CreateOAuthUser sign in by the oauth provider, a new user will be created for an unknown identity
+func CreateOAuthUser(ctx context.Context, provider, code, sessionSecret string) (*User, error) {
+	p, err := oauth.Lookup(provider)
+	if err != nil {
+		return nil, session.ServerError(ctx, err)
+	} else if p == nil {
+		return nil, session.NotFoundError(ctx)
+	}
+	identity, err := p.Authenticate(ctx, code)
+	if err != nil {
+		return nil, session.ServerError(ctx, err)
+	}
+
+	var user *User
+	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		ui, err := findUserIdentity(ctx, tx, identity.Provider, identity.Subject)
+		if err != nil {
+			return err
+		}
+		if ui != nil {
+			user, err = findUserByID(ctx, tx, ui.UserID)
+			if err != nil {
+				return err
+			}
+			if err := ui.refresh(ctx, tx, identity); err != nil {
+				return err
+			}
+			user, err = createUser(ctx, tx, "", "", "", "", "", sessionSecret, user)
+			return err
+		}
+
+		email, err := availableOAuthEmail(ctx, tx, identity)
+		if err != nil {
+			return err
+		}
+		username, err := availableOAuthUsername(ctx, tx, identity)
+		if err != nil {
+			return err
+		}
+		nickname := identity.Name
+		if nickname == "" {
+			nickname = identity.Login
+		}
+		user, err = createUser(ctx, tx, "", email, username, nickname, "", sessionSecret, nil)
+		if err != nil {
+			return err
+		}
+		_, err = createUserIdentity(ctx, tx, user, identity)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	if user.isNew {
+		UpsertStatistic(ctx, StatisticTypeUsers)
+	}
+	return user, nil
+}
+
+// ReadIdentities read all oauth identities of the user
+func (user *User) ReadIdentities(ctx context.Context) ([]*UserIdentity, error) {
+	var identities []*UserIdentity
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		identities, err = readUserIdentities(ctx, tx, user.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return identities, nil
+}
+
+func createUserIdentity(ctx context.Context, tx pgx.Tx, user *User, identity *oauth.Identity) (*UserIdentity, error) {
+	t := time.Now()
+	ui := &UserIdentity{
+		IdentityID: uuid.Must(uuid.NewV4()).String(),
+		UserID:     user.UserID,
+		Provider:   identity.Provider,
+		Subject:    identity.Subject,
+		Login:      identity.Login,
+		Email:      identity.Email,
+		CreatedAt:  t,
+		UpdatedAt:  t,
+	}
+	rows := [][]interface{}{ui.values()}
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{"user_identities"}, userIdentityColumns, pgx.CopyFromRows(rows))
+	return ui, err
+}
+
+func (ui *UserIdentity) refresh(ctx context.Context, tx pgx.Tx, identity *oauth.Identity) error {
+	if ui.Login == identity.Login && ui.Email == identity.Email {
+		return nil
+	}
+	ui.Login, ui.Email, ui.UpdatedAt = identity.Login, identity.Email, time.Now()
+	_, err := tx.Exec(ctx, "UPDATE user_identities SET (login,email,updated_at)=($1,$2,$3) WHERE identity_id=$4", ui.Login, ui.Email, ui.UpdatedAt, ui.IdentityID)
+	return err
+}
+
+func findUserIdentity(ctx context.Context, tx pgx.Tx, provider, subject string) (*UserIdentity, error) {
+	row := tx.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM user_identities WHERE provider=$1 AND subject=$2", strings.Join(userIdentityColumns, ",")), provider, subject)
+	ui, err := userIdentityFromRow(row)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return ui, err
+}
+
+func readUserIdentities(ctx context.Context, tx pgx.Tx, userID string) ([]*UserIdentity, error) {
+	rows, err := tx.Query(ctx, fmt.Sprintf("SELECT %s FROM user_identities WHERE user_id=$1 ORDER BY user_id,created_at", strings.Join(userIdentityColumns, ",")), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*UserIdentity
+	for rows.Next() {
+		ui, err := userIdentityFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, ui)
+	}
+	return identities, rows.Err()
+}
+
+// availableOAuthEmail only trusts verified emails that no one else has
+func availableOAuthEmail(ctx context.Context, tx pgx.Tx, identity *oauth.Identity) (string, error) {
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	if !identity.EmailVerified || !emailRegexp.MatchString(email) {
+		return "", nil
+	}
+	var count int64
+	err := tx.QueryRow(ctx, "SELECT count(*) FROM users WHERE LOWER(email)=$1", email).Scan(&count)
+	if err != nil || count > 0 {
+		return "", err
+	}
+	return email, nil
+}
+
+// availableOAuthUsername is blank when the login is taken or can't be a username
+func availableOAuthUsername(ctx context.Context, tx pgx.Tx, identity *oauth.Identity) (string, error) {
+	username := strings.ReplaceAll(identity.Login, "-", "_")
+	if suffix := usernameSuffixes[identity.Type]; suffix != "" {
+		username = fmt.Sprintf("%s_%s", username, suffix)
+	}
+	if !usernameRegexp.MatchString(username) {
+		return "", nil
+	}
+	var count int64
+	err := tx.QueryRow(ctx, "SELECT count(*) FROM users WHERE LOWER(username)=$1", strings.ToLower(username)).Scan(&count)
+	if err != nil || count > 0 {
+		return "", err
+	}
+	return username, nil
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"satellity/internal/configs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuthUserCRUD(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "token"})
+	})
+	mux.HandleFunc("/api/v4/user", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 42, "username": "tanuki-san", "name": "Tanuki", "email": "im.yuqlee@gmail.com", "confirmed_at": "2020-01-01T00:00:00Z"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	configs.AppConfig.OAuth = []configs.OAuthProvider{{Name: "corp", Type: "gitlab", URL: server.URL}}
+	defer func() { configs.AppConfig.OAuth = nil }()
+
+	public, _, _ := ed25519.GenerateKey(rand.Reader)
+	user, err := CreateOAuthUser(ctx, "none", "code", hex.EncodeToString(public))
+	assert.NotNil(err)
+	assert.Nil(user)
+
+	existing := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(existing)
+	user, err = CreateOAuthUser(ctx, "corp", "code", hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.NotNil(user)
+	assert.NotEqual(existing.UserID, user.UserID)
+	assert.Equal("tanuki_san_GL", user.Username.String)
+	assert.Equal("Tanuki", user.Nickname)
+	assert.False(user.Email.Valid)
+	assert.NotEqual("", user.SessionID)
+	identities, err := user.ReadIdentities(ctx)
+	assert.Nil(err)
+	assert.Len(identities, 1)
+	assert.Equal("42", identities[0].Subject)
+
+	public, _, _ = ed25519.GenerateKey(rand.Reader)
+	old, err := CreateOAuthUser(ctx, "corp", "code", hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.NotNil(old)
+	assert.Equal(user.UserID, old.UserID)
+	assert.NotEqual(user.SessionID, old.SessionID)
+}
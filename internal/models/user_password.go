@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"satellity/internal/session"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ChangePassword replace the password of the user and revoke all other sessions.
+// The current password is required unless the user signed up by oauth or wallet
+// and never set one, the wrong ones are counted as failed logins.
+func (user *User) ChangePassword(ctx context.Context, current, password string) error {
+	keys := append(ipAttemptKeys(ctx), userAttemptKey(user.UserID))
+	if user.EncryptedPassword.Valid {
+		if err := checkLoginAttempts(ctx, keys...); err != nil {
+			return err
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.EncryptedPassword.String), []byte(current)); err != nil {
+			if err := failLoginAttempt(ctx, keys...); err != nil {
+				return err
+			}
+			return session.InvalidPasswordError(ctx)
+		}
+	}
+	password, err := validateAndEncryptPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+	t := time.Now()
+	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "UPDATE users SET (encrypted_password,password_reset_required,updated_at)=($1,false,$2) WHERE user_id=$3", password, t, user.UserID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM sessions WHERE user_id=$1 AND session_id<>$2", user.UserID, user.SessionID)
+		return err
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	user.EncryptedPassword = sql.NullString{String: password, Valid: true}
+	user.PasswordResetRequired = false
+	user.UpdatedAt = t
+	return clearLoginAttempts(ctx, userAttemptKey(user.UserID))
+}
+
+// RequirePasswordReset revoke all sessions of the user, the password can't be
+// used to sign in until it's reset by email or changed.
+func (user *User) RequirePasswordReset(ctx context.Context, operator *User) error {
+	if operator == nil || operator.UserID == user.UserID {
+		return session.ForbiddenError(ctx)
+	}
+	if user.GetRole() == UserRoleAdmin && operator.GetRole() != UserRoleAdmin {
+		return session.ForbiddenError(ctx)
+	}
+	if can, err := operator.Can(ctx, PermissionUserManage); err != nil {
+		return err
+	} else if !can {
+		return session.ForbiddenError(ctx)
+	}
+	t := time.Now()
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "UPDATE users SET (password_reset_required,updated_at)=(true,$1) WHERE user_id=$2", t, user.UserID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM sessions WHERE user_id=$1", user.UserID)
+		return err
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	user.PasswordResetRequired = true
+	user.UpdatedAt = t
+	return nil
+}
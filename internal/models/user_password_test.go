@@ -0,0 +1,69 @@
+package models
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"satellity/internal/configs"
+	"satellity/internal/session"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserPassword(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	operator := createTestUser(ctx, "operator@gmail.com", "operator", "password")
+	assert.NotNil(operator)
+	public, _, _ := ed25519.GenerateKey(rand.Reader)
+	other, err := CreateSession(ctx, "username", "password", "", hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.NotNil(other)
+
+	err = user.ChangePassword(ctx, "wrong password", "new password")
+	assert.NotNil(err)
+	err = user.ChangePassword(ctx, "password", "short")
+	assert.NotNil(err)
+	err = user.ChangePassword(ctx, "password", "new password")
+	assert.Nil(err)
+	sessions, err := user.ReadSessions(ctx)
+	assert.Nil(err)
+	assert.Len(sessions, 1)
+	assert.Equal(user.SessionID, sessions[0].SessionID)
+	_, err = CreateSession(ctx, "username", "password", "", hex.EncodeToString(public))
+	assert.NotNil(err)
+
+	err = user.RequirePasswordReset(ctx, operator)
+	assert.NotNil(err)
+	configs.AppConfig.OperatorSet["operator@gmail.com"] = true
+	defer delete(configs.AppConfig.OperatorSet, "operator@gmail.com")
+	err = operator.RequirePasswordReset(ctx, operator)
+	assert.NotNil(err)
+	err = user.RequirePasswordReset(ctx, operator)
+	assert.Nil(err)
+	assert.True(user.PasswordResetRequired)
+	sessions, err = user.ReadSessions(ctx)
+	assert.Nil(err)
+	assert.Len(sessions, 0)
+	_, err = CreateSession(ctx, "username", "new password", "", hex.EncodeToString(public))
+	assert.NotNil(err)
+	assert.Equal(10017, err.(session.Error).Code)
+
+	err = user.ChangePassword(ctx, "new password", "another password")
+	assert.Nil(err)
+	existing, err := CreateSession(ctx, "username", "another password", "", hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.False(existing.PasswordResetRequired)
+	for i := 0; i < loginAttemptUserLimit; i++ {
+		err = existing.ChangePassword(ctx, "wrong password", "new password")
+		assert.NotNil(err)
+	}
+	err = existing.ChangePassword(ctx, "another password", "new password")
+	assert.NotNil(err)
+	assert.Equal(10018, err.(session.Error).Code)
+}
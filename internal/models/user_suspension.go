@@ -0,0 +1,75 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// IsSuspended is true if the user is suspended permanently or the suspension not expired
+func (u *User) IsSuspended() bool {
+	if !u.SuspendedAt.Valid {
+		return false
+	}
+	return !u.SuspendedUntil.Valid || u.SuspendedUntil.Time.After(time.Now())
+}
+
+// Suspend the user until the time, a zero until means permanently.
+// All sessions of the user are revoked, the api tokens are kept but refused
+// until the suspension is lifted.
+func (user *User) Suspend(ctx context.Context, until time.Time, reason string, operator *User) error {
+	if operator == nil || operator.UserID == user.UserID || user.GetRole() == UserRoleAdmin {
+		return session.ForbiddenError(ctx)
+	}
+	if can, err := operator.Can(ctx, PermissionUserBan); err != nil {
+		return err
+	} else if !can {
+		return session.ForbiddenError(ctx)
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len(reason) > 1024 {
+		return session.BadDataErrorWithFieldAndData(ctx, "reason", "invalid", reason)
+	}
+	t := time.Now()
+	if !until.IsZero() && until.Before(t) {
+		return session.BadDataErrorWithFieldAndData(ctx, "until", "invalid", until.String())
+	}
+
+	user.SuspendedAt = sql.NullTime{Time: t, Valid: true}
+	user.SuspendedUntil = sql.NullTime{Time: until, Valid: !until.IsZero()}
+	user.SuspensionReason = reason
+	user.UpdatedAt = t
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "UPDATE users SET (suspended_at,suspended_until,suspension_reason,updated_at)=($1,$2,$3,$4) WHERE user_id=$5", user.SuspendedAt, user.SuspendedUntil, user.SuspensionReason, user.UpdatedAt, user.UserID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM sessions WHERE user_id=$1", user.UserID)
+		return err
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// Unsuspend lift the suspension of the user
+func (user *User) Unsuspend(ctx context.Context, operator *User) error {
+	if can, err := operator.Can(ctx, PermissionUserBan); err != nil {
+		return err
+	} else if !can {
+		return session.ForbiddenError(ctx)
+	}
+	user.SuspendedAt, user.SuspendedUntil = sql.NullTime{}, sql.NullTime{}
+	user.SuspensionReason = ""
+	user.UpdatedAt = time.Now()
+	_, err := session.Database(ctx).Exec(ctx, "UPDATE users SET (suspended_at,suspended_until,suspension_reason,updated_at)=(NULL,NULL,'',$1) WHERE user_id=$2", user.UpdatedAt, user.UserID)
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
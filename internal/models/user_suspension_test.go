@@ -0,0 +1,78 @@
+package models
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"satellity/internal/configs"
+	"satellity/internal/session"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserSuspension(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	operator := createTestUser(ctx, "operator@gmail.com", "operator", "password")
+	assert.NotNil(operator)
+	token, err := user.CreateAPIToken(ctx, "bot", []string{APITokenScopeRead})
+	assert.Nil(err)
+	assert.False(user.IsSuspended())
+
+	err = user.Suspend(ctx, time.Time{}, "spam", operator)
+	assert.NotNil(err)
+	configs.AppConfig.OperatorSet["operator@gmail.com"] = true
+	defer delete(configs.AppConfig.OperatorSet, "operator@gmail.com")
+	err = user.Suspend(ctx, time.Time{}, " ", operator)
+	assert.NotNil(err)
+	err = user.Suspend(ctx, time.Now().Add(-time.Hour), "spam", operator)
+	assert.NotNil(err)
+	err = operator.Suspend(ctx, time.Time{}, "spam", operator)
+	assert.NotNil(err)
+	err = user.Suspend(ctx, time.Time{}, "spam", operator)
+	assert.Nil(err)
+	assert.True(user.IsSuspended())
+
+	sessions, err := user.ReadSessions(ctx)
+	assert.Nil(err)
+	assert.Len(sessions, 0)
+	tokens, err := user.ReadAPITokens(ctx)
+	assert.Nil(err)
+	assert.Len(tokens, 1)
+	existing, err := AuthenticateAPIToken(ctx, token.Token)
+	assert.NotNil(err)
+	assert.Equal(10016, err.(session.Error).Code)
+	assert.Nil(existing)
+	public, _, _ := ed25519.GenerateKey(rand.Reader)
+	existing, err = CreateSession(ctx, "username", "password", "", hex.EncodeToString(public))
+	assert.NotNil(err)
+	assert.Equal(10016, err.(session.Error).Code)
+	assert.Nil(existing)
+
+	existing, err = ReadUser(ctx, user.UserID)
+	assert.Nil(err)
+	assert.True(existing.IsSuspended())
+	assert.False(existing.SuspendedUntil.Valid)
+	assert.Equal("spam", existing.SuspensionReason)
+	err = existing.Unsuspend(ctx, operator)
+	assert.Nil(err)
+	assert.False(existing.IsSuspended())
+	existing, err = AuthenticateAPIToken(ctx, token.Token)
+	assert.Nil(err)
+	assert.NotNil(existing)
+	existing, err = CreateSession(ctx, "username", "password", "", hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.NotNil(existing)
+
+	err = existing.Suspend(ctx, time.Now().Add(time.Hour), "spam", operator)
+	assert.Nil(err)
+	assert.True(existing.IsSuspended())
+	existing.SuspendedUntil.Time = time.Now().Add(-time.Minute)
+	assert.False(existing.IsSuspended())
+}
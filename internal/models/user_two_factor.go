@@ -0,0 +1,296 @@
+package models
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"satellity/internal/configs"
+	"satellity/internal/durable"
+	"satellity/internal/session"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// TOTP related CONST, RFC 6238 with the defaults most authenticator apps expect
+const (
+	totpPeriod         = 30
+	totpDigits         = 6
+	totpSkew           = 1
+	totpSecretSize     = 20
+	recoveryCodesCount = 10
+)
+
+// totpClock is replaceable in tests
+var totpClock = time.Now
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactor is the TOTP secret of a user, it's pending until EnabledAt is valid
+type TwoFactor struct {
+	UserID       string
+	Secret       string
+	LastUsedStep int64
+	EnabledAt    sql.NullTime
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	RecoveryCodes []string
+}
+
+var twoFactorColumns = []string{"user_id", "secret", "last_used_step", "enabled_at", "created_at", "updated_at"}
+
+func (tf *TwoFactor) values() []interface{} {
+	return []interface{}{tf.UserID, tf.Secret, tf.LastUsedStep, tf.EnabledAt, tf.CreatedAt, tf.UpdatedAt}
+}
+
+func twoFactorFromRow(row durable.Row) (*TwoFactor, error) {
+	var tf TwoFactor
+	err := row.Scan(&tf.UserID, &tf.Secret, &tf.LastUsedStep, &tf.EnabledAt, &tf.CreatedAt, &tf.UpdatedAt)
+	return &tf, err
+}
+
+// URI is the otpauth uri for QR codes of authenticator apps
+func (tf *TwoFactor) URI(user *User) string {
+	issuer := configs.AppConfig.Name
+	account := user.Email.String
+	if account == "" {
+		account = user.Username.String
+	}
+	v := url.Values{}
+	v.Set("secret", tf.Secret)
+	v.Set("issuer", issuer)
+	v.Set("digits", fmt.Sprint(totpDigits))
+	v.Set("period", fmt.Sprint(totpPeriod))
+	return fmt.Sprintf("otpauth://totp/%s:%s?%s", url.PathEscape(issuer), url.PathEscape(account), v.Encode())
+}
+
+// ReadTwoFactor read the two factor of the user, nil if never enrolled
+func (user *User) ReadTwoFactor(ctx context.Context) (*TwoFactor, error) {
+	var tf *TwoFactor
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		tf, err = findTwoFactor(ctx, tx, user.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return tf, nil
+}
+
+// EnrollTwoFactor generate a new pending secret, the enabled one must be disabled first
+func (user *User) EnrollTwoFactor(ctx context.Context) (*TwoFactor, error) {
+	var b [totpSecretSize]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, session.ServerError(ctx, err)
+	}
+	t := time.Now()
+	tf := &TwoFactor{
+		UserID:    user.UserID,
+		Secret:    totpEncoding.EncodeToString(b[:]),
+		CreatedAt: t,
+		UpdatedAt: t,
+	}
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		old, err := findTwoFactor(ctx, tx, user.UserID)
+		if err != nil {
+			return err
+		}
+		if old != nil && old.EnabledAt.Valid {
+			return session.ForbiddenError(ctx)
+		}
+		cols, params := durable.PrepareColumnsAndExpressions(twoFactorColumns, 0)
+		_, err = tx.Exec(ctx, fmt.Sprintf("INSERT INTO user_two_factors(%s) VALUES (%s) ON CONFLICT (user_id) DO UPDATE SET (secret,last_used_step,created_at,updated_at)=(EXCLUDED.secret,EXCLUDED.last_used_step,EXCLUDED.created_at,EXCLUDED.updated_at)", cols, params), tf.values()...)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return tf, nil
+}
+
+// EnableTwoFactor confirm the pending secret with a code, returns the recovery codes only once
+func (user *User) EnableTwoFactor(ctx context.Context, code string) (*TwoFactor, error) {
+	var tf *TwoFactor
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		tf, err = findTwoFactor(ctx, tx, user.UserID)
+		if err != nil {
+			return err
+		}
+		if tf == nil || tf.EnabledAt.Valid {
+			return session.ForbiddenError(ctx)
+		}
+		step, valid := validateTOTP(tf.Secret, code, totpClock(), tf.LastUsedStep)
+		if !valid {
+			return session.InvalidTwoFactorCodeError(ctx)
+		}
+		t := time.Now()
+		tf.LastUsedStep = step
+		tf.EnabledAt = sql.NullTime{Time: t, Valid: true}
+		tf.UpdatedAt = t
+		_, err = tx.Exec(ctx, "UPDATE user_two_factors SET (last_used_step,enabled_at,updated_at)=($1,$2,$3) WHERE user_id=$4", tf.LastUsedStep, tf.EnabledAt, tf.UpdatedAt, tf.UserID)
+		if err != nil {
+			return err
+		}
+		tf.RecoveryCodes, err = resetRecoveryCodes(ctx, tx, user.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return tf, nil
+}
+
+// DisableTwoFactor turn off two factor, requires a valid code or recovery code
+func (user *User) DisableTwoFactor(ctx context.Context, code string) error {
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		if err := verifyTwoFactor(ctx, tx, user, code); err != nil {
+			return err
+		}
+		return deleteTwoFactor(ctx, tx, user.UserID)
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// ResetTwoFactor let a user manager turn off two factor of a user who lost the device
+func (user *User) ResetTwoFactor(ctx context.Context, operator *User) error {
+	if can, err := operator.Can(ctx, PermissionUserManage); err != nil {
+		return err
+	} else if !can {
+		return session.ForbiddenError(ctx)
+	}
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		return deleteTwoFactor(ctx, tx, user.UserID)
+	})
+	if err != nil {
+		return session.TransactionError(ctx, err)
+	}
+	return nil
+}
+
+// verifyTwoFactor is a no-op unless the user enabled two factor
+func verifyTwoFactor(ctx context.Context, tx pgx.Tx, user *User, code string) error {
+	tf, err := findTwoFactor(ctx, tx, user.UserID)
+	if err != nil {
+		return err
+	}
+	if tf == nil || !tf.EnabledAt.Valid {
+		return nil
+	}
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if code == "" {
+		return session.TwoFactorRequiredError(ctx)
+	}
+	if step, valid := validateTOTP(tf.Secret, code, totpClock(), tf.LastUsedStep); valid {
+		_, err = tx.Exec(ctx, "UPDATE user_two_factors SET (last_used_step,updated_at)=($1,$2) WHERE user_id=$3", step, time.Now(), tf.UserID)
+		return err
+	}
+	cmd, err := tx.Exec(ctx, "UPDATE user_recovery_codes SET used_at=$1 WHERE user_id=$2 AND code_hash=$3 AND used_at IS NULL", time.Now(), user.UserID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() != 1 {
+		return session.InvalidTwoFactorCodeError(ctx)
+	}
+	return nil
+}
+
+func resetRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string) ([]string, error) {
+	_, err := tx.Exec(ctx, "DELETE FROM user_recovery_codes WHERE user_id=$1", userID)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, recoveryCodesCount)
+	rows := make([][]interface{}, recoveryCodesCount)
+	for i := range codes {
+		var b [5]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		s := hex.EncodeToString(b[:])
+		codes[i] = s[:5] + "-" + s[5:]
+		rows[i] = []interface{}{uuid.Must(uuid.NewV4()).String(), userID, hashRecoveryCode(codes[i]), time.Now()}
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"user_recovery_codes"}, []string{"code_id", "user_id", "code_hash", "created_at"}, pgx.CopyFromRows(rows))
+	return codes, err
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func deleteTwoFactor(ctx context.Context, tx pgx.Tx, userID string) error {
+	_, err := tx.Exec(ctx, "DELETE FROM user_recovery_codes WHERE user_id=$1", userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM user_two_factors WHERE user_id=$1", userID)
+	return err
+}
+
+func findTwoFactor(ctx context.Context, tx pgx.Tx, userID string) (*TwoFactor, error) {
+	row := tx.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM user_two_factors WHERE user_id=$1", strings.Join(twoFactorColumns, ",")), userID)
+	tf, err := twoFactorFromRow(row)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return tf, err
+}
+
+// validateTOTP accepts codes within totpSkew steps, and rejects steps not after lastUsed to prevent replay
+func validateTOTP(secret, code string, t time.Time, lastUsed int64) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	step := t.Unix() / totpPeriod
+	for i := -totpSkew; i <= totpSkew; i++ {
+		s := step + int64(i)
+		if s <= lastUsed {
+			continue
+		}
+		expected, err := generateTOTP(secret, s)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+func generateTOTP(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod), nil
+}
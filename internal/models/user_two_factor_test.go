@@ -0,0 +1,127 @@
+package models
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"satellity/internal/session"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTP(t *testing.T) {
+	assert := assert.New(t)
+
+	// RFC 6238 Appendix B, SHA1 with the last 6 digits
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	totpCases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tc := range totpCases {
+		code, err := generateTOTP(secret, tc.unix/totpPeriod)
+		assert.Nil(err)
+		assert.Equal(tc.code, code)
+		step, valid := validateTOTP(secret, tc.code, time.Unix(tc.unix, 0), 0)
+		assert.True(valid)
+		assert.Equal(tc.unix/totpPeriod, step)
+		_, valid = validateTOTP(secret, tc.code, time.Unix(tc.unix+totpPeriod, 0), 0)
+		assert.True(valid)
+		_, valid = validateTOTP(secret, tc.code, time.Unix(tc.unix+totpPeriod*3, 0), 0)
+		assert.False(valid)
+		_, valid = validateTOTP(secret, tc.code, time.Unix(tc.unix, 0), step)
+		assert.False(valid)
+	}
+	_, valid := validateTOTP(secret, "28708", time.Unix(59, 0), 0)
+	assert.False(valid)
+}
+
+func TestTwoFactorCRUD(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	now := time.Now()
+	totpClock = func() time.Time { return now }
+	defer func() { totpClock = time.Now }()
+
+	user := createTestUser(ctx, "im.yuqlee@gmail.com", "username", "password")
+	assert.NotNil(user)
+	tf, err := user.ReadTwoFactor(ctx)
+	assert.Nil(err)
+	assert.Nil(tf)
+
+	tf, err = user.EnrollTwoFactor(ctx)
+	assert.Nil(err)
+	assert.NotNil(tf)
+	assert.False(tf.EnabledAt.Valid)
+	public, _, _ := ed25519.GenerateKey(rand.Reader)
+	existing, err := CreateSession(ctx, "username", "password", "", hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.NotNil(existing)
+
+	tf, err = user.EnableTwoFactor(ctx, "000000")
+	assert.NotNil(err)
+	assert.Nil(tf)
+	tf, _ = user.ReadTwoFactor(ctx)
+	code, _ := generateTOTP(tf.Secret, now.Unix()/totpPeriod)
+	tf, err = user.EnableTwoFactor(ctx, code)
+	assert.Nil(err)
+	assert.NotNil(tf)
+	assert.True(tf.EnabledAt.Valid)
+	assert.Len(tf.RecoveryCodes, recoveryCodesCount)
+	_, err = user.EnrollTwoFactor(ctx)
+	assert.NotNil(err)
+
+	existing, err = CreateSession(ctx, "username", "password", "", hex.EncodeToString(public))
+	assert.Nil(existing)
+	assert.Equal(10014, err.(session.Error).Code)
+	existing, err = CreateSession(ctx, "username", "password", code, hex.EncodeToString(public))
+	assert.Nil(existing)
+	assert.Equal(10015, err.(session.Error).Code)
+	now = now.Add(totpPeriod * time.Second)
+	code, _ = generateTOTP(tf.Secret, now.Unix()/totpPeriod)
+	existing, err = CreateSession(ctx, "username", "password", code, hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.NotNil(existing)
+	existing, err = CreateSession(ctx, "username", "password", tf.RecoveryCodes[0], hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.NotNil(existing)
+	existing, err = CreateSession(ctx, "username", "password", tf.RecoveryCodes[0], hex.EncodeToString(public))
+	assert.NotNil(err)
+	assert.Nil(existing)
+
+	ev, err := CreateEmailVerification(ctx, EmailVerificationPurposeUser, "im.yuqlee@gmail.com", "testrecaptcha")
+	assert.Nil(err)
+	existing, err = VerifyEmailVerification(ctx, ev.VerificationID, ev.Code, "", "password", "", hex.EncodeToString(public))
+	assert.Nil(existing)
+	assert.Equal(10014, err.(session.Error).Code)
+	existing, err = VerifyEmailVerification(ctx, ev.VerificationID, ev.Code, "", "password", "000000", hex.EncodeToString(public))
+	assert.Nil(existing)
+	assert.Equal(10015, err.(session.Error).Code)
+	now = now.Add(totpPeriod * time.Second)
+	code, _ = generateTOTP(tf.Secret, now.Unix()/totpPeriod)
+	existing, err = VerifyEmailVerification(ctx, ev.VerificationID, ev.Code, "", "password", code, hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.NotNil(existing)
+	assert.Equal(user.UserID, existing.UserID)
+
+	err = user.ResetTwoFactor(ctx, user)
+	assert.NotNil(err)
+	err = user.DisableTwoFactor(ctx, tf.RecoveryCodes[1])
+	assert.Nil(err)
+	tf, err = user.ReadTwoFactor(ctx)
+	assert.Nil(err)
+	assert.Nil(tf)
+	existing, err = CreateSession(ctx, "username", "password", "", hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.NotNil(existing)
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"satellity/internal/configs"
+	"satellity/internal/durable"
+	"satellity/internal/session"
+	"satellity/internal/siwe"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	web3NonceLifetime = 10 * time.Minute
+	web3ClockSkew     = time.Minute
+)
+
+// Web3Nonce is a single use nonce of Sign-In with Ethereum (EIP-4361) messages
+type Web3Nonce struct {
+	Nonce     string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+var web3NonceColumns = []string{"nonce", "expires_at", "created_at"}
+
+func (n *Web3Nonce) values() []interface{} {
+	return []interface{}{n.Nonce, n.ExpiresAt, n.CreatedAt}
+}
+
+func web3NonceFromRow(row durable.Row) (*Web3Nonce, error) {
+	var n Web3Nonce
+	err := row.Scan(&n.Nonce, &n.ExpiresAt, &n.CreatedAt)
+	return &n, err
+}
+
+// Web3Options is what a message should be signed for, from the web3 config
+func Web3Options() siwe.Options {
+	config := configs.AppConfig
+	opts := siwe.Options{
+		Domain:   config.Web3.Domain,
+		URI:      config.Web3.URI,
+		ChainIDs: config.Web3.ChainIDs,
+		MaxAge:   web3NonceLifetime,
+		Skew:     web3ClockSkew,
+	}
+	if opts.Domain == "" {
+		if u, err := url.Parse(config.HTTP.Host); err == nil {
+			opts.Domain = u.Host
+		}
+	}
+	if len(opts.ChainIDs) == 0 {
+		opts.ChainIDs = []int64{1}
+	}
+	return opts
+}
+
+// CreateWeb3Nonce issue a nonce to put in the next message, expired nonces are cleaned up
+func CreateWeb3Nonce(ctx context.Context) (*Web3Nonce, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, session.ServerError(ctx, err)
+	}
+	t := time.Now()
+	n := &Web3Nonce{
+		Nonce:     hex.EncodeToString(b[:]),
+		ExpiresAt: t.Add(web3NonceLifetime),
+		CreatedAt: t,
+	}
+	err := session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "DELETE FROM web3_nonces WHERE expires_at<$1", t)
+		if err != nil {
+			return err
+		}
+		rows := [][]interface{}{n.values()}
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"web3_nonces"}, web3NonceColumns, pgx.CopyFromRows(rows))
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	return n, nil
+}
+
+// CreateWeb3User sign in by an EIP-4361 message, a new user will be created for an unknown address.
+// The Request ID of the message must be the session public key, so a signature
+// can't be replayed with another session.
+func CreateWeb3User(ctx context.Context, nickname, message, sig, sessionPub string) (*User, error) {
+	m, publicKey, err := verifyWeb3Message(ctx, message, sig)
+	if err != nil {
+		return nil, err
+	}
+	if sessionPub == "" || m.RequestID != sessionPub {
+		return nil, session.BadDataErrorWithFieldAndData(ctx, "message", "invalid", "request id")
+	}
+
+	nickname = strings.TrimSpace(nickname)
+	if nickname == "" {
+		nickname = publicKey
+	}
+	var user *User
+	err = session.Database(ctx).RunInTransaction(ctx, func(tx pgx.Tx) error {
+		if err := consumeWeb3Nonce(ctx, tx, m.Nonce); err != nil {
+			return err
+		}
+		old, err := findUserByPublicKey(ctx, tx, publicKey)
+		if err != nil {
+			return err
+		}
+		user, err = createUser(ctx, tx, publicKey, "", "", nickname, "", sessionPub, old)
+		return err
+	})
+	if err != nil {
+		return nil, session.TransactionError(ctx, err)
+	}
+	if user.isNew {
+		UpsertStatistic(ctx, StatisticTypeUsers)
+	}
+	return user, nil
+}
+
+// verifyWeb3Message parse and validate the message, then recover the checksum address of the signer
+func verifyWeb3Message(ctx context.Context, message, sig string) (*siwe.Message, string, error) {
+	m, err := siwe.Parse(message)
+	if err != nil {
+		return nil, "", session.BadDataErrorWithFieldAndData(ctx, "message", "invalid", err.Error())
+	}
+	if err := m.Validate(Web3Options(), time.Now()); err != nil {
+		return nil, "", session.BadDataErrorWithFieldAndData(ctx, "message", "invalid", err.Error())
+	}
+	address, err := recoverWeb3Address([]byte(message), sig)
+	if err != nil || !strings.EqualFold(address, m.Address) {
+		return nil, "", session.BadDataErrorWithFieldAndData(ctx, "signature", "invalid", sig)
+	}
+	return m, address, nil
+}
+
+// consumeWeb3Nonce delete the nonce, fails if it's unknown, used or expired
+func consumeWeb3Nonce(ctx context.Context, tx pgx.Tx, nonce string) error {
+	row := tx.QueryRow(ctx, fmt.Sprintf("DELETE FROM web3_nonces WHERE nonce=$1 RETURNING %s", strings.Join(web3NonceColumns, ",")), nonce)
+	n, err := web3NonceFromRow(row)
+	if err == pgx.ErrNoRows {
+		return session.BadDataErrorWithFieldAndData(ctx, "nonce", "invalid", nonce)
+	} else if err != nil {
+		return err
+	}
+	if n.ExpiresAt.Before(time.Now()) {
+		return session.BadDataErrorWithFieldAndData(ctx, "nonce", "expired", nonce)
+	}
+	return nil
+}
+
+// recoverWeb3Address recover the address of a personal_sign signature over data
+func recoverWeb3Address(data []byte, sig string) (string, error) {
+	sigBuf, err := hex.DecodeString(strings.TrimPrefix(sig, "0x"))
+	if err != nil {
+		return "", err
+	}
+	if len(sigBuf) != crypto.SignatureLength {
+		return "", fmt.Errorf("invalid signature length %d", len(sigBuf))
+	}
+	// wallets use 27 and 28 as the recovery id
+	if sigBuf[crypto.RecoveryIDOffset] >= 27 {
+		sigBuf[crypto.RecoveryIDOffset] -= 27
+	}
+	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(data), data)
+	hash := crypto.Keccak256Hash([]byte(msg))
+	sigPublicKey, err := crypto.Ecrecover(hash.Bytes(), sigBuf)
+	if err != nil {
+		return "", err
+	}
+	pubKey, err := crypto.UnmarshalPubkey(sigPublicKey)
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(*pubKey).Hex(), nil
+}
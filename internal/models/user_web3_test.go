@@ -0,0 +1,88 @@
+package models
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"satellity/internal/siwe"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeb3UserCRUD(t *testing.T) {
+	assert := assert.New(t)
+	ctx := setupTestContext()
+	defer teardownTestContext(ctx)
+
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(err)
+	privateKey, err := crypto.HexToECDSA("0123456789012345678901234567890123456789012345678901234567890123")
+	assert.Nil(err)
+
+	message, signature := signTestWeb3Message(ctx, privateKey, time.Now(), hex.EncodeToString(public))
+	user, err := CreateWeb3User(ctx, "abc", message, signature, hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.NotNil(user)
+	assert.Equal("abc", user.Nickname)
+	assert.Equal("0x14791697260E4c9A71f18484C9f997B308e59325", user.PublicKey.String)
+	old, err := CreateWeb3User(ctx, "abc", message, signature, hex.EncodeToString(public))
+	assert.NotNil(err)
+	assert.Nil(old)
+
+	message, signature = signTestWeb3Message(ctx, privateKey, time.Now(), hex.EncodeToString(public))
+	old, err = CreateWeb3User(ctx, "abcd", message, signature, hex.EncodeToString(public))
+	assert.Nil(err)
+	assert.NotNil(old)
+	assert.Equal(user.UserID, old.UserID)
+	assert.Equal("abc", old.Nickname)
+	assert.NotEqual(user.SessionID, old.SessionID)
+
+	message, signature = signTestWeb3Message(ctx, privateKey, time.Now().Add(-time.Hour), hex.EncodeToString(public))
+	old, err = CreateWeb3User(ctx, "abc", message, signature, hex.EncodeToString(public))
+	assert.NotNil(err)
+	assert.Nil(old)
+	message, _ = signTestWeb3Message(ctx, privateKey, time.Now(), hex.EncodeToString(public))
+	old, err = CreateWeb3User(ctx, "abc", message, signature, hex.EncodeToString(public))
+	assert.NotNil(err)
+	assert.Nil(old)
+
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(err)
+	message, signature = signTestWeb3Message(ctx, privateKey, time.Now(), hex.EncodeToString(public))
+	old, err = CreateWeb3User(ctx, "abc", message, signature, hex.EncodeToString(other))
+	assert.NotNil(err)
+	assert.Nil(old)
+	message, signature = signTestWeb3Message(ctx, privateKey, time.Now(), "")
+	old, err = CreateWeb3User(ctx, "abc", message, signature, "")
+	assert.NotNil(err)
+	assert.Nil(old)
+}
+
+func signTestWeb3Message(ctx context.Context, privateKey *ecdsa.PrivateKey, issuedAt time.Time, sessionPub string) (string, string) {
+	n, _ := CreateWeb3Nonce(ctx)
+	opts := Web3Options()
+	m := &siwe.Message{
+		Domain:    opts.Domain,
+		Address:   crypto.PubkeyToAddress(privateKey.PublicKey).Hex(),
+		Statement: "Sign in to Satellity.",
+		URI:       fmt.Sprintf("http://%s/", opts.Domain),
+		Version:   "1",
+		ChainID:   opts.ChainIDs[0],
+		Nonce:     n.Nonce,
+		IssuedAt:  issuedAt,
+		RequestID: sessionPub,
+	}
+	if opts.URI != "" {
+		m.URI = opts.URI
+	}
+	message := m.String()
+	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)
+	signature, _ := crypto.Sign(crypto.Keccak256Hash([]byte(msg)).Bytes(), privateKey)
+	return message, hex.EncodeToString(signature)
+}
@@ -0,0 +1,71 @@
+package oauth
+
+import (
+	"context"
+	"satellity/internal/configs"
+	"strings"
+)
+
+type github struct {
+	config configs.OAuthProvider
+	webURL string
+	apiURL string
+}
+
+// newGithub uses github.com by default, or a GitHub Enterprise host from c.URL
+func newGithub(c configs.OAuthProvider) *github {
+	p := &github{config: c, webURL: "https://github.com", apiURL: "https://api.github.com"}
+	if c.URL != "" {
+		p.webURL = strings.TrimSuffix(c.URL, "/")
+		p.apiURL = p.webURL + "/api/v3"
+	}
+	return p
+}
+
+func (p *github) Name() string {
+	return p.config.Name
+}
+
+func (p *github) ClientID() string {
+	return p.config.ClientID
+}
+
+func (p *github) AuthorizeURL(ctx context.Context) (string, error) {
+	return authorizeURL(p.webURL+"/login/oauth/authorize", p.config, []string{"user:email"}), nil
+}
+
+func (p *github) Authenticate(ctx context.Context, code string) (*Identity, error) {
+	token, err := exchangeCode(ctx, p.webURL+"/login/oauth/access_token", p.config, code)
+	if err != nil {
+		return nil, err
+	}
+	var user struct {
+		NodeID string `json:"node_id"`
+		Login  string `json:"login"`
+		Name   string `json:"name"`
+	}
+	if err := getJSON(ctx, p.apiURL+"/user", token, &user); err != nil {
+		return nil, err
+	}
+	identity := &Identity{
+		Provider: p.Name(),
+		Type:     p.config.Type,
+		Subject:  user.NodeID,
+		Login:    user.Login,
+		Name:     user.Name,
+	}
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, p.apiURL+"/user/emails", token, &emails); err != nil {
+		return nil, err
+	}
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			identity.Email, identity.EmailVerified = e.Email, true
+		}
+	}
+	return identity, nil
+}
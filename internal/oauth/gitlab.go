@@ -0,0 +1,63 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"satellity/internal/configs"
+	"strings"
+)
+
+type gitlab struct {
+	config configs.OAuthProvider
+	url    string
+}
+
+// newGitlab uses gitlab.com by default, or a self-managed host from c.URL
+func newGitlab(c configs.OAuthProvider) *gitlab {
+	p := &gitlab{config: c, url: "https://gitlab.com"}
+	if c.URL != "" {
+		p.url = strings.TrimSuffix(c.URL, "/")
+	}
+	return p
+}
+
+func (p *gitlab) Name() string {
+	return p.config.Name
+}
+
+func (p *gitlab) ClientID() string {
+	return p.config.ClientID
+}
+
+func (p *gitlab) AuthorizeURL(ctx context.Context) (string, error) {
+	return authorizeURL(p.url+"/oauth/authorize", p.config, []string{"read_user"}), nil
+}
+
+func (p *gitlab) Authenticate(ctx context.Context, code string) (*Identity, error) {
+	token, err := exchangeCode(ctx, p.url+"/oauth/token", p.config, code)
+	if err != nil {
+		return nil, err
+	}
+	var user struct {
+		ID          int64  `json:"id"`
+		Username    string `json:"username"`
+		Name        string `json:"name"`
+		Email       string `json:"email"`
+		ConfirmedAt string `json:"confirmed_at"`
+	}
+	if err := getJSON(ctx, p.url+"/api/v4/user", token, &user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		return nil, fmt.Errorf("oauth provider %s returns blank user", p.Name())
+	}
+	return &Identity{
+		Provider:      p.Name(),
+		Type:          p.config.Type,
+		Subject:       fmt.Sprint(user.ID),
+		Login:         user.Username,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.Email != "" && user.ConfirmedAt != "",
+	}, nil
+}
@@ -0,0 +1,179 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"satellity/internal/configs"
+	"strings"
+	"time"
+)
+
+// Provider types
+const (
+	TypeGithub = "github"
+	TypeGitlab = "gitlab"
+	TypeOIDC   = "oidc"
+)
+
+// Identity is the user info from an oauth provider, Subject is stable and unique in the provider.
+// Provider is the configured name, and Type the kind of the provider.
+type Identity struct {
+	Provider      string
+	Type          string
+	Subject       string
+	Login         string
+	Name          string
+	Email         string
+	EmailVerified bool
+}
+
+// Provider exchanges an authorization code for the identity of the user
+type Provider interface {
+	Name() string
+	ClientID() string
+	AuthorizeURL(ctx context.Context) (string, error)
+	Authenticate(ctx context.Context, code string) (*Identity, error)
+}
+
+// New create a provider by the type of the config
+func New(c configs.OAuthProvider) (Provider, error) {
+	if c.Name == "" {
+		c.Name = c.Type
+	}
+	switch c.Type {
+	case TypeGithub:
+		return newGithub(c), nil
+	case TypeGitlab:
+		return newGitlab(c), nil
+	case TypeOIDC:
+		if c.URL == "" {
+			return nil, fmt.Errorf("oauth provider %s requires the issuer url", c.Name)
+		}
+		return newOIDC(c), nil
+	}
+	return nil, fmt.Errorf("oauth provider %s has unknown type %s", c.Name, c.Type)
+}
+
+// Providers read all configured providers, the legacy github section is a provider named github
+func Providers() ([]Provider, error) {
+	config := configs.AppConfig
+	var providers []Provider
+	names := make(map[string]bool)
+	for _, c := range config.OAuth {
+		p, err := New(c)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+		names[p.Name()] = true
+	}
+	if !names[TypeGithub] && config.Github.ClientID != "" {
+		providers = append(providers, newGithub(configs.OAuthProvider{
+			Name:         TypeGithub,
+			Type:         TypeGithub,
+			ClientID:     config.Github.ClientID,
+			ClientSecret: config.Github.ClientSecret,
+		}))
+	}
+	return providers, nil
+}
+
+// Lookup find the provider by name, nil if not configured
+func Lookup(name string) (Provider, error) {
+	providers, err := Providers()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range providers {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+func httpClient() *http.Client {
+	return &http.Client{Timeout: 5 * time.Second}
+}
+
+func postForm(ctx context.Context, endpoint string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return do(req, out)
+}
+
+func getJSON(ctx context.Context, endpoint, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return do(req, out)
+}
+
+func do(req *http.Request, out interface{}) error {
+	req.Close = true
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oauth request %s %d %s", req.URL.Path, resp.StatusCode, string(data))
+	}
+	return json.Unmarshal(data, out)
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func exchangeCode(ctx context.Context, endpoint string, c configs.OAuthProvider, code string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("client_id", c.ClientID)
+	form.Set("client_secret", c.ClientSecret)
+	form.Set("code", code)
+	if c.RedirectURL != "" {
+		form.Set("redirect_uri", c.RedirectURL)
+	}
+	var body tokenResponse
+	if err := postForm(ctx, endpoint, form, &body); err != nil {
+		return "", err
+	}
+	if body.Error != "" || body.AccessToken == "" {
+		return "", fmt.Errorf("oauth token %s %s", body.Error, body.Description)
+	}
+	return body.AccessToken, nil
+}
+
+func authorizeURL(endpoint string, c configs.OAuthProvider, scopes []string) string {
+	if len(c.Scopes) > 0 {
+		scopes = c.Scopes
+	}
+	v := url.Values{}
+	v.Set("client_id", c.ClientID)
+	v.Set("response_type", "code")
+	v.Set("scope", strings.Join(scopes, " "))
+	if c.RedirectURL != "" {
+		v.Set("redirect_uri", c.RedirectURL)
+	}
+	return endpoint + "?" + v.Encode()
+}
@@ -0,0 +1,140 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"satellity/internal/configs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testCode  = "fake-code"
+	testToken = "fake-token"
+)
+
+func newFakeServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	render := func(w http.ResponseWriter, data interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(data)
+	}
+	token := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Method != "POST" || r.Form.Get("code") != testCode || r.Form.Get("client_secret") != "secret" {
+			render(w, map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		render(w, map[string]string{"access_token": testToken})
+	}
+	authorized := func(fn func(w http.ResponseWriter)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+testToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fn(w)
+		}
+	}
+
+	mux.HandleFunc("/login/oauth/access_token", token)
+	mux.HandleFunc("/api/v3/user", authorized(func(w http.ResponseWriter) {
+		render(w, map[string]interface{}{"id": 1, "node_id": "MDQ6VXNlcjE=", "login": "octocat", "name": "The Octocat"})
+	}))
+	mux.HandleFunc("/api/v3/user/emails", authorized(func(w http.ResponseWriter) {
+		render(w, []map[string]interface{}{
+			{"email": "octocat@users.noreply.github.com", "primary": false, "verified": true},
+			{"email": "octocat@github.com", "primary": true, "verified": true},
+		})
+	}))
+	mux.HandleFunc("/oauth/token", token)
+	mux.HandleFunc("/api/v4/user", authorized(func(w http.ResponseWriter) {
+		render(w, map[string]interface{}{"id": 42, "username": "tanuki", "name": "Tanuki", "email": "tanuki@gitlab.com", "confirmed_at": "2020-01-01T00:00:00Z"})
+	}))
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		render(w, map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/oauth/token",
+			"userinfo_endpoint":      server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/userinfo", authorized(func(w http.ResponseWriter) {
+		render(w, map[string]interface{}{"sub": "248289761001", "preferred_username": "jane", "name": "Jane Doe", "email": "jane@example.com", "email_verified": true})
+	}))
+	server = httptest.NewServer(mux)
+	return server
+}
+
+func TestProviders(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	server := newFakeServer(t)
+	defer server.Close()
+
+	providerCases := []struct {
+		typ      string
+		subject  string
+		login    string
+		email    string
+		authPath string
+	}{
+		{TypeGithub, "MDQ6VXNlcjE=", "octocat", "octocat@github.com", "/login/oauth/authorize"},
+		{TypeGitlab, "42", "tanuki", "tanuki@gitlab.com", "/oauth/authorize"},
+		{TypeOIDC, "248289761001", "jane", "jane@example.com", "/authorize"},
+	}
+	for _, tc := range providerCases {
+		t.Run(tc.typ, func(t *testing.T) {
+			p, err := New(configs.OAuthProvider{Type: tc.typ, ClientID: "client", ClientSecret: "secret", URL: server.URL})
+			assert.Nil(err)
+			assert.Equal(tc.typ, p.Name())
+			assert.Equal("client", p.ClientID())
+			authorize, err := p.AuthorizeURL(ctx)
+			assert.Nil(err)
+			assert.True(strings.HasPrefix(authorize, server.URL+tc.authPath+"?"))
+			assert.Contains(authorize, "client_id=client")
+
+			identity, err := p.Authenticate(ctx, testCode)
+			assert.Nil(err)
+			assert.NotNil(identity)
+			assert.Equal(tc.typ, identity.Provider)
+			assert.Equal(tc.typ, identity.Type)
+			assert.Equal(tc.subject, identity.Subject)
+			assert.Equal(tc.login, identity.Login)
+			assert.Equal(tc.email, identity.Email)
+			assert.True(identity.EmailVerified)
+
+			identity, err = p.Authenticate(ctx, "invalid")
+			assert.NotNil(err)
+			assert.Nil(identity)
+		})
+	}
+
+	p, err := New(configs.OAuthProvider{Type: "unknown"})
+	assert.NotNil(err)
+	assert.Nil(p)
+	p, err = New(configs.OAuthProvider{Type: TypeOIDC})
+	assert.NotNil(err)
+	assert.Nil(p)
+
+	configs.AppConfig = &configs.Option{}
+	configs.AppConfig.Github.ClientID = "legacy"
+	configs.AppConfig.OAuth = []configs.OAuthProvider{{Name: "sso", Type: TypeOIDC, URL: server.URL}}
+	providers, err := Providers()
+	assert.Nil(err)
+	assert.Len(providers, 2)
+	p, err = Lookup(TypeGithub)
+	assert.Nil(err)
+	assert.Equal("legacy", p.ClientID())
+	p, err = Lookup("sso")
+	assert.Nil(err)
+	assert.NotNil(p)
+	p, err = Lookup("none")
+	assert.Nil(err)
+	assert.Nil(p)
+}
@@ -14,6 +14,7 @@ const (
 	keyRender      contextValueKey = 2
 	keyDatabase    contextValueKey = 3
 	keyRequestBody contextValueKey = 13
+	keyRemoteAddr  contextValueKey = 14
 )
 
 // Logger read logger from context
@@ -59,3 +60,14 @@ func RequestBody(ctx context.Context) string {
 func WithRequestBody(ctx context.Context, body string) context.Context {
 	return context.WithValue(ctx, keyRequestBody, body)
 }
+
+// RemoteAddr read the client ip from context
+func RemoteAddr(ctx context.Context) string {
+	v, _ := ctx.Value(keyRemoteAddr).(string)
+	return v
+}
+
+// WithRemoteAddr put the client ip to context
+func WithRemoteAddr(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, keyRemoteAddr, addr)
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"runtime"
+	"time"
 
 	"github.com/go-errors/errors"
 )
@@ -52,6 +53,12 @@ func NotFoundError(ctx context.Context) Error {
 	return createError(ctx, http.StatusAccepted, http.StatusNotFound, description, nil)
 }
 
+// TooManyRequestsError return 429 when the rate limit is exceeded
+func TooManyRequestsError(ctx context.Context) Error {
+	description := http.StatusText(http.StatusTooManyRequests)
+	return createError(ctx, http.StatusTooManyRequests, http.StatusTooManyRequests, description, nil)
+}
+
 // ServerError means some server error are occurred.
 func ServerError(ctx context.Context, err error) Error {
 	description := http.StatusText(http.StatusInternalServerError)
@@ -105,6 +112,48 @@ func PasswordTooSimpleError(ctx context.Context) Error {
 	return createError(ctx, http.StatusAccepted, 10013, description, nil)
 }
 
+// TwoFactorRequiredError means the user enabled two factor, a code is required to sign in.
+func TwoFactorRequiredError(ctx context.Context) Error {
+	description := "Two-factor authentication code required."
+	return createError(ctx, http.StatusAccepted, 10014, description, nil)
+}
+
+// InvalidTwoFactorCodeError means the two factor code or recovery code is invalid.
+func InvalidTwoFactorCodeError(ctx context.Context) Error {
+	description := "Invalid two-factor authentication code."
+	return createError(ctx, http.StatusAccepted, 10015, description, nil)
+}
+
+// UserSuspendedError means the user is suspended until the time, zero time for permanently.
+func UserSuspendedError(ctx context.Context, until time.Time, reason string) Error {
+	description := "User suspended."
+	err := createError(ctx, http.StatusAccepted, 10016, description, nil)
+	extra := map[string]string{
+		"reason": reason,
+	}
+	if !until.IsZero() {
+		extra["until"] = until.Format(time.RFC3339)
+	}
+	err.Extra = extra
+	return err
+}
+
+// PasswordResetRequiredError means the password must be reset before signing in with it.
+func PasswordResetRequiredError(ctx context.Context) Error {
+	description := "Password reset required."
+	return createError(ctx, http.StatusAccepted, 10017, description, nil)
+}
+
+// TooManyAttemptsError means too many failed attempts, retry after the time.
+func TooManyAttemptsError(ctx context.Context, until time.Time) Error {
+	description := "Too many attempts, please retry later."
+	err := createError(ctx, http.StatusAccepted, 10018, description, nil)
+	err.Extra = map[string]string{
+		"until": until.Format(time.RFC3339),
+	}
+	return err
+}
+
 // VerificationCodeInvalidError means verification code is invalid
 func VerificationCodeInvalidError(ctx context.Context) Error {
 	description := "Invalid verification code."
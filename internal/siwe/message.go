@@ -0,0 +1,223 @@
+// Package siwe parses and validates Sign-In with Ethereum (EIP-4361) messages,
+// the signature itself is verified by the caller.
+package siwe
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	header  = " wants you to sign in with your Ethereum account:"
+	version = "1"
+)
+
+var (
+	addressRegexp = regexp.MustCompile(`^0x[a-fA-F0-9]{40}$`)
+	nonceRegexp   = regexp.MustCompile(`^[a-zA-Z0-9]{8,}$`)
+)
+
+// Message is an EIP-4361 message, optional times are zero when absent
+type Message struct {
+	Scheme         string
+	Domain         string
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime time.Time
+	NotBefore      time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// Parse read a message in the EIP-4361 text format
+func Parse(text string) (*Message, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if len(lines) < 8 || !strings.HasSuffix(lines[0], header) {
+		return nil, errors.New("siwe: invalid header")
+	}
+	m := &Message{Domain: strings.TrimSuffix(lines[0], header)}
+	if i := strings.Index(m.Domain, "://"); i > 0 {
+		m.Scheme, m.Domain = m.Domain[:i], m.Domain[i+3:]
+	}
+	if m.Domain == "" || strings.ContainsAny(m.Domain, " /") {
+		return nil, errors.New("siwe: invalid domain")
+	}
+	m.Address = lines[1]
+	if !addressRegexp.MatchString(m.Address) {
+		return nil, errors.New("siwe: invalid address")
+	}
+
+	i := 2
+	var statement []string
+	for ; i < len(lines) && !strings.HasPrefix(lines[i], "URI: "); i++ {
+		if lines[i] != "" {
+			statement = append(statement, lines[i])
+		}
+	}
+	if len(statement) > 1 {
+		return nil, errors.New("siwe: invalid statement")
+	}
+	m.Statement = strings.Join(statement, "")
+
+	var err error
+	fields := make(map[string]bool)
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" && i == len(lines)-1 {
+			break
+		}
+		if line == "Resources:" {
+			for i++; i < len(lines) && strings.HasPrefix(lines[i], "- "); i++ {
+				m.Resources = append(m.Resources, strings.TrimPrefix(lines[i], "- "))
+			}
+			if i < len(lines) && lines[i] != "" {
+				return nil, fmt.Errorf("siwe: unexpected line %q", lines[i])
+			}
+			break
+		}
+		parts := strings.SplitN(line, ": ", 2)
+		if len(parts) != 2 || fields[parts[0]] {
+			return nil, fmt.Errorf("siwe: unexpected line %q", line)
+		}
+		key, value := parts[0], parts[1]
+		fields[key] = true
+		switch key {
+		case "URI":
+			m.URI = value
+		case "Version":
+			m.Version = value
+		case "Chain ID":
+			m.ChainID, err = strconv.ParseInt(value, 10, 64)
+		case "Nonce":
+			m.Nonce = value
+		case "Issued At":
+			m.IssuedAt, err = time.Parse(time.RFC3339Nano, value)
+		case "Expiration Time":
+			m.ExpirationTime, err = time.Parse(time.RFC3339Nano, value)
+		case "Not Before":
+			m.NotBefore, err = time.Parse(time.RFC3339Nano, value)
+		case "Request ID":
+			m.RequestID = value
+		default:
+			return nil, fmt.Errorf("siwe: unexpected field %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("siwe: invalid %s %w", key, err)
+		}
+	}
+
+	for _, key := range []string{"URI", "Version", "Chain ID", "Nonce", "Issued At"} {
+		if !fields[key] {
+			return nil, fmt.Errorf("siwe: missing %s", key)
+		}
+	}
+	if _, err := url.ParseRequestURI(m.URI); err != nil {
+		return nil, errors.New("siwe: invalid uri")
+	}
+	if !nonceRegexp.MatchString(m.Nonce) {
+		return nil, errors.New("siwe: invalid nonce")
+	}
+	return m, nil
+}
+
+// String format the message, it's the text to sign
+func (m *Message) String() string {
+	var b strings.Builder
+	if m.Scheme != "" {
+		b.WriteString(m.Scheme + "://")
+	}
+	b.WriteString(m.Domain + header + "\n")
+	b.WriteString(m.Address + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n")
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "URI: %s\nVersion: %s\nChain ID: %d\nNonce: %s\nIssued At: %s", m.URI, m.Version, m.ChainID, m.Nonce, m.IssuedAt.UTC().Format(time.RFC3339))
+	if !m.ExpirationTime.IsZero() {
+		fmt.Fprintf(&b, "\nExpiration Time: %s", m.ExpirationTime.UTC().Format(time.RFC3339))
+	}
+	if !m.NotBefore.IsZero() {
+		fmt.Fprintf(&b, "\nNot Before: %s", m.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if m.RequestID != "" {
+		fmt.Fprintf(&b, "\nRequest ID: %s", m.RequestID)
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("\nResources:")
+		for _, r := range m.Resources {
+			b.WriteString("\n- " + r)
+		}
+	}
+	return b.String()
+}
+
+// Options are what the server expects of a message
+type Options struct {
+	Domain   string
+	URI      string
+	ChainIDs []int64
+	// MaxAge limits how long ago the message may be issued
+	MaxAge time.Duration
+	// Skew tolerates the clock difference between the wallet and the server
+	Skew time.Duration
+}
+
+// Validate check the domain, uri, chain and times of the message at now
+func (m *Message) Validate(opts Options, now time.Time) error {
+	if m.Version != version {
+		return fmt.Errorf("siwe: unsupported version %s", m.Version)
+	}
+	if !strings.EqualFold(m.Domain, opts.Domain) {
+		return fmt.Errorf("siwe: domain %s mismatch", m.Domain)
+	}
+	if opts.URI != "" && !sameOrigin(m.URI, opts.URI) {
+		return fmt.Errorf("siwe: uri %s mismatch", m.URI)
+	}
+	if !containsChain(opts.ChainIDs, m.ChainID) {
+		return fmt.Errorf("siwe: chain %d unsupported", m.ChainID)
+	}
+	if m.IssuedAt.After(now.Add(opts.Skew)) {
+		return errors.New("siwe: issued in the future")
+	}
+	if opts.MaxAge > 0 && m.IssuedAt.Add(opts.MaxAge).Before(now.Add(-opts.Skew)) {
+		return errors.New("siwe: issued too long ago")
+	}
+	if !m.ExpirationTime.IsZero() && !m.ExpirationTime.After(now.Add(-opts.Skew)) {
+		return errors.New("siwe: expired")
+	}
+	if !m.NotBefore.IsZero() && m.NotBefore.After(now.Add(opts.Skew)) {
+		return errors.New("siwe: not valid yet")
+	}
+	return nil
+}
+
+func sameOrigin(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host)
+}
+
+func containsChain(chains []int64, id int64) bool {
+	for _, c := range chains {
+		if c == id {
+			return true
+		}
+	}
+	return false
+}
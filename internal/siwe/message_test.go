@@ -0,0 +1,92 @@
+package siwe
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMessage = `satellity.org wants you to sign in with your Ethereum account:
+0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2
+
+Sign in to Satellity.
+
+URI: https://satellity.org/login
+Version: 1
+Chain ID: 1
+Nonce: 32891756abcd
+Issued At: 2021-09-30T16:25:24Z
+Expiration Time: 2021-09-30T16:35:24Z
+Resources:
+- https://satellity.org/terms`
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := Parse(testMessage)
+	assert.Nil(err)
+	assert.NotNil(m)
+	assert.Equal("satellity.org", m.Domain)
+	assert.Equal("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2", m.Address)
+	assert.Equal("Sign in to Satellity.", m.Statement)
+	assert.Equal("https://satellity.org/login", m.URI)
+	assert.Equal(int64(1), m.ChainID)
+	assert.Equal("32891756abcd", m.Nonce)
+	assert.Equal([]string{"https://satellity.org/terms"}, m.Resources)
+	assert.Equal(testMessage, m.String())
+
+	noStatement := strings.Replace(testMessage, "Sign in to Satellity.\n\n", "\n", 1)
+	m, err = Parse(noStatement)
+	assert.Nil(err)
+	assert.Equal("", m.Statement)
+	assert.Equal(noStatement, m.String())
+
+	m, err = Parse("https://" + testMessage)
+	assert.Nil(err)
+	assert.Equal("https", m.Scheme)
+	assert.Equal("satellity.org", m.Domain)
+
+	invalidCases := []string{
+		"",
+		strings.Replace(testMessage, "wants you", "want you", 1),
+		strings.Replace(testMessage, "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2", "0xC02a", 1),
+		strings.Replace(testMessage, "Nonce: 32891756abcd", "Nonce: 123", 1),
+		strings.Replace(testMessage, "Nonce: 32891756abcd\n", "", 1),
+		strings.Replace(testMessage, "Chain ID: 1", "Chain ID: one", 1),
+		strings.Replace(testMessage, "Issued At: 2021-09-30T16:25:24Z", "Issued At: yesterday", 1),
+		strings.Replace(testMessage, "Version: 1", "Version: 1\nVersion: 1", 1),
+		strings.Replace(testMessage, "Version: 1", "Unknown: 1", 1),
+	}
+	for _, text := range invalidCases {
+		m, err := Parse(text)
+		assert.NotNil(err)
+		assert.Nil(m)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := Parse(testMessage)
+	assert.Nil(err)
+	now, _ := time.Parse(time.RFC3339, "2021-09-30T16:26:00Z")
+	opts := Options{Domain: "satellity.org", URI: "https://satellity.org", ChainIDs: []int64{1}, MaxAge: 10 * time.Minute, Skew: time.Minute}
+	assert.Nil(m.Validate(opts, now))
+
+	validateCases := []struct {
+		opts Options
+		now  time.Time
+	}{
+		{Options{Domain: "evil.org", ChainIDs: []int64{1}}, now},
+		{Options{Domain: "satellity.org", URI: "https://evil.org", ChainIDs: []int64{1}}, now},
+		{Options{Domain: "satellity.org", ChainIDs: []int64{5}}, now},
+		{opts, now.Add(-5 * time.Minute)},
+		{opts, now.Add(15 * time.Minute)},
+		{Options{Domain: "satellity.org", ChainIDs: []int64{1}, MaxAge: time.Minute}, now.Add(5 * time.Minute)},
+	}
+	for _, tc := range validateCases {
+		assert.NotNil(m.Validate(tc.opts, tc.now))
+	}
+}
@@ -0,0 +1,48 @@
+package views
+
+import (
+	"net/http"
+	"satellity/internal/models"
+	"time"
+)
+
+// APITokenView is the response body of a personal api token,
+// the plain token is only visible once when it's created
+type APITokenView struct {
+	Type       string     `json:"type"`
+	TokenID    string     `json:"token_id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func buildAPIToken(token *models.APIToken) APITokenView {
+	view := APITokenView{
+		Type:      "api_token",
+		TokenID:   token.TokenID,
+		Name:      token.Name,
+		Token:     token.Token,
+		Scopes:    token.Scopes,
+		CreatedAt: token.CreatedAt,
+	}
+	if token.LastUsedAt.Valid {
+		view.LastUsedAt = &token.LastUsedAt.Time
+	}
+	return view
+}
+
+// RenderAPIToken response a single api token
+func RenderAPIToken(w http.ResponseWriter, r *http.Request, token *models.APIToken) {
+	RenderResponse(w, r, buildAPIToken(token))
+}
+
+// RenderAPITokens response an array of api tokens
+func RenderAPITokens(w http.ResponseWriter, r *http.Request, tokens []*models.APIToken) {
+	tokenViews := make([]APITokenView, len(tokens))
+	for i, token := range tokens {
+		tokenViews[i] = buildAPIToken(token)
+	}
+	RenderResponse(w, r, tokenViews)
+}
@@ -9,31 +9,41 @@ import (
 // CategoryView is the response body of a category
 // A category uses to categorize topics
 type CategoryView struct {
-	Type        string    `json:"type"`
-	CategoryID  string    `json:"category_id"`
-	Name        string    `json:"name"`
-	Alias       string    `json:"alias"`
-	Description string    `json:"description"`
-	TopicsCount int64     `json:"topics_count"`
-	LastTopicID string    `json:"last_topic_id"`
-	Position    int64     `json:"position"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	Type         string     `json:"type"`
+	CategoryID   string     `json:"category_id"`
+	Name         string     `json:"name"`
+	Alias        string     `json:"alias"`
+	Description  string     `json:"description"`
+	TopicsCount  int64      `json:"topics_count"`
+	LastTopicID  string     `json:"last_topic_id"`
+	Position     int64      `json:"position"`
+	CommentsView string     `json:"comments_view"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Moderators   []UserView `json:"moderators,omitempty"`
 }
 
 func buildCategory(category *models.Category) CategoryView {
-	return CategoryView{
-		Type:        "category",
-		CategoryID:  category.CategoryID,
-		Name:        category.Name,
-		Alias:       category.Alias,
-		Description: category.Description,
-		TopicsCount: category.TopicsCount,
-		LastTopicID: category.LastTopicID.String,
-		Position:    category.Position,
-		CreatedAt:   category.CreatedAt,
-		UpdatedAt:   category.UpdatedAt,
+	view := CategoryView{
+		Type:         "category",
+		CategoryID:   category.CategoryID,
+		Name:         category.Name,
+		Alias:        category.Alias,
+		Description:  category.Description,
+		TopicsCount:  category.TopicsCount,
+		LastTopicID:  category.LastTopicID.String,
+		Position:     category.Position,
+		CommentsView: category.CommentsView,
+		CreatedAt:    category.CreatedAt,
+		UpdatedAt:    category.UpdatedAt,
 	}
+	if category.Moderators != nil {
+		view.Moderators = make([]UserView, len(category.Moderators))
+		for i, u := range category.Moderators {
+			view.Moderators[i] = buildUser(u)
+		}
+	}
+	return view
 }
 
 // RenderCategory responses a single category
@@ -8,31 +8,57 @@ import (
 
 // CommentView is the response body of comment, which belongs to a topic
 type CommentView struct {
-	Type      string    `json:"type"`
-	CommentID string    `json:"comment_id"`
-	Body      string    `json:"body"`
-	TopicID   string    `json:"topic_id"`
-	UserID    string    `json:"user_id"`
-	Score     int       `json:"score"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	User      UserView  `json:"user"`
+	Type            string        `json:"type"`
+	CommentID       string        `json:"comment_id"`
+	Body            string        `json:"body"`
+	TopicID         string        `json:"topic_id"`
+	UserID          string        `json:"user_id"`
+	Score           int           `json:"score"`
+	ParentCommentID string        `json:"parent_comment_id,omitempty"`
+	Depth           int           `json:"depth"`
+	RepliesCount    int64         `json:"replies_count"`
+	Vote            int           `json:"vote"`
+	Deleted         bool          `json:"deleted"`
+	DeletedBy       string        `json:"deleted_by,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+	User            UserView      `json:"user"`
+	Mentions        []MentionView `json:"mentions"`
+	Parent          *CommentView  `json:"parent,omitempty"`
 }
 
 func buildComment(comment *models.Comment) CommentView {
 	view := CommentView{
-		Type:      "comment",
-		CommentID: comment.CommentID,
-		Body:      comment.Body,
-		TopicID:   comment.TopicID,
-		UserID:    comment.UserID,
-		Score:     comment.Score,
-		CreatedAt: comment.CreatedAt,
-		UpdatedAt: comment.UpdatedAt,
+		Type:            "comment",
+		CommentID:       comment.CommentID,
+		Body:            comment.Body,
+		TopicID:         comment.TopicID,
+		UserID:          comment.UserID,
+		Score:           comment.Score,
+		ParentCommentID: comment.ParentCommentID.String,
+		Depth:           comment.Depth,
+		RepliesCount:    comment.RepliesCount,
+		Vote:            comment.Vote,
+		Mentions:        buildMentions(comment.Mentions),
+		CreatedAt:       comment.CreatedAt,
+		UpdatedAt:       comment.UpdatedAt,
 	}
 	if comment.User != nil {
 		view.User = buildUser(comment.User)
 	}
+	if comment.Parent != nil {
+		parent := buildComment(comment.Parent)
+		view.Parent = &parent
+	}
+	if comment.IsDeleted() {
+		view.Body = ""
+		view.Mentions = []MentionView{}
+		view.Deleted = true
+		view.DeletedBy = "author"
+		if comment.IsDeletedByModerator() {
+			view.DeletedBy = "moderator"
+		}
+	}
 	return view
 }
 
@@ -49,3 +75,36 @@ func RenderComments(w http.ResponseWriter, r *http.Request, comments []*models.C
 	}
 	RenderResponse(w, r, views)
 }
+
+// CommentRevisionView is the response body of a comment revision
+type CommentRevisionView struct {
+	Type       string    `json:"type"`
+	RevisionID string    `json:"revision_id"`
+	CommentID  string    `json:"comment_id"`
+	Version    int64     `json:"version"`
+	Body       string    `json:"body"`
+	BodyDiff   string    `json:"body_diff"`
+	CreatedAt  time.Time `json:"created_at"`
+	User       *UserView `json:"user"`
+}
+
+// RenderCommentRevisions response the revisions of a comment
+func RenderCommentRevisions(w http.ResponseWriter, r *http.Request, revisions []*models.CommentRevision) {
+	views := make([]CommentRevisionView, len(revisions))
+	for i, revision := range revisions {
+		views[i] = CommentRevisionView{
+			Type:       "comment_revision",
+			RevisionID: revision.RevisionID,
+			CommentID:  revision.CommentID,
+			Version:    revision.Version,
+			Body:       revision.Body,
+			BodyDiff:   revision.BodyDiff,
+			CreatedAt:  revision.CreatedAt,
+		}
+		if revision.User != nil {
+			user := buildUser(revision.User)
+			views[i].User = &user
+		}
+	}
+	RenderResponse(w, r, views)
+}
@@ -0,0 +1,27 @@
+package views
+
+import (
+	"net/http"
+	"satellity/internal/models"
+	"time"
+)
+
+// EmailPreferenceView is the response body of the emails a user receives
+type EmailPreferenceView struct {
+	Type      string    `json:"type"`
+	Replies   bool      `json:"replies"`
+	Mentions  bool      `json:"mentions"`
+	Digest    string    `json:"digest"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RenderEmailPreference response an email preference
+func RenderEmailPreference(w http.ResponseWriter, r *http.Request, p *models.EmailPreference) {
+	RenderResponse(w, r, EmailPreferenceView{
+		Type:      "email_preference",
+		Replies:   p.Replies,
+		Mentions:  p.Mentions,
+		Digest:    p.Digest,
+		UpdatedAt: p.UpdatedAt,
+	})
+}
@@ -0,0 +1,29 @@
+package views
+
+import (
+	"satellity/internal/models"
+)
+
+// MentionView is a reference to a user mentioned by @username in a body
+type MentionView struct {
+	Type     string `json:"type"`
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Nickname string `json:"nickname"`
+}
+
+func buildMentions(mentions []*models.Mention) []MentionView {
+	views := make([]MentionView, 0, len(mentions))
+	for _, m := range mentions {
+		if m.User == nil {
+			continue
+		}
+		views = append(views, MentionView{
+			Type:     "mention",
+			UserID:   m.UserID,
+			Username: m.User.Username.String,
+			Nickname: m.User.Name(),
+		})
+	}
+	return views
+}
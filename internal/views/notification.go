@@ -0,0 +1,55 @@
+package views
+
+import (
+	"net/http"
+	"satellity/internal/models"
+	"time"
+)
+
+// NotificationView is the response body of a notification, actors are the
+// latest users of the grouped events and actors_count is all of them
+type NotificationView struct {
+	Type             string     `json:"type"`
+	NotificationID   string     `json:"notification_id"`
+	NotificationType string     `json:"notification_type"`
+	TopicID          string     `json:"topic_id"`
+	CommentID        string     `json:"comment_id"`
+	Actors           []UserView `json:"actors"`
+	ActorsCount      int        `json:"actors_count"`
+	Read             bool       `json:"read"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	Topic            *TopicView `json:"topic"`
+}
+
+func buildNotification(n *models.Notification) NotificationView {
+	view := NotificationView{
+		Type:             "notification",
+		NotificationID:   n.NotificationID,
+		NotificationType: n.NotificationType,
+		TopicID:          n.TopicID.String,
+		CommentID:        n.CommentID.String,
+		Actors:           make([]UserView, len(n.Actors)),
+		ActorsCount:      len(n.ActorIDs),
+		Read:             n.IsRead(),
+		CreatedAt:        n.CreatedAt,
+		UpdatedAt:        n.UpdatedAt,
+	}
+	for i, u := range n.Actors {
+		view.Actors[i] = buildUser(u)
+	}
+	if n.Topic != nil {
+		topic := buildTopic(n.Topic)
+		view.Topic = &topic
+	}
+	return view
+}
+
+// RenderNotifications response a bundle of notifications
+func RenderNotifications(w http.ResponseWriter, r *http.Request, notifications []*models.Notification) {
+	notificationViews := make([]NotificationView, len(notifications))
+	for i, n := range notifications {
+		notificationViews[i] = buildNotification(n)
+	}
+	RenderResponse(w, r, notificationViews)
+}
@@ -0,0 +1,31 @@
+package views
+
+import (
+	"net/http"
+	"satellity/internal/models"
+	"time"
+)
+
+// RankingWeightView is the response body of a weight of the topic ranking
+type RankingWeightView struct {
+	Type      string    `json:"type"`
+	Name      string    `json:"name"`
+	Weight    float64   `json:"weight"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RenderRankingWeights response all ranking weights
+func RenderRankingWeights(w http.ResponseWriter, r *http.Request, weights []*models.RankingWeight) {
+	weightViews := make([]RankingWeightView, len(weights))
+	for i, weight := range weights {
+		weightViews[i] = RankingWeightView{
+			Type:      "ranking_weight",
+			Name:      weight.Name,
+			Weight:    weight.Weight,
+			CreatedAt: weight.CreatedAt,
+			UpdatedAt: weight.UpdatedAt,
+		}
+	}
+	RenderResponse(w, r, weightViews)
+}
@@ -0,0 +1,44 @@
+package views
+
+import (
+	"net/http"
+	"satellity/internal/models"
+	"time"
+)
+
+// RoleView is the response body of a role
+type RoleView struct {
+	Type        string    `json:"type"`
+	Name        string    `json:"name"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func buildRole(role *models.Role) RoleView {
+	permissions := role.Permissions
+	if role.Name == models.UserRoleAdmin {
+		permissions = models.Permissions
+	}
+	return RoleView{
+		Type:        "role",
+		Name:        role.Name,
+		Permissions: permissions,
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+}
+
+// RenderRole response a role
+func RenderRole(w http.ResponseWriter, r *http.Request, role *models.Role) {
+	RenderResponse(w, r, buildRole(role))
+}
+
+// RenderRoles response all roles
+func RenderRoles(w http.ResponseWriter, r *http.Request, roles []*models.Role) {
+	roleViews := make([]RoleView, len(roles))
+	for i, role := range roles {
+		roleViews[i] = buildRole(role)
+	}
+	RenderResponse(w, r, roleViews)
+}
@@ -0,0 +1,43 @@
+package views
+
+import (
+	"net/http"
+	"satellity/internal/models"
+)
+
+// SearchResultView is the response body of a matched topic or comment, the
+// snippets are escaped html with the matches in <mark>
+type SearchResultView struct {
+	Type         string       `json:"type"`
+	ResultType   string       `json:"result_type"`
+	Rank         float32      `json:"rank"`
+	TitleSnippet string       `json:"title_snippet"`
+	Snippet      string       `json:"snippet"`
+	Cursor       string       `json:"cursor"`
+	Topic        *TopicView   `json:"topic,omitempty"`
+	Comment      *CommentView `json:"comment,omitempty"`
+}
+
+// RenderSearchResults response the results of a search
+func RenderSearchResults(w http.ResponseWriter, r *http.Request, results []*models.SearchResult) {
+	resultViews := make([]SearchResultView, len(results))
+	for i, result := range results {
+		resultViews[i] = SearchResultView{
+			Type:         "search_result",
+			ResultType:   result.Type,
+			Rank:         result.Rank,
+			TitleSnippet: result.TitleSnippet,
+			Snippet:      result.Snippet,
+			Cursor:       result.Cursor,
+		}
+		if result.Topic != nil {
+			topic := buildTopic(result.Topic)
+			resultViews[i].Topic = &topic
+		}
+		if result.Comment != nil {
+			comment := buildComment(result.Comment)
+			resultViews[i].Comment = &comment
+		}
+	}
+	RenderResponse(w, r, resultViews)
+}
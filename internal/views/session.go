@@ -0,0 +1,37 @@
+package views
+
+import (
+	"net/http"
+	"satellity/internal/models"
+	"time"
+)
+
+// SessionView is the response body of a login session
+type SessionView struct {
+	Type         string    `json:"type"`
+	SessionID    string    `json:"session_id"`
+	IsCurrent    bool      `json:"is_current"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastActiveAt time.Time `json:"last_active_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+func buildSession(s *models.Session, current string) SessionView {
+	return SessionView{
+		Type:         "session",
+		SessionID:    s.SessionID,
+		IsCurrent:    s.SessionID == current,
+		CreatedAt:    s.CreatedAt,
+		LastActiveAt: s.LastActiveAt,
+		ExpiresAt:    s.ExpiresAt,
+	}
+}
+
+// RenderSessions response sessions of the user, marks the one in use
+func RenderSessions(w http.ResponseWriter, r *http.Request, user *models.User, sessions []*models.Session) {
+	sessionViews := make([]SessionView, len(sessions))
+	for i, s := range sessions {
+		sessionViews[i] = buildSession(s, user.SessionID)
+	}
+	RenderResponse(w, r, sessionViews)
+}
@@ -0,0 +1,27 @@
+package views
+
+import (
+	"net/http"
+	"satellity/internal/models"
+)
+
+// SubscriptionView is the response body of the subscription of a user to a
+// topic or category
+type SubscriptionView struct {
+	Type       string `json:"type"`
+	TopicID    string `json:"topic_id,omitempty"`
+	CategoryID string `json:"category_id"`
+	Level      string `json:"level"`
+	Inherited  bool   `json:"inherited"`
+}
+
+// RenderSubscription response a subscription
+func RenderSubscription(w http.ResponseWriter, r *http.Request, s *models.Subscription) {
+	RenderResponse(w, r, SubscriptionView{
+		Type:       "subscription",
+		TopicID:    s.TopicID,
+		CategoryID: s.CategoryID,
+		Level:      s.Level,
+		Inherited:  s.Inherited,
+	})
+}
@@ -8,25 +8,27 @@ import (
 
 // TopicView is the response body of topic
 type TopicView struct {
-	Type           string       `json:"type"`
-	TopicID        string       `json:"topic_id"`
-	Title          string       `json:"title"`
-	Body           string       `json:"body"`
-	TopicType      string       `json:"topic_type"`
-	UserID         string       `json:"user_id"`
-	CategoryID     string       `json:"category_id"`
-	CommentsCount  int64        `json:"comments_count"`
-	LikesCount     int64        `json:"likes_count"`
-	ViewsCount     int64        `json:"views_count"`
-	BookmarksCount int64        `json:"bookmarks_count"`
-	IsLikedBy      bool         `json:"is_liked_by"`
-	IsBookmarkedBy bool         `json:"is_bookmarked_by"`
-	Draft          bool         `json:"draft"`
-	Score          int          `json:"score"`
-	CreatedAt      time.Time    `json:"created_at"`
-	UpdatedAt      time.Time    `json:"updated_at"`
-	User           UserView     `json:"user"`
-	Category       CategoryView `json:"category"`
+	Type           string        `json:"type"`
+	TopicID        string        `json:"topic_id"`
+	Title          string        `json:"title"`
+	Body           string        `json:"body"`
+	TopicType      string        `json:"topic_type"`
+	UserID         string        `json:"user_id"`
+	CategoryID     string        `json:"category_id"`
+	CommentsCount  int64         `json:"comments_count"`
+	LikesCount     int64         `json:"likes_count"`
+	ViewsCount     int64         `json:"views_count"`
+	BookmarksCount int64         `json:"bookmarks_count"`
+	IsLikedBy      bool          `json:"is_liked_by"`
+	IsBookmarkedBy bool          `json:"is_bookmarked_by"`
+	Draft          bool          `json:"draft"`
+	Locked         bool          `json:"locked"`
+	Score          float64       `json:"score"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	User           UserView      `json:"user"`
+	Category       CategoryView  `json:"category"`
+	Mentions       []MentionView `json:"mentions"`
 }
 
 func buildTopic(topic *models.Topic) TopicView {
@@ -45,7 +47,9 @@ func buildTopic(topic *models.Topic) TopicView {
 		ViewsCount:     topic.ViewsCount,
 		BookmarksCount: topic.BookmarksCount,
 		Draft:          topic.Draft,
+		Locked:         topic.Locked,
 		Score:          topic.Score,
+		Mentions:       buildMentions(topic.Mentions),
 		CreatedAt:      topic.CreatedAt,
 		UpdatedAt:      topic.UpdatedAt,
 	}
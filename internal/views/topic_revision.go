@@ -0,0 +1,53 @@
+package views
+
+import (
+	"net/http"
+	"satellity/internal/models"
+	"time"
+)
+
+// TopicRevisionView is the response body of a topic revision
+type TopicRevisionView struct {
+	Type               string    `json:"type"`
+	RevisionID         string    `json:"revision_id"`
+	TopicID            string    `json:"topic_id"`
+	Version            int64     `json:"version"`
+	Title              string    `json:"title"`
+	Body               string    `json:"body"`
+	CategoryID         string    `json:"category_id"`
+	PreviousCategoryID string    `json:"previous_category_id,omitempty"`
+	TitleDiff          string    `json:"title_diff"`
+	BodyDiff           string    `json:"body_diff"`
+	CreatedAt          time.Time `json:"created_at"`
+	User               *UserView `json:"user"`
+}
+
+func buildTopicRevision(revision *models.TopicRevision) TopicRevisionView {
+	view := TopicRevisionView{
+		Type:               "topic_revision",
+		RevisionID:         revision.RevisionID,
+		TopicID:            revision.TopicID,
+		Version:            revision.Version,
+		Title:              revision.Title,
+		Body:               revision.Body,
+		CategoryID:         revision.CategoryID,
+		PreviousCategoryID: revision.PreviousCategoryID,
+		TitleDiff:          revision.TitleDiff,
+		BodyDiff:           revision.BodyDiff,
+		CreatedAt:          revision.CreatedAt,
+	}
+	if revision.User != nil {
+		user := buildUser(revision.User)
+		view.User = &user
+	}
+	return view
+}
+
+// RenderTopicRevisions response the revisions of a topic
+func RenderTopicRevisions(w http.ResponseWriter, r *http.Request, revisions []*models.TopicRevision) {
+	revisionViews := make([]TopicRevisionView, len(revisions))
+	for i, revision := range revisions {
+		revisionViews[i] = buildTopicRevision(revision)
+	}
+	RenderResponse(w, r, revisionViews)
+}
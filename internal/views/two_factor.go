@@ -0,0 +1,33 @@
+package views
+
+import (
+	"net/http"
+	"satellity/internal/models"
+	"time"
+)
+
+// TwoFactorView is the response body of user's TOTP two factor,
+// the secret is only visible before it's enabled
+type TwoFactorView struct {
+	Type          string    `json:"type"`
+	Enabled       bool      `json:"enabled"`
+	Secret        string    `json:"secret,omitempty"`
+	URI           string    `json:"uri,omitempty"`
+	RecoveryCodes []string  `json:"recovery_codes,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// RenderTwoFactor response two factor status of the user
+func RenderTwoFactor(w http.ResponseWriter, r *http.Request, user *models.User, tf *models.TwoFactor) {
+	view := TwoFactorView{Type: "two_factor"}
+	if tf != nil {
+		view.Enabled = tf.EnabledAt.Valid
+		view.RecoveryCodes = tf.RecoveryCodes
+		view.CreatedAt = tf.CreatedAt
+		if !view.Enabled {
+			view.Secret = tf.Secret
+			view.URI = tf.URI(user)
+		}
+	}
+	RenderResponse(w, r, view)
+}
@@ -20,10 +20,27 @@ type UserView struct {
 // AccountView is the response body of a sign in user
 type AccountView struct {
 	UserView
-	Username  string `json:"username"`
-	Email     string `json:"email"`
-	SessionID string `json:"session_id"`
-	Role      string `json:"role"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	PublicKey   string `json:"public_key"`
+	SessionID   string `json:"session_id"`
+	Role        string `json:"role"`
+	HasPassword bool   `json:"has_password"`
+
+	UnreadNotificationsCount int64 `json:"unread_notifications_count"`
+}
+
+// AdminUserView is the response body of a user for admins
+type AdminUserView struct {
+	UserView
+	Username              string     `json:"username"`
+	Email                 string     `json:"email"`
+	Role                  string     `json:"role"`
+	Suspended             bool       `json:"suspended"`
+	SuspendedAt           *time.Time `json:"suspended_at"`
+	SuspendedUntil        *time.Time `json:"suspended_until"`
+	SuspensionReason      string     `json:"suspension_reason"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
 }
 
 func buildUser(user *models.User) UserView {
@@ -55,11 +72,48 @@ func RenderUsers(w http.ResponseWriter, r *http.Request, users []*models.User) {
 // RenderAccount response
 func RenderAccount(w http.ResponseWriter, r *http.Request, user *models.User) {
 	accountView := AccountView{
-		UserView:  buildUser(user),
-		Username:  user.Username.String,
-		Email:     user.Email.String,
-		SessionID: user.SessionID,
-		Role:      user.GetRole(),
+		UserView:    buildUser(user),
+		Username:    user.Username.String,
+		Email:       user.Email.String,
+		PublicKey:   user.PublicKey.String,
+		SessionID:   user.SessionID,
+		Role:        user.GetRole(),
+		HasPassword: user.EncryptedPassword.Valid,
+
+		UnreadNotificationsCount: user.UnreadNotificationsCount,
 	}
 	RenderResponse(w, r, accountView)
 }
+
+func buildAdminUser(user *models.User) AdminUserView {
+	view := AdminUserView{
+		UserView:              buildUser(user),
+		Username:              user.Username.String,
+		Email:                 user.Email.String,
+		Role:                  user.GetRole(),
+		Suspended:             user.IsSuspended(),
+		SuspensionReason:      user.SuspensionReason,
+		PasswordResetRequired: user.PasswordResetRequired,
+	}
+	if user.SuspendedAt.Valid {
+		view.SuspendedAt = &user.SuspendedAt.Time
+	}
+	if user.SuspendedUntil.Valid {
+		view.SuspendedUntil = &user.SuspendedUntil.Time
+	}
+	return view
+}
+
+// RenderAdminUser response a user for admins
+func RenderAdminUser(w http.ResponseWriter, r *http.Request, user *models.User) {
+	RenderResponse(w, r, buildAdminUser(user))
+}
+
+// RenderAdminUsers response a bundle of users for admins
+func RenderAdminUsers(w http.ResponseWriter, r *http.Request, users []*models.User) {
+	userViews := make([]AdminUserView, len(users))
+	for i, user := range users {
+		userViews[i] = buildAdminUser(user)
+	}
+	RenderResponse(w, r, userViews)
+}
@@ -0,0 +1,86 @@
+package views
+
+import (
+	"net/http"
+	"satellity/internal/models"
+	"time"
+)
+
+// UserArchiveView is the personal data export of a user
+type UserArchiveView struct {
+	Type         string            `json:"type"`
+	User         AccountView       `json:"user"`
+	Topics       []TopicView       `json:"topics"`
+	Comments     []CommentView     `json:"comments"`
+	TopicUsers   []TopicUserView   `json:"topic_users"`
+	CommentUsers []CommentUserView `json:"comment_users"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// TopicUserView is a like, bookmark or subscription of a topic
+type TopicUserView struct {
+	TopicID      string     `json:"topic_id"`
+	LikedAt      *time.Time `json:"liked_at"`
+	BookmarkedAt *time.Time `json:"bookmarked_at"`
+	Subscription string     `json:"subscription"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// CommentUserView is a vote on a comment
+type CommentUserView struct {
+	CommentID string    `json:"comment_id"`
+	Vote      int       `json:"vote"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RenderUserArchive response the personal data export
+func RenderUserArchive(w http.ResponseWriter, r *http.Request, archive *models.UserArchive) {
+	user := archive.User
+	view := UserArchiveView{
+		Type: "user_archive",
+		User: AccountView{
+			UserView:  buildUser(user),
+			Username:  user.Username.String,
+			Email:     user.Email.String,
+			PublicKey: user.PublicKey.String,
+			Role:      user.GetRole(),
+		},
+		Topics:       make([]TopicView, len(archive.Topics)),
+		Comments:     make([]CommentView, len(archive.Comments)),
+		TopicUsers:   make([]TopicUserView, len(archive.TopicUsers)),
+		CommentUsers: make([]CommentUserView, len(archive.CommentUsers)),
+		CreatedAt:    archive.CreatedAt,
+	}
+	for i, topic := range archive.Topics {
+		view.Topics[i] = buildTopic(topic)
+	}
+	for i, comment := range archive.Comments {
+		view.Comments[i] = buildComment(comment)
+	}
+	for i, tu := range archive.TopicUsers {
+		view.TopicUsers[i] = TopicUserView{
+			TopicID:      tu.TopicID,
+			Subscription: tu.Subscription,
+			CreatedAt:    tu.CreatedAt,
+			UpdatedAt:    tu.UpdatedAt,
+		}
+		if tu.LikedAt.Valid {
+			view.TopicUsers[i].LikedAt = &tu.LikedAt.Time
+		}
+		if tu.BookmarkedAt.Valid {
+			view.TopicUsers[i].BookmarkedAt = &tu.BookmarkedAt.Time
+		}
+	}
+	for i, cu := range archive.CommentUsers {
+		view.CommentUsers[i] = CommentUserView{
+			CommentID: cu.CommentID,
+			Vote:      cu.Vote,
+			CreatedAt: cu.CreatedAt,
+			UpdatedAt: cu.UpdatedAt,
+		}
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="satellity-export.json"`)
+	RenderResponse(w, r, view)
+}
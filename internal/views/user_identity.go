@@ -0,0 +1,42 @@
+package views
+
+import (
+	"net/http"
+	"satellity/internal/models"
+	"time"
+)
+
+// IdentityView is the response body of an oauth identity linked to the user
+type IdentityView struct {
+	Type       string    `json:"type"`
+	IdentityID string    `json:"identity_id"`
+	Provider   string    `json:"provider"`
+	Login      string    `json:"login"`
+	Email      string    `json:"email"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func buildIdentity(ui *models.UserIdentity) IdentityView {
+	return IdentityView{
+		Type:       "identity",
+		IdentityID: ui.IdentityID,
+		Provider:   ui.Provider,
+		Login:      ui.Login,
+		Email:      ui.Email,
+		CreatedAt:  ui.CreatedAt,
+	}
+}
+
+// RenderIdentity response an identity
+func RenderIdentity(w http.ResponseWriter, r *http.Request, ui *models.UserIdentity) {
+	RenderResponse(w, r, buildIdentity(ui))
+}
+
+// RenderIdentities response the identities of the user
+func RenderIdentities(w http.ResponseWriter, r *http.Request, identities []*models.UserIdentity) {
+	identityViews := make([]IdentityView, len(identities))
+	for i, ui := range identities {
+		identityViews[i] = buildIdentity(ui)
+	}
+	RenderResponse(w, r, identityViews)
+}
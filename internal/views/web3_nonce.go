@@ -0,0 +1,30 @@
+package views
+
+import (
+	"net/http"
+	"satellity/internal/models"
+	"satellity/internal/siwe"
+	"time"
+)
+
+// Web3NonceView is the response body of a nonce, with what the message should be signed for
+type Web3NonceView struct {
+	Type      string    `json:"type"`
+	Nonce     string    `json:"nonce"`
+	Domain    string    `json:"domain"`
+	URI       string    `json:"uri"`
+	ChainIDs  []int64   `json:"chain_ids"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RenderWeb3Nonce response a nonce
+func RenderWeb3Nonce(w http.ResponseWriter, r *http.Request, nonce *models.Web3Nonce, opts siwe.Options) {
+	RenderResponse(w, r, Web3NonceView{
+		Type:      "web3_nonce",
+		Nonce:     nonce.Nonce,
+		Domain:    opts.Domain,
+		URI:       opts.URI,
+		ChainIDs:  opts.ChainIDs,
+		ExpiresAt: nonce.ExpiresAt,
+	})
+}
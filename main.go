@@ -12,9 +12,11 @@ import (
 	"satellity/internal/controllers"
 	"satellity/internal/durable"
 	"satellity/internal/middlewares"
+	"satellity/internal/models"
+	"satellity/internal/session"
+	"time"
 
 	"github.com/dimfeld/httptreemux"
-	"github.com/gorilla/handlers"
 	"github.com/jackc/pgx/v4/pgxpool"
 	flags "github.com/jessevdk/go-flags"
 	"github.com/unrolled/render"
@@ -27,24 +29,55 @@ func startHTTP(db *pgxpool.Pool, logger *zap.Logger, port string) error {
 	controllers.RegisterHanders(router)
 	controllers.RegisterRoutes(router)
 
-	handler := middlewares.Authenticate(router)
+	var limiter durable.Limiter = durable.NewMemoryLimiter()
+	if configs.AppConfig.RateLimit.Backend == "postgres" {
+		limiter = durable.NewPostgresLimiter(database)
+	}
+	handler := middlewares.RateLimit(router, limiter, configs.AppConfig.RateLimit.Groups)
+	handler = middlewares.Authenticate(handler)
 	handler = middlewares.Constraint(handler)
 	handler = middlewares.Context(handler, database, render.New())
 	handler = middlewares.State(handler)
 	handler = middlewares.Logger(handler, durable.NewLogger(logger))
-	handler = handlers.ProxyHeaders(handler)
+	handler = middlewares.ProxyHeaders(handler, configs.AppConfig.HTTP.TrustedProxies)
+
+	go rankTopics(database, logger)
+	go deliverEmails(database, logger)
 
 	log.Printf("HTTP server running at: http://localhost:%s", port)
 	return http.ListenAndServe(fmt.Sprintf(":%s", port), handler)
 }
 
+// rankTopics recompute the hot scores of topics in the background, errors are
+// logged by the models
+func rankTopics(database *durable.Database, logger *zap.Logger) {
+	ctx := session.WithDatabase(context.Background(), database)
+	ctx = session.WithLogger(ctx, durable.NewLogger(logger))
+	for {
+		models.RankTopics(ctx)
+		time.Sleep(models.TopicRankingInterval)
+	}
+}
+
+// deliverEmails send the batched notification emails and the digests in the
+// background, errors are logged by the models
+func deliverEmails(database *durable.Database, logger *zap.Logger) {
+	ctx := session.WithDatabase(context.Background(), database)
+	ctx = session.WithLogger(ctx, durable.NewLogger(logger))
+	for {
+		models.DeliverEmails(ctx)
+		time.Sleep(models.EmailDeliveryInterval)
+	}
+}
+
 func main() {
 	var options struct {
 		Config      string `short:"c" long:"config" description:"Where's the config file place, default ./internal/configs/config.yaml"`
 		Environment string `short:"e" long:"environment" default:"development"`
 	}
 	p := flags.NewParser(&options, flags.Default)
-	if _, err := p.Parse(); err != nil {
+	args, err := p.Parse()
+	if err != nil {
 		log.Panicln(err)
 	}
 
@@ -70,6 +103,23 @@ func main() {
 	})
 	defer db.Close()
 
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(durable.WrapDatabase(db), args[1:]); err != nil {
+			log.Panicln(err)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "reindex" {
+		ctx := session.WithDatabase(context.Background(), durable.WrapDatabase(db))
+		count, err := models.ReindexSearch(ctx)
+		if err != nil {
+			log.Panicln(err)
+		}
+		log.Printf("reindexed %d topics and comments", count)
+		return
+	}
+
 	logger, err := zap.NewDevelopment()
 	if config.Environment == "production" {
 		logger, err = zap.NewProduction()
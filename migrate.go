@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"satellity/internal/durable"
+	"satellity/internal/migrations"
+	"strconv"
+	"time"
+)
+
+// runMigrate handles `migrate up`, `migrate down N` and `migrate status`
+func runMigrate(db *durable.Database, args []string) error {
+	ctx := context.Background()
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down [N] | status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(ctx, db)
+		for _, m := range applied {
+			log.Printf("migrated up %04d_%s", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			log.Println("database is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid steps %s, must be a positive number", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrations.Down(ctx, db, steps)
+		for _, m := range reverted {
+			log.Printf("migrated down %04d_%s", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrations.ReadStatus(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt.Valid {
+				state = s.AppliedAt.Time.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown migrate command %s", args[0])
+}